@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/aclements/go-z3/z3"
 	"github.com/vldF/symbolic_execution_course/constraints/smt"
+	"reflect"
 )
 
 func solveArrays() {
@@ -118,13 +119,31 @@ func solveCompareAges() {
 	runForCase(compareAge4)
 }
 
-func compareAge1(sCtx *smt.SymContext) string {
-	personStructDescriptor := map[string]z3.Sort{
-		"Name": sCtx.Ctx.UninterpretedSort("string"),
-		"Age":  sCtx.Ctx.IntSort(),
+type Person struct {
+	Name string
+	Age  int
+}
+
+func newPeopleArgument(sCtx *smt.SymContext) smt.SymStructArray {
+	personStructDescriptor, err := sCtx.DescribeStruct(reflect.TypeOf(Person{}))
+	if err != nil {
+		panic(err)
+	}
+
+	return sCtx.NewStructArray("people", personStructDescriptor)
+}
+
+func personAge(people smt.SymStructArray, index z3.Int) z3.Int {
+	age, err := smt.GetField[smt.SymInt](people.GetStructure(index), "Age")
+	if err != nil {
+		panic(err)
 	}
 
-	argPeople := sCtx.NewStructArray("people", personStructDescriptor)
+	return age.Int()
+}
+
+func compareAge1(sCtx *smt.SymContext) string {
+	argPeople := newPeopleArgument(sCtx)
 	argIndex := sCtx.Ctx.IntConst("index")
 	_ = sCtx.Ctx.IntConst("value")
 
@@ -136,12 +155,7 @@ func compareAge1(sCtx *smt.SymContext) string {
 }
 
 func compareAge2(sCtx *smt.SymContext) string {
-	personStructDescriptor := map[string]z3.Sort{
-		"Name": sCtx.Ctx.UninterpretedSort("string"),
-		"Age":  sCtx.Ctx.IntSort(),
-	}
-
-	argPeople := sCtx.NewStructArray("people", personStructDescriptor)
+	argPeople := newPeopleArgument(sCtx)
 	argIndex := sCtx.Ctx.IntConst("index")
 	argValue := sCtx.Ctx.IntConst("value")
 
@@ -149,19 +163,14 @@ func compareAge2(sCtx *smt.SymContext) string {
 	prevCond := argIndex.LT(zeroIntConst).Or(argIndex.GT(argPeople.Len()).Or(argIndex.Eq(argPeople.Len())))
 	sCtx.Solver.Assert(prevCond.Not())
 
-	cond := argPeople.GetStructure(argIndex)["Age"].(z3.Int).GT(argValue)
+	cond := personAge(argPeople, argIndex).GT(argValue)
 	sCtx.Solver.Assert(cond)
 
 	return "!(index < 0 || index >= len(people)) && (age > value)"
 }
 
 func compareAge3(sCtx *smt.SymContext) string {
-	personStructDescriptor := map[string]z3.Sort{
-		"Name": sCtx.Ctx.UninterpretedSort("string"),
-		"Age":  sCtx.Ctx.IntSort(),
-	}
-
-	argPeople := sCtx.NewStructArray("people", personStructDescriptor)
+	argPeople := newPeopleArgument(sCtx)
 	argIndex := sCtx.Ctx.IntConst("index")
 	argValue := sCtx.Ctx.IntConst("value")
 
@@ -169,22 +178,17 @@ func compareAge3(sCtx *smt.SymContext) string {
 	prevCond1 := argIndex.LT(zeroIntConst).Or(argIndex.GT(argPeople.Len()).Or(argIndex.Eq(argPeople.Len())))
 	sCtx.Solver.Assert(prevCond1.Not())
 
-	prevCond2 := argPeople.GetStructure(argIndex)["Age"].(z3.Int).GT(argValue)
+	prevCond2 := personAge(argPeople, argIndex).GT(argValue)
 	sCtx.Solver.Assert(prevCond2.Not())
 
-	cond := argPeople.GetStructure(argIndex)["Age"].(z3.Int).LT(argValue)
+	cond := personAge(argPeople, argIndex).LT(argValue)
 	sCtx.Solver.Assert(cond)
 
 	return "!(index < 0 || index >= len(people)) && !(age > value) && (age < value)"
 }
 
 func compareAge4(sCtx *smt.SymContext) string {
-	personStructDescriptor := map[string]z3.Sort{
-		"Name": sCtx.Ctx.UninterpretedSort("string"),
-		"Age":  sCtx.Ctx.IntSort(),
-	}
-
-	argPeople := sCtx.NewStructArray("people", personStructDescriptor)
+	argPeople := newPeopleArgument(sCtx)
 	argIndex := sCtx.Ctx.IntConst("index")
 	argValue := sCtx.Ctx.IntConst("value")
 
@@ -192,10 +196,10 @@ func compareAge4(sCtx *smt.SymContext) string {
 	prevCond1 := argIndex.LT(zeroIntConst).Or(argIndex.GT(argPeople.Len()).Or(argIndex.Eq(argPeople.Len())))
 	sCtx.Solver.Assert(prevCond1.Not())
 
-	prevCond2 := argPeople.GetStructure(argIndex)["Age"].(z3.Int).GT(argValue)
+	prevCond2 := personAge(argPeople, argIndex).GT(argValue)
 	sCtx.Solver.Assert(prevCond2.Not())
 
-	prevCond3 := argPeople.GetStructure(argIndex)["Age"].(z3.Int).LT(argValue)
+	prevCond3 := personAge(argPeople, argIndex).LT(argValue)
 	sCtx.Solver.Assert(prevCond3.Not())
 
 	return "!(index < 0 || index >= len(people)) && !(age > value) && !(age < value)"
@@ -0,0 +1,189 @@
+// Command explore runs a function of a Go package symbolically and prints its feasible paths
+// with the inputs leading to them. With -tests it prints a test file covering the paths,
+// with -mcdc it prints which conditions of the decisions the paths show to affect them.
+// Methods are named by their types, as Circle.Area or (*Circle).Scale:
+//
+//	go run ./cmd/explore -dir examples/shapes -func Kind -tests
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	"github.com/vldF/symbolic_execution_course/constraints/engine"
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+	"github.com/vldF/symbolic_execution_course/constraints/smt/types"
+	"github.com/vldF/symbolic_execution_course/constraints/testgen"
+	"math"
+	"math/bits"
+	"os"
+	"strings"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "directory of the package or a Go file making the package")
+	function := flag.String("func", "", "function or method to explore")
+	tests := flag.Bool("tests", false, "print the tests covering the paths")
+	mcdc := flag.Bool("mcdc", false, "print the MC/DC coverage of the decisions")
+	bound := flag.Int("bound", engine.DefaultLoopBound, "number of iterations of the loops")
+	depth := flag.Int("depth", engine.DefaultCallDepth, "number of nested calls")
+	recursion := flag.Int("recursion", engine.DefaultRecursionBound, "number of recursive calls of a function active at once")
+	pointers := flag.Int("pointers", engine.DefaultPointerDepth, "number of pointers followed from an input, the deeper pointers are nil")
+	timeout := flag.Duration("timeout", engine.DefaultSolverTimeout, "time of a solver check, the paths whose checks run out of it are incomplete")
+	induction := flag.Int("induction", 0, "prove the invariants stated above the loops by k-induction with this k")
+	summarize := flag.Bool("summarize", true, "run the loops with affine updates at once instead of unrolling them")
+	summaries := flag.Bool("summaries", true, "instantiate the summaries of the called functions instead of exploring them at every call")
+	summary := flag.Bool("summary", false, "print the summary of the function")
+	symbolicGlobals := flag.Bool("symbolic-globals", false, "start with any values of the package variables instead of their initializers")
+	candidates := flag.String("candidates", "", "functions the function parameters may be, as f=double,square;g=inc, the other function parameters are uninterpreted")
+	policy := flag.String("policy", "stop", "what happens at the calls of the functions which can't be explored: stop, abstract or concretize. Only the library functions listed by engine.StandardImplementations, as strconv.Atoi or math.Pow, can be concretized, the paths concretizing the others are unsupported")
+	policies := flag.String("policies", "", "policies of the functions which can't be explored, as strconv.Itoa=concretize;math.Sin=abstract")
+	flag.Parse()
+
+	if *function == "" {
+		fmt.Fprintln(os.Stderr, "usage: explore [-dir <package directory>] -func <function> [-bound <iterations>] [-depth <calls>] [-recursion <calls>] [-pointers <depth>] [-timeout <duration>] [-summarize=false] [-summaries=false] [-summary] [-candidates <parameter>=<function>,...;...] [-symbolic-globals] [-policy stop|abstract|concretize] [-policies <function>=<policy>;...] [-induction <k>] [-tests] [-mcdc]")
+		os.Exit(2)
+	}
+
+	defaultPolicy, err := engine.ParsePolicy(*policy)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	functionPolicies, err := parsePolicies(*policies)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	pkg, explorer, paths, err := explore(*dir, *function, func(explorer *engine.Explorer) {
+		explorer.LoopBound = *bound
+		explorer.SummarizeLoops = *summarize
+		explorer.CallDepth = *depth
+		explorer.RecursionBound = *recursion
+		explorer.PointerDepth = *pointers
+		explorer.SolverTimeout = *timeout
+		if !*summaries {
+			explorer.Summaries = nil
+		}
+		explorer.Candidates = parseCandidates(*candidates)
+		explorer.SymbolicGlobals = *symbolicGlobals
+		explorer.DefaultPolicy = defaultPolicy
+		explorer.Policies = functionPolicies
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	cases := make([]testgen.Case, 0, len(paths))
+	for _, path := range paths {
+		fmt.Println(path)
+		// the incomplete paths have no outcome to check
+		if path.Status != engine.Incomplete && path.Status != engine.Unsupported {
+			cases = append(cases, path.Test)
+		}
+	}
+
+	if *summary {
+		s, err := explorer.Summarize(*function)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+
+		fmt.Println()
+		fmt.Println(s)
+	}
+
+	if *induction > 0 {
+		proofs, err := explorer.ProveInvariants(*function, *induction)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+
+		fmt.Println()
+		for _, proof := range proofs {
+			fmt.Println(proof)
+		}
+	}
+
+	if *mcdc {
+		for _, coverage := range explorer.MCDC(paths) {
+			fmt.Println()
+			fmt.Println(coverage)
+		}
+	}
+
+	// the paths without tests make no test file
+	if file := testgen.RenderFile(pkg.Package.Name(), cases, explorer.Notes()...); *tests && file != "" {
+		fmt.Println()
+		fmt.Print(file)
+	}
+}
+
+// parseCandidates parses the candidates of the function parameters, as f=double,square;g=inc
+func parseCandidates(value string) map[string][]string {
+	result := make(map[string][]string)
+	for _, param := range strings.Split(value, ";") {
+		name, functions, ok := strings.Cut(param, "=")
+		if !ok {
+			continue
+		}
+		result[strings.TrimSpace(name)] = strings.Split(strings.ReplaceAll(functions, " ", ""), ",")
+	}
+
+	return result
+}
+
+// parsePolicies parses the policies of the functions, as strconv.Itoa=concretize;math.Sin=abstract
+func parsePolicies(value string) (map[string]engine.Policy, error) {
+	result := make(map[string]engine.Policy)
+	for _, function := range strings.Split(value, ";") {
+		name, policyName, ok := strings.Cut(function, "=")
+		if !ok {
+			continue
+		}
+		policy, err := engine.ParsePolicy(strings.TrimSpace(policyName))
+		if err != nil {
+			return nil, err
+		}
+		result[strings.TrimSpace(name)] = policy
+	}
+
+	return result, nil
+}
+
+func explore(dir string, function string, configure func(explorer *engine.Explorer)) (*types.LoadedPackage, *engine.Explorer, []engine.Path, error) {
+	pkg, err := types.Load(dir)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	ctx := z3.NewContext(&z3.Config{})
+	sCtx := &smt.SymContext{
+		Solver: z3.NewSolver(ctx),
+		Ctx:    ctx,
+		TypesCtx: smt.TypesContext{
+			MaxInt:      math.MaxInt32,
+			MinInt:      math.MinInt32,
+			IntSize:     bits.UintSize,
+			MaxFloat64:  math.MaxFloat64,
+			MinFloat64:  -math.MaxFloat64,
+			Float64Size: 64,
+
+			MaxStringLength: 8,
+			MaxSliceLength:  4,
+		},
+	}
+
+	explorer, err := engine.NewExplorer(sCtx, pkg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	configure(explorer)
+
+	paths, err := explorer.Explore(function)
+	return pkg, explorer, paths, err
+}
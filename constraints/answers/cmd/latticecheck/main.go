@@ -0,0 +1,60 @@
+// Command latticecheck checks that a type hierarchy encoded into the solver is a semilattice.
+// It reports counterexamples to reflexivity, antisymmetry and transitivity of subtyping
+// and pairs of types without the least upper bound:
+//
+//	go run ./cmd/latticecheck hierarchies/java.txt
+package main
+
+import (
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+	"github.com/vldF/symbolic_execution_course/constraints/smt/types"
+	"os"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: latticecheck <hierarchy description>")
+		os.Exit(2)
+	}
+
+	violations, err := check(os.Args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if len(violations) == 0 {
+		fmt.Println("the hierarchy is a semilattice")
+		return
+	}
+
+	for _, violation := range violations {
+		fmt.Println(violation)
+	}
+	os.Exit(1)
+}
+
+func check(path string) ([]types.Violation, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hierarchy, err := types.ParseHierarchy(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	ctx := z3.NewContext(&z3.Config{})
+	sCtx := &smt.SymContext{Solver: z3.NewSolver(ctx), Ctx: ctx}
+
+	ts, err := types.Encode(sCtx, hierarchy)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return ts.CheckLattice()
+}
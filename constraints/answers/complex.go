@@ -3,12 +3,14 @@ package main
 import (
 	"fmt"
 	"github.com/vldF/symbolic_execution_course/constraints/smt"
+	"reflect"
 )
 
 func solveComplex() {
 	solveBasicComplexOperations()
 	solveComplexMagnitude()
 	solveComplexComparison()
+	solveComplexOrder()
 	solveComplexOperations()
 	solveNestedComplexOperations()
 }
@@ -96,14 +98,17 @@ func complexMagnitude1(ctx *smt.SymContext) string {
 
 func solveComplexComparison() {
 	fmt.Println("func complexComparison(a complex128, b complex128) string")
-	runForCase(complexComparison1)
-	runForCase(complexComparison2)
-	runForCase(complexComparison3)
+	runForTestCase("complexComparison", complexComparison1)
+	runForTestCase("complexComparison", complexComparison2)
+	runForTestCase("complexComparison", complexComparison3)
+
+	// the engine follows the calls of complexMagnitude instead of having them inlined by hand
+	exploreFunction("../complex.go", "complexComparison")
 }
 
 func complexComparison1(ctx *smt.SymContext) string {
-	argA := ctx.NewComplexConst("a")
-	argB := ctx.NewComplexConst("b")
+	argA := ctx.NewComplexArgument("a")
+	argB := ctx.NewComplexArgument("b")
 
 	// inlined call of complexMagnitude
 	magnitudeArgA := argA.Real().Mul(argA.Real()).Add(argA.Imag().Mul(argA.Imag()))
@@ -112,12 +117,14 @@ func complexComparison1(ctx *smt.SymContext) string {
 	cond := magnitudeArgA.GT(magnitudeArgB)
 	ctx.Solver.Assert(cond)
 
+	ctx.AddStringResult(ctx.NewStringConst("Magnitude of a is greater than b"))
+
 	return "magA > magB"
 }
 
 func complexComparison2(ctx *smt.SymContext) string {
-	argA := ctx.NewComplexConst("a")
-	argB := ctx.NewComplexConst("b")
+	argA := ctx.NewComplexArgument("a")
+	argB := ctx.NewComplexArgument("b")
 
 	// inlined call of complexMagnitude
 	magnitudeArgA := argA.Real().Mul(argA.Real()).Add(argA.Imag().Mul(argA.Imag()))
@@ -129,12 +136,14 @@ func complexComparison2(ctx *smt.SymContext) string {
 	cond := magnitudeArgA.LT(magnitudeArgB)
 	ctx.Solver.Assert(cond)
 
+	ctx.AddStringResult(ctx.NewStringConst("Magnitude of b is greater than a"))
+
 	return "!(magA > magB) && (magA < magB)"
 }
 
 func complexComparison3(ctx *smt.SymContext) string {
-	argA := ctx.NewComplexConst("a")
-	argB := ctx.NewComplexConst("b")
+	argA := ctx.NewComplexArgument("a")
+	argB := ctx.NewComplexArgument("b")
 
 	// inlined call of complexMagnitude
 	magnitudeArgA := argA.Real().Mul(argA.Real()).Add(argA.Imag().Mul(argA.Imag()))
@@ -146,9 +155,87 @@ func complexComparison3(ctx *smt.SymContext) string {
 	prevCond2 := magnitudeArgA.LT(magnitudeArgB)
 	ctx.Solver.Assert(prevCond2.Not())
 
+	ctx.AddStringResult(ctx.NewStringConst("Magnitudes are equal"))
+
 	return "!(magA > magB) && !(magA < magB)"
 }
 
+// complexComparisonResult encodes the string returned by complexComparison on all of its paths at once,
+// so callers can branch on it
+func complexComparisonResult(ctx *smt.SymContext, argA smt.SymComplex, argB smt.SymComplex) smt.SymString {
+	// inlined call of complexMagnitude
+	magnitudeArgA := argA.Real().Mul(argA.Real()).Add(argA.Imag().Mul(argA.Imag()))
+	magnitudeArgB := argB.Real().Mul(argB.Real()).Add(argB.Imag().Mul(argB.Imag()))
+
+	resultEqual := ctx.NewStringConst("Magnitudes are equal")
+	resultLess := ctx.StringIfThenElse(magnitudeArgA.LT(magnitudeArgB), ctx.NewStringConst("Magnitude of b is greater than a"), resultEqual)
+
+	return ctx.StringIfThenElse(magnitudeArgA.GT(magnitudeArgB), ctx.NewStringConst("Magnitude of a is greater than b"), resultLess)
+}
+
+//	func complexOrder(a complex128, b complex128) int {
+//		switch complexComparison(a, b) {
+//		case "Magnitude of a is greater than b":
+//			return 1								(1)
+//		case "Magnitude of b is greater than a":
+//			return -1								(2)
+//		}
+//		return 0									(3)
+//	}
+func solveComplexOrder() {
+	fmt.Println("func complexOrder(a complex128, b complex128) int")
+	runForTestCase("complexOrder", complexOrder1)
+	runForTestCase("complexOrder", complexOrder2)
+	runForTestCase("complexOrder", complexOrder3)
+
+	exploreFunction("../complex.go", "complexOrder")
+}
+
+func complexOrder1(ctx *smt.SymContext) string {
+	argA := ctx.NewComplexArgument("a")
+	argB := ctx.NewComplexArgument("b")
+
+	message := complexComparisonResult(ctx, argA, argB)
+	cond := message.Eq(ctx.NewStringConst("Magnitude of a is greater than b"))
+	ctx.Solver.Assert(cond)
+
+	ctx.AddResult(ctx.Ctx.FromInt(1, ctx.Ctx.IntSort()), reflect.TypeOf(0))
+
+	return "complexComparison(a, b) == \"Magnitude of a is greater than b\""
+}
+
+func complexOrder2(ctx *smt.SymContext) string {
+	argA := ctx.NewComplexArgument("a")
+	argB := ctx.NewComplexArgument("b")
+
+	message := complexComparisonResult(ctx, argA, argB)
+	prevCond := message.Eq(ctx.NewStringConst("Magnitude of a is greater than b"))
+	ctx.Solver.Assert(prevCond.Not())
+
+	cond := message.Eq(ctx.NewStringConst("Magnitude of b is greater than a"))
+	ctx.Solver.Assert(cond)
+
+	ctx.AddResult(ctx.Ctx.FromInt(-1, ctx.Ctx.IntSort()), reflect.TypeOf(0))
+
+	return "!(complexComparison(a, b) == \"Magnitude of a is greater than b\") && (complexComparison(a, b) == \"Magnitude of b is greater than a\")"
+}
+
+func complexOrder3(ctx *smt.SymContext) string {
+	argA := ctx.NewComplexArgument("a")
+	argB := ctx.NewComplexArgument("b")
+
+	message := complexComparisonResult(ctx, argA, argB)
+	prevCond1 := message.Eq(ctx.NewStringConst("Magnitude of a is greater than b"))
+	ctx.Solver.Assert(prevCond1.Not())
+
+	prevCond2 := message.Eq(ctx.NewStringConst("Magnitude of b is greater than a"))
+	ctx.Solver.Assert(prevCond2.Not())
+
+	ctx.AddResult(ctx.Ctx.FromInt(0, ctx.Ctx.IntSort()), reflect.TypeOf(0))
+
+	return "!(complexComparison(a, b) == \"Magnitude of a is greater than b\") && !(complexComparison(a, b) == \"Magnitude of b is greater than a\")"
+}
+
 //	func complexOperations(a complex128, b complex128) complex128 {
 //		if real(a) == 0 && imag(a) == 0 {
 //			return b								(1)
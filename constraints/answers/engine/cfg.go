@@ -0,0 +1,924 @@
+package engine
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// Function is the control flow graph of a Go function. Blocks consist of straight-line nodes
+// and end with a terminator. Calls are hoisted out of expressions into nodes of their own,
+// so the interpreter never has to stop in the middle of an expression to run a callee
+type Function struct {
+	Name      string
+	Object    *types.Func
+	Signature *types.Signature
+	Entry     *Block
+	Blocks    []*Block
+
+	// Decisions are the compound conditions of the function in the source order.
+	// Loops are the for statements, the loops of ranges and gotos aren't listed
+	Decisions []*Decision
+	Loops     []*Loop
+
+	// callResults are the temporaries holding the results of the hoisted calls
+	callResults map[*ast.CallExpr][]*types.Var
+	// shortCircuits are the temporaries holding the values of && and || used as values
+	shortCircuits map[*ast.BinaryExpr]*types.Var
+	// literals are the functions of the function literals of the body, the literals nested in them are theirs
+	literals map[*ast.FuncLit]*Function
+}
+
+// Decision is a boolean expression with && or ||. Its conditions are the operands
+// which aren't && and || themselves, in the order of evaluation
+type Decision struct {
+	Pos        token.Position
+	Expr       ast.Expr
+	Conditions []ast.Expr
+
+	// the blocks the decision goes to when it holds and when it doesn't
+	then *Block
+	els  *Block
+}
+
+// Loop is a for statement, a range statement or a labeled statement a goto jumps back to. Every entering
+// of its body is an iteration. Loops with a summary may be run at once instead of iteration by iteration.
+// Only for statements have the statement, the loops of gotos have the header for the body
+type Loop struct {
+	Pos     token.Position
+	stmt    *ast.ForStmt
+	header  *Block
+	body    *Block
+	exit    *Block
+	summary *loopSummary
+}
+
+type Block struct {
+	Index int
+	Nodes []Node
+	Term  Terminator
+
+	// loop is the loop the block is the body of
+	loop *Loop
+}
+
+// Node is a straight-line step of a block: a simple statement (assignment, increment,
+// declaration or expression) or one of the synthetic nodes created by the builder
+type Node interface {
+	Pos() token.Pos
+}
+
+// callNode calls the function and stores its results into the temporaries
+type callNode struct {
+	call    *ast.CallExpr
+	results []*types.Var
+}
+
+// defineNode stores the value of the expression into the temporary
+type defineNode struct {
+	target *types.Var
+	value  ast.Expr
+}
+
+// bindNode binds the variable of a type switch clause. When the clause lists
+// the only type, the variable gets the value of that type
+type bindNode struct {
+	target *types.Var
+	value  *types.Var
+	types  []types.Type
+}
+
+// boolNode stores the constant into the temporary
+type boolNode struct {
+	target *types.Var
+	value  bool
+}
+
+// loopNode starts the loop, its iterations are counted from zero. The summarized loop
+// is run by the node and the state goes to the exit of the loop
+type loopNode struct {
+	loop *Loop
+	pos  token.Pos
+}
+
+// rangeNode starts the range loop: the temporary gets the iteration over the value of the range expression
+type rangeNode struct {
+	iteration *types.Var
+	value     ast.Expr
+}
+
+// elementNode assigns the key and the value of the current element of the iteration to the iteration
+// variables, define tells that the range statement declares them. Key and value are nil when omitted
+type elementNode struct {
+	iteration  *types.Var
+	key, value ast.Expr
+	define     bool
+	pos        token.Pos
+}
+
+// advanceNode moves the iteration to the next element
+type advanceNode struct {
+	iteration *types.Var
+	pos       token.Pos
+}
+
+// deferNode evaluates the function and the arguments of the deferred call, the call runs when the function
+// returns or panics. The literal is the function of the deferred function literal
+type deferNode struct {
+	call    *ast.CallExpr
+	literal *Function
+}
+
+func (node *callNode) Pos() token.Pos    { return node.call.Pos() }
+func (node *deferNode) Pos() token.Pos   { return node.call.Pos() }
+func (node *defineNode) Pos() token.Pos  { return node.value.Pos() }
+func (node *bindNode) Pos() token.Pos    { return node.target.Pos() }
+func (node *boolNode) Pos() token.Pos    { return node.target.Pos() }
+func (node *loopNode) Pos() token.Pos    { return node.pos }
+func (node *rangeNode) Pos() token.Pos   { return node.value.Pos() }
+func (node *elementNode) Pos() token.Pos { return node.pos }
+func (node *advanceNode) Pos() token.Pos { return node.pos }
+
+type Terminator interface {
+	Pos() token.Pos
+}
+
+type jump struct {
+	target *Block
+	pos    token.Pos
+}
+
+// branch goes to then when the condition holds and to els otherwise.
+// The condition is a boolean expression, a typeTest, a caseTest or a rangeTest. The branches on the conditions
+// of a decision refer to it and to the index of the condition
+type branch struct {
+	cond condition
+	then *Block
+	els  *Block
+
+	decision  *Decision
+	condition int
+}
+
+type condition interface {
+	Pos() token.Pos
+}
+
+// typeTest checks the dynamic type of the interface value, like a case of a type switch does.
+// The nil type stands for the case nil
+type typeTest struct {
+	value *types.Var
+	types []types.Type
+	pos   token.Pos
+}
+
+// caseTest compares the tag of the switch with the expression of a case
+type caseTest struct {
+	tag   *types.Var
+	value ast.Expr
+}
+
+// rangeTest holds while the iteration of the range loop has elements left
+type rangeTest struct {
+	iteration *types.Var
+	pos       token.Pos
+}
+
+// ret returns the results, results are empty for the naked return and for functions without results
+type ret struct {
+	results []ast.Expr
+	pos     token.Pos
+}
+
+func (term *jump) Pos() token.Pos      { return term.pos }
+func (term *branch) Pos() token.Pos    { return term.cond.Pos() }
+func (term *ret) Pos() token.Pos       { return term.pos }
+func (test *typeTest) Pos() token.Pos  { return test.pos }
+func (test *caseTest) Pos() token.Pos  { return test.value.Pos() }
+func (test *rangeTest) Pos() token.Pos { return test.pos }
+
+// UnsupportedError is reported for the constructs the engine can't handle
+type UnsupportedError struct {
+	Pos     token.Position
+	Message string
+}
+
+func (err *UnsupportedError) Error() string {
+	return fmt.Sprintf("%s: %s", err.Pos, err.Message)
+}
+
+type builder struct {
+	fset *token.FileSet
+	info *types.Info
+	fn   *Function
+
+	current  *Block
+	temps    int
+	literals int
+	// err is the first error of the function literals built while hoisting
+	err error
+
+	// targets are the blocks break and continue go to in the enclosing statements, the innermost is the last.
+	// Switches have no continue target. label is the label of the statement being built, its targets take it
+	targets []targets
+	label   *types.Label
+	// fallthroughTo is the body of the next clause of the switch
+	fallthroughTo *Block
+
+	// labels are the blocks starting at the labeled statements, loopLabels are the labels gotos jump back to
+	labels     map[*types.Label]*Block
+	loopLabels map[*types.Label]bool
+}
+
+type targets struct {
+	label      *types.Label
+	breakTo    *Block
+	continueTo *Block
+}
+
+func buildFunction(fset *token.FileSet, info *types.Info, obj *types.Func, body *ast.BlockStmt) (*Function, error) {
+	return build(fset, info, &Function{Name: obj.Name(), Object: obj, Signature: obj.Type().(*types.Signature)}, body)
+}
+
+// buildLiteral builds the function literal, it has no object
+func buildLiteral(fset *token.FileSet, info *types.Info, name string, lit *ast.FuncLit) (*Function, error) {
+	return build(fset, info, &Function{Name: name, Signature: info.TypeOf(lit).(*types.Signature)}, lit.Body)
+}
+
+func build(fset *token.FileSet, info *types.Info, fn *Function, body *ast.BlockStmt) (*Function, error) {
+	fn.callResults = make(map[*ast.CallExpr][]*types.Var)
+	fn.shortCircuits = make(map[*ast.BinaryExpr]*types.Var)
+	fn.literals = make(map[*ast.FuncLit]*Function)
+	b := &builder{fset: fset, info: info, fn: fn, labels: make(map[*types.Label]*Block), loopLabels: loopLabels(info, body)}
+	b.fn.Entry = b.newBlock()
+	b.current = b.fn.Entry
+
+	if err := b.stmtList(body.List); err != nil {
+		return nil, err
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+	b.jumpTo(nil, body.Rbrace)
+
+	return b.fn, nil
+}
+
+// loopLabels returns the labels the gotos following them jump to, the labeled statements are loops
+func loopLabels(info *types.Info, body *ast.BlockStmt) map[*types.Label]bool {
+	result := make(map[*types.Label]bool)
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.BranchStmt:
+			if node.Tok != token.GOTO {
+				break
+			}
+			if label := info.Uses[node.Label].(*types.Label); label.Pos() < node.Pos() {
+				result[label] = true
+			}
+		}
+		return true
+	})
+
+	return result
+}
+
+func (b *builder) unsupported(pos token.Pos, format string, args ...any) error {
+	return &UnsupportedError{Pos: b.fset.Position(pos), Message: fmt.Sprintf(format, args...)}
+}
+
+func (b *builder) newBlock() *Block {
+	block := &Block{Index: len(b.fn.Blocks)}
+	b.fn.Blocks = append(b.fn.Blocks, block)
+
+	return block
+}
+
+func (b *builder) newTemp(t types.Type, pos token.Pos) *types.Var {
+	b.temps++
+	return types.NewVar(pos, nil, fmt.Sprintf("t%d", b.temps), t)
+}
+
+func (b *builder) add(node Node) {
+	b.current.Nodes = append(b.current.Nodes, node)
+}
+
+// jumpTo ends the current block with the jump. The nil target means the end of the function,
+// falling off it is the return without results
+func (b *builder) jumpTo(target *Block, pos token.Pos) {
+	if b.current.Term != nil {
+		return
+	}
+
+	if target == nil {
+		b.current.Term = &ret{pos: pos}
+	} else {
+		b.current.Term = &jump{target: target, pos: pos}
+	}
+}
+
+func (b *builder) stmtList(list []ast.Stmt) error {
+	for _, stmt := range list {
+		if err := b.stmt(stmt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *builder) stmt(stmt ast.Stmt) error {
+	switch stmt := stmt.(type) {
+	case *ast.BlockStmt:
+		return b.stmtList(stmt.List)
+	case *ast.EmptyStmt:
+		return nil
+	case *ast.ExprStmt:
+		b.hoist(stmt.X)
+		// the call is a node already
+		if call, ok := ast.Unparen(stmt.X).(*ast.CallExpr); ok && b.fn.callResults[call] != nil {
+			return nil
+		}
+		b.add(stmt)
+	case *ast.AssignStmt:
+		b.hoistAll(stmt.Rhs)
+		for _, lhs := range stmt.Lhs {
+			b.hoistOperands(lhs)
+		}
+		b.add(stmt)
+	case *ast.IncDecStmt:
+		b.hoistOperands(stmt.X)
+		b.add(stmt)
+	case *ast.DeclStmt:
+		decl := stmt.Decl.(*ast.GenDecl)
+		if decl.Tok != token.VAR {
+			// constants are folded by the type checker, local types need no code
+			return nil
+		}
+		for _, spec := range decl.Specs {
+			b.hoistAll(spec.(*ast.ValueSpec).Values)
+		}
+		b.add(stmt)
+	case *ast.ReturnStmt:
+		b.hoistAll(stmt.Results)
+		b.current.Term = &ret{results: stmt.Results, pos: stmt.Pos()}
+		b.current = b.newBlock()
+	case *ast.IfStmt:
+		return b.ifStmt(stmt)
+	case *ast.ForStmt:
+		return b.forStmt(stmt)
+	case *ast.RangeStmt:
+		return b.rangeStmt(stmt)
+	case *ast.BranchStmt:
+		return b.branchStmt(stmt)
+	case *ast.SwitchStmt:
+		return b.switchStmt(stmt)
+	case *ast.TypeSwitchStmt:
+		return b.typeSwitchStmt(stmt)
+	case *ast.LabeledStmt:
+		return b.labeledStmt(stmt)
+	case *ast.DeferStmt:
+		return b.deferStmt(stmt)
+	default:
+		return b.unsupported(stmt.Pos(), "unsupported statement %T", stmt)
+	}
+
+	return nil
+}
+
+// deferStmt hoists the calls of the receiver and of the arguments, they're evaluated by the defer statement
+func (b *builder) deferStmt(stmt *ast.DeferStmt) error {
+	node := &deferNode{call: stmt.Call}
+	switch fun := ast.Unparen(stmt.Call.Fun).(type) {
+	case *ast.FuncLit:
+		literal, err := b.literal(fun)
+		if err != nil {
+			return err
+		}
+		node.literal = literal
+	case *ast.SelectorExpr:
+		b.hoist(fun.X)
+	}
+
+	b.hoistAll(stmt.Call.Args)
+	b.add(node)
+	return nil
+}
+
+// literal builds the function literal, it's named after the function the way the compiler names it
+func (b *builder) literal(lit *ast.FuncLit) (*Function, error) {
+	if fn, ok := b.fn.literals[lit]; ok {
+		return fn, nil
+	}
+
+	b.literals++
+	fn, err := buildLiteral(b.fset, b.info, fmt.Sprintf("%s.func%d", b.fn.Name, b.literals), lit)
+	if err != nil {
+		return nil, err
+	}
+	b.fn.literals[lit] = fn
+
+	return fn, nil
+}
+
+func (b *builder) ifStmt(stmt *ast.IfStmt) error {
+	if stmt.Init != nil {
+		if err := b.stmt(stmt.Init); err != nil {
+			return err
+		}
+	}
+	then := b.newBlock()
+	after := b.newBlock()
+	els := after
+	if stmt.Else != nil {
+		els = b.newBlock()
+	}
+	b.decision(stmt.Cond, then, els)
+
+	b.current = then
+	if err := b.stmt(stmt.Body); err != nil {
+		return err
+	}
+	b.jumpTo(after, stmt.Body.Rbrace)
+
+	if stmt.Else != nil {
+		b.current = els
+		if err := b.stmt(stmt.Else); err != nil {
+			return err
+		}
+		b.jumpTo(after, stmt.Else.End())
+	}
+
+	b.current = after
+	return nil
+}
+
+// forStmt lowers the loop into the header checking the condition, the body and the post statement
+// jumping back to the header
+func (b *builder) forStmt(stmt *ast.ForStmt) error {
+	if stmt.Init != nil {
+		if err := b.stmt(stmt.Init); err != nil {
+			return err
+		}
+	}
+
+	header := b.newBlock()
+	body := b.newBlock()
+	post := b.newBlock()
+	after := b.newBlock()
+
+	loop := &Loop{
+		Pos:     b.fset.Position(stmt.Pos()),
+		stmt:    stmt,
+		header:  header,
+		body:    body,
+		exit:    after,
+		summary: b.summarizeLoop(stmt),
+	}
+	body.loop = loop
+	b.fn.Loops = append(b.fn.Loops, loop)
+
+	b.add(&loopNode{loop: loop, pos: stmt.Pos()})
+	b.jumpTo(header, stmt.Pos())
+
+	b.current = header
+	if stmt.Cond != nil {
+		b.decision(stmt.Cond, body, after)
+	} else {
+		b.jumpTo(body, stmt.Pos())
+	}
+
+	b.current = body
+	b.pushTargets(targets{breakTo: after, continueTo: post})
+	defer b.popTargets()
+
+	if err := b.stmt(stmt.Body); err != nil {
+		return err
+	}
+	b.jumpTo(post, stmt.Body.Rbrace)
+
+	b.current = post
+	if stmt.Post != nil {
+		if err := b.stmt(stmt.Post); err != nil {
+			return err
+		}
+	}
+	b.jumpTo(header, stmt.Body.Rbrace)
+
+	b.current = after
+	return nil
+}
+
+// pushTargets enters the loop or the switch, its targets take the label of the statement
+func (b *builder) pushTargets(t targets) {
+	t.label, b.label = b.label, nil
+	b.targets = append(b.targets, t)
+}
+
+func (b *builder) popTargets() {
+	b.targets = b.targets[:len(b.targets)-1]
+}
+
+// rangeStmt lowers the range loop into the header testing that elements are left, the body starting
+// with the assignment of the iteration variables and the advance to the next element jumping back to the header
+func (b *builder) rangeStmt(stmt *ast.RangeStmt) error {
+	b.hoist(stmt.X)
+	iteration := b.newTemp(b.info.TypeOf(stmt.X), stmt.X.Pos())
+	b.add(&rangeNode{iteration: iteration, value: stmt.X})
+
+	header := b.newBlock()
+	body := b.newBlock()
+	post := b.newBlock()
+	after := b.newBlock()
+
+	loop := &Loop{Pos: b.fset.Position(stmt.Pos()), header: header, body: body, exit: after}
+	body.loop = loop
+	b.add(&loopNode{loop: loop, pos: stmt.Pos()})
+	b.jumpTo(header, stmt.Pos())
+
+	b.current = header
+	b.current.Term = &branch{cond: &rangeTest{iteration: iteration, pos: stmt.Pos()}, then: body, els: after}
+
+	b.current = body
+	b.pushTargets(targets{breakTo: after, continueTo: post})
+	defer b.popTargets()
+
+	if stmt.Tok != token.DEFINE {
+		b.hoistOperands(stmt.Key)
+		b.hoistOperands(stmt.Value)
+	}
+	b.add(&elementNode{iteration: iteration, key: stmt.Key, value: stmt.Value, define: stmt.Tok == token.DEFINE, pos: stmt.Pos()})
+	if err := b.stmt(stmt.Body); err != nil {
+		return err
+	}
+	b.jumpTo(post, stmt.Body.Rbrace)
+
+	b.current = post
+	b.add(&advanceNode{iteration: iteration, pos: stmt.Body.Rbrace})
+	b.jumpTo(header, stmt.Body.Rbrace)
+
+	b.current = after
+	return nil
+}
+
+func (b *builder) branchStmt(stmt *ast.BranchStmt) error {
+	var label *types.Label
+	if stmt.Label != nil {
+		label = b.info.Uses[stmt.Label].(*types.Label)
+	}
+
+	var target *Block
+	switch stmt.Tok {
+	case token.GOTO:
+		target = b.labelBlock(label)
+	case token.FALLTHROUGH:
+		target = b.fallthroughTo
+	default:
+		for i := len(b.targets) - 1; i >= 0 && target == nil; i-- {
+			if label != nil && b.targets[i].label != label {
+				continue
+			}
+			switch stmt.Tok {
+			case token.BREAK:
+				target = b.targets[i].breakTo
+			case token.CONTINUE:
+				target = b.targets[i].continueTo
+			default:
+				return b.unsupported(stmt.Pos(), "unsupported statement %s", stmt.Tok)
+			}
+		}
+	}
+	if target == nil {
+		return b.unsupported(stmt.Pos(), "%s outside of a loop", stmt.Tok)
+	}
+
+	b.jumpTo(target, stmt.Pos())
+	b.current = b.newBlock()
+
+	return nil
+}
+
+// labeledStmt starts a block at the label for the gotos. The statement a goto jumps back to is a loop,
+// it's entered by the loop node like a for statement. Loops and switches take the label for break and continue
+func (b *builder) labeledStmt(stmt *ast.LabeledStmt) error {
+	label := b.info.Defs[stmt.Label].(*types.Label)
+	block := b.labelBlock(label)
+	if b.loopLabels[label] {
+		loop := &Loop{Pos: b.fset.Position(stmt.Pos()), header: block, body: block}
+		block.loop = loop
+		b.add(&loopNode{loop: loop, pos: stmt.Pos()})
+	}
+	b.jumpTo(block, stmt.Pos())
+	b.current = block
+
+	switch stmt.Stmt.(type) {
+	case *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+		b.label = label
+	}
+	return b.stmt(stmt.Stmt)
+}
+
+func (b *builder) labelBlock(label *types.Label) *Block {
+	block, ok := b.labels[label]
+	if !ok {
+		block = b.newBlock()
+		b.labels[label] = block
+	}
+
+	return block
+}
+
+// switchStmt lowers the switch into a chain of tests of the cases in the source order, the expressions
+// of a case are compared with the tag one by one. The tagless switch tests them as conditions.
+// The default clause is taken when no case matches, fallthrough goes to the body of the next clause
+func (b *builder) switchStmt(stmt *ast.SwitchStmt) error {
+	if stmt.Init != nil {
+		if err := b.stmt(stmt.Init); err != nil {
+			return err
+		}
+	}
+
+	var tag *types.Var
+	if stmt.Tag != nil {
+		b.hoist(stmt.Tag)
+		tag = b.newTemp(b.info.TypeOf(stmt.Tag), stmt.Tag.Pos())
+		b.add(&defineNode{target: tag, value: stmt.Tag})
+	}
+
+	after := b.newBlock()
+	b.pushTargets(targets{breakTo: after})
+	defer b.popTargets()
+
+	bodies := make([]*Block, len(stmt.Body.List))
+	for i := range bodies {
+		bodies[i] = b.newBlock()
+	}
+
+	fallback := after
+	for i, clause := range stmt.Body.List {
+		clause := clause.(*ast.CaseClause)
+		if clause.List == nil {
+			fallback = bodies[i]
+			continue
+		}
+
+		for _, expr := range clause.List {
+			next := b.newBlock()
+			if tag == nil {
+				b.decision(expr, bodies[i], next)
+			} else {
+				b.hoist(expr)
+				b.current.Term = &branch{cond: &caseTest{tag: tag, value: expr}, then: bodies[i], els: next}
+			}
+			b.current = next
+		}
+	}
+	b.jumpTo(fallback, stmt.Body.Rbrace)
+
+	defer func(fallthroughTo *Block) { b.fallthroughTo = fallthroughTo }(b.fallthroughTo)
+	for i, clause := range stmt.Body.List {
+		b.fallthroughTo = nil
+		if i+1 < len(bodies) {
+			b.fallthroughTo = bodies[i+1]
+		}
+
+		b.current = bodies[i]
+		if err := b.stmtList(clause.(*ast.CaseClause).Body); err != nil {
+			return err
+		}
+		b.jumpTo(after, clause.End())
+	}
+
+	b.current = after
+	return nil
+}
+
+// typeSwitchStmt lowers the type switch into a chain of type tests, one per clause in the source order
+func (b *builder) typeSwitchStmt(stmt *ast.TypeSwitchStmt) error {
+	if stmt.Init != nil {
+		if err := b.stmt(stmt.Init); err != nil {
+			return err
+		}
+	}
+
+	var assert *ast.TypeAssertExpr
+	switch assign := stmt.Assign.(type) {
+	case *ast.ExprStmt:
+		assert = assign.X.(*ast.TypeAssertExpr)
+	case *ast.AssignStmt:
+		assert = assign.Rhs[0].(*ast.TypeAssertExpr)
+	}
+
+	b.hoist(assert.X)
+	value := b.newTemp(b.info.TypeOf(assert.X), assert.X.Pos())
+	b.add(&defineNode{target: value, value: assert.X})
+
+	after := b.newBlock()
+	b.pushTargets(targets{breakTo: after})
+	defer b.popTargets()
+
+	var defaultClause *ast.CaseClause
+	for _, stmt := range stmt.Body.List {
+		clause := stmt.(*ast.CaseClause)
+		if clause.List == nil {
+			defaultClause = clause
+			continue
+		}
+
+		caseTypes := make([]types.Type, 0, len(clause.List))
+		for _, expr := range clause.List {
+			if b.info.Types[expr].IsNil() {
+				caseTypes = append(caseTypes, nil)
+			} else {
+				caseTypes = append(caseTypes, b.info.TypeOf(expr))
+			}
+		}
+
+		body := b.newBlock()
+		next := b.newBlock()
+		b.current.Term = &branch{cond: &typeTest{value: value, types: caseTypes, pos: clause.Pos()}, then: body, els: next}
+
+		b.current = body
+		if err := b.caseClause(clause, value, caseTypes, after); err != nil {
+			return err
+		}
+		b.current = next
+	}
+
+	if defaultClause != nil {
+		if err := b.caseClause(defaultClause, value, nil, after); err != nil {
+			return err
+		}
+	}
+	b.jumpTo(after, stmt.Body.Rbrace)
+
+	b.current = after
+	return nil
+}
+
+func (b *builder) caseClause(clause *ast.CaseClause, value *types.Var, caseTypes []types.Type, after *Block) error {
+	if obj, ok := b.info.Implicits[clause].(*types.Var); ok {
+		b.add(&bindNode{target: obj, value: value, types: caseTypes})
+	}
+
+	if err := b.stmtList(clause.Body); err != nil {
+		return err
+	}
+	b.jumpTo(after, clause.End())
+
+	return nil
+}
+
+// isLogical checks that the expression is built with && or ||, possibly negated
+func isLogical(expr ast.Expr) bool {
+	switch expr := expr.(type) {
+	case *ast.BinaryExpr:
+		return expr.Op == token.LAND || expr.Op == token.LOR
+	case *ast.UnaryExpr:
+		return expr.Op == token.NOT && isLogical(ast.Unparen(expr.X))
+	default:
+		return false
+	}
+}
+
+// decision ends the current block with the branches on the condition. && and || are control flow,
+// so the right operand is evaluated only when the left one doesn't decide the outcome
+func (b *builder) decision(cond ast.Expr, then *Block, els *Block) {
+	var decision *Decision
+	if isLogical(ast.Unparen(cond)) {
+		decision = &Decision{Pos: b.fset.Position(cond.Pos()), Expr: cond, then: then, els: els}
+		b.fn.Decisions = append(b.fn.Decisions, decision)
+	}
+
+	b.condition(cond, then, els, decision)
+}
+
+func (b *builder) condition(cond ast.Expr, then *Block, els *Block, decision *Decision) {
+	switch expr := ast.Unparen(cond).(type) {
+	case *ast.UnaryExpr:
+		if expr.Op == token.NOT && isLogical(ast.Unparen(expr.X)) {
+			b.condition(expr.X, els, then, decision)
+			return
+		}
+	case *ast.BinaryExpr:
+		switch expr.Op {
+		case token.LAND:
+			right := b.newBlock()
+			b.condition(expr.X, right, els, decision)
+			b.current = right
+			b.condition(expr.Y, then, els, decision)
+			return
+		case token.LOR:
+			right := b.newBlock()
+			b.condition(expr.X, then, right, decision)
+			b.current = right
+			b.condition(expr.Y, then, els, decision)
+			return
+		}
+	}
+
+	b.hoist(cond)
+	term := &branch{cond: cond, then: then, els: els, decision: decision, condition: -1}
+	if decision != nil {
+		term.condition = len(decision.Conditions)
+		decision.Conditions = append(decision.Conditions, cond)
+	}
+	b.current.Term = term
+}
+
+// shortCircuit lowers && or || used as a value into branches storing the value into a temporary
+func (b *builder) shortCircuit(expr *ast.BinaryExpr) {
+	result := b.newTemp(types.Typ[types.Bool], expr.Pos())
+	b.fn.shortCircuits[expr] = result
+
+	then := b.newBlock()
+	els := b.newBlock()
+	after := b.newBlock()
+	b.decision(expr, then, els)
+
+	b.current = then
+	b.add(&boolNode{target: result, value: true})
+	b.jumpTo(after, expr.End())
+
+	b.current = els
+	b.add(&boolNode{target: result, value: false})
+	b.jumpTo(after, expr.End())
+
+	b.current = after
+}
+
+func (b *builder) hoistAll(exprs []ast.Expr) {
+	for _, expr := range exprs {
+		b.hoist(expr)
+	}
+}
+
+// hoistOperands hoists calls from the operands of the assignment target, but not the target itself
+func (b *builder) hoistOperands(expr ast.Expr) {
+	switch expr := ast.Unparen(expr).(type) {
+	case *ast.SelectorExpr:
+		b.hoist(expr.X)
+	case *ast.StarExpr:
+		b.hoist(expr.X)
+	case *ast.IndexExpr:
+		b.hoist(expr.X)
+		b.hoist(expr.Index)
+	}
+}
+
+// hoist creates call nodes for the calls of the expression in the order of evaluation
+func (b *builder) hoist(expr ast.Node) {
+	if expr == nil {
+		return
+	}
+
+	ast.Inspect(expr, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FuncLit:
+			if _, err := b.literal(node); err != nil && b.err == nil {
+				b.err = err
+			}
+			return false
+		case *ast.BinaryExpr:
+			if isLogical(node) {
+				b.shortCircuit(node)
+				return false
+			}
+		case *ast.CallExpr:
+			if node == expr {
+				return true
+			}
+			b.hoist(node)
+			return false
+		}
+		return true
+	})
+
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || b.isBuiltinOrConversion(call) {
+		return
+	}
+
+	results := make([]*types.Var, 0)
+	switch t := b.info.TypeOf(call).(type) {
+	case *types.Tuple:
+		for i := 0; i < t.Len(); i++ {
+			results = append(results, b.newTemp(t.At(i).Type(), call.Pos()))
+		}
+	default:
+		if t != nil {
+			results = append(results, b.newTemp(t, call.Pos()))
+		}
+	}
+
+	b.fn.callResults[call] = results
+	b.add(&callNode{call: call, results: results})
+}
+
+func (b *builder) isBuiltinOrConversion(call *ast.CallExpr) bool {
+	fun := b.info.Types[call.Fun]
+	return fun.IsType() || fun.IsBuiltin()
+}
@@ -0,0 +1,286 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+	smttypes "github.com/vldF/symbolic_execution_course/constraints/smt/types"
+	"github.com/vldF/symbolic_execution_course/constraints/testgen"
+	"go/ast"
+	"go/types"
+	"reflect"
+)
+
+// closure is a function value: the function and the cells of the variables it captures, it shares them
+// with the frame creating it. Declared functions capture nothing, the nil function has no function
+type closure struct {
+	smt.SymValueBase
+	fn       *Function
+	captured map[types.Object]int
+	typeArgs map[*types.TypeParam]types.Type
+}
+
+// candidateFunction is the function argument which is one of the candidate functions, choice is its index
+type candidateFunction struct {
+	smt.SymValueBase
+	candidates []*types.Func
+	choice     z3.Int
+}
+
+// capture returns the cells of the variables of the frame, the function literals created by it get them
+func capture(fr *frame) map[types.Object]int {
+	captured := make(map[types.Object]int, len(fr.locals))
+	for obj, address := range fr.locals {
+		captured[obj] = address
+	}
+	return captured
+}
+
+func (e *Explorer) evalFuncLit(st *State, lit *ast.FuncLit) (smt.SymValue, error) {
+	fr := st.frame()
+	fn, ok := fr.fn.literals[lit]
+	if !ok {
+		return nil, e.unsupported(lit, "function literal isn't built with %s", fr.fn.Name)
+	}
+
+	return closure{fn: fn, captured: capture(fr), typeArgs: fr.typeArgs}, nil
+}
+
+// functionValue is the value of the declared function used without calling it
+func (e *Explorer) functionValue(ident *ast.Ident, obj *types.Func) (smt.SymValue, error) {
+	if obj.Type().(*types.Signature).TypeParams().Len() > 0 {
+		return nil, e.unsupported(ident, "value of the generic function %s", ident.Name)
+	}
+
+	fn, err := e.function(obj)
+	if err != nil {
+		return nil, e.unsupported(ident, "value of %s: %s", obj.FullName(), err)
+	}
+
+	return closure{fn: fn}, nil
+}
+
+// isFunctionValue tells that the called expression is a function value rather than a declared function or method
+func (e *Explorer) isFunctionValue(fun ast.Expr) bool {
+	switch fun := fun.(type) {
+	case *ast.Ident:
+		_, ok := e.pkg.Info.Uses[fun].(*types.Func)
+		return !ok
+	case *ast.SelectorExpr:
+		if selection, ok := e.pkg.Info.Selections[fun]; ok {
+			return selection.Kind() == types.FieldVal
+		}
+		_, ok := e.pkg.Info.Uses[fun.Sel].(*types.Func)
+		return !ok
+	default:
+		return true
+	}
+}
+
+// callValue calls the function value. The closure enters its function with the captured variables, the candidate
+// function forks the state for every candidate and the uninterpreted function returns its results at once.
+// Calling the nil function panics
+func (e *Explorer) callValue(st *State, node *callNode, fun ast.Expr) ([]*State, error) {
+	value, err := e.eval(st, fun)
+	if err != nil {
+		return nil, err
+	}
+
+	signature := e.typeOf(st, fun).Underlying().(*types.Signature)
+	args, err := e.callArguments(st, node.call, signature, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch value := value.(type) {
+	case closure:
+		if value.fn == nil {
+			if err := e.raise(st, nil, nilDereference); err != nil {
+				return nil, err
+			}
+			return nil, errStopped
+		}
+		return []*State{st}, e.enterClosure(st, node, value, args)
+	case candidateFunction:
+		return e.callCandidate(st, node, value, args)
+	case smt.SymFunction:
+		results, err := value.Call(args)
+		if err != nil {
+			return nil, e.unsupported(node.call, "call of %s: %s", types.ExprString(fun), err)
+		}
+		for i, result := range node.results {
+			st.define(result, results[i])
+		}
+		return []*State{st}, nil
+	default:
+		return nil, e.unsupported(node.call, "call of %T", value)
+	}
+}
+
+// enterClosure enters the function of the closure, the variables it captures are visible in its frame
+func (e *Explorer) enterClosure(st *State, node *callNode, c closure, args []smt.SymValue) error {
+	e.follow(c.fn.Object)
+	if err := e.checkBounds(st, node, c.fn); err != nil {
+		return err
+	}
+
+	if err := e.enterFunction(st, c.fn, node.results, nil, args, c.typeArgs); err != nil {
+		return err
+	}
+	for obj, address := range c.captured {
+		st.frame().locals[obj] = address
+	}
+
+	return nil
+}
+
+// callCandidate forks the state for every candidate the function argument may be and calls it
+func (e *Explorer) callCandidate(st *State, node *callNode, value candidateFunction, args []smt.SymValue) ([]*State, error) {
+	conds := make([]z3.Bool, 0, len(value.candidates))
+	for i := range value.candidates {
+		conds = append(conds, value.choice.Eq(e.intConst(int64(i))))
+	}
+
+	successors := make([]*State, 0, len(conds))
+	for _, i := range e.fork(st, conds...) {
+		successor := st.clone()
+		successor.take(i)
+		successor.assume(conds[i])
+
+		err := e.invoke(successor, node, value.candidates[i], nil, args, nil)
+		switch {
+		case errors.Is(err, errStopped):
+			continue
+		case err != nil:
+			return nil, err
+		}
+		successors = append(successors, successor)
+	}
+
+	return successors, nil
+}
+
+// deferValue pushes the deferred call of the function value, only closures may be deferred
+func (e *Explorer) deferValue(st *State, node *deferNode, fun ast.Expr) error {
+	value, err := e.eval(st, fun)
+	if err != nil {
+		return err
+	}
+
+	c, ok := value.(closure)
+	if !ok || c.fn == nil {
+		return e.unsupported(node.call, "deferred call of %s", types.ExprString(fun))
+	}
+
+	signature := e.typeOf(st, fun).Underlying().(*types.Signature)
+	args, err := e.callArguments(st, node.call, signature, nil)
+	if err != nil {
+		return err
+	}
+
+	fr := st.frame()
+	fr.defers = append(fr.defers, deferred{fn: c.fn, args: args, typeArgs: c.typeArgs, captured: c.captured})
+	return nil
+}
+
+// newFunctionArgument creates the function argument. The parameter with candidates is one of the candidate
+// functions of the package, the others are uninterpreted functions of basic parameters and results
+func (e *Explorer) newFunctionArgument(st *State, name string, signature *types.Signature) (smt.SymValue, error) {
+	if names, ok := e.Candidates[name]; ok {
+		value := candidateFunction{choice: e.sCtx.Ctx.IntConst(name + ".choice")}
+		for _, candidateName := range names {
+			candidate, ok := e.pkg.Package.Scope().Lookup(candidateName).(*types.Func)
+			if !ok {
+				return nil, fmt.Errorf("candidate %s of %s isn't a function of %s", candidateName, name, e.pkg.Package.Name())
+			}
+			if !types.Identical(candidate.Type(), signature) {
+				return nil, fmt.Errorf("candidate %s of %s has type %s, want %s", candidateName, name, candidate.Type(), signature)
+			}
+			value.candidates = append(value.candidates, candidate)
+		}
+		if len(value.candidates) == 0 {
+			return nil, fmt.Errorf("argument %s has no candidates", name)
+		}
+
+		st.assume(value.choice.GE(e.intConst(0)).And(value.choice.LT(e.intConst(int64(len(value.candidates))))))
+		return value, nil
+	}
+
+	if signature.Variadic() {
+		return nil, fmt.Errorf("arguments of the variadic type %s aren't supported", signature)
+	}
+	params, err := basicTypes(signature.Params())
+	if err != nil {
+		return nil, err
+	}
+	results, err := basicTypes(signature.Results())
+	if err != nil {
+		return nil, err
+	}
+
+	return e.sCtx.NewFunctionArgument(name, params, results)
+}
+
+// basicTypes returns the underlying basic types of the variables, uninterpreted functions take and return only those
+func basicTypes(vars *types.Tuple) ([]*types.Basic, error) {
+	result := make([]*types.Basic, 0, vars.Len())
+	for i := 0; i < vars.Len(); i++ {
+		basic, ok := vars.At(i).Type().Underlying().(*types.Basic)
+		if !ok {
+			return nil, fmt.Errorf("functions taking or returning %s aren't supported", vars.At(i).Type())
+		}
+		result = append(result, basic)
+	}
+
+	return result, nil
+}
+
+// decodeFunction builds the function value in the model: the name of the function, the chosen candidate
+// or the stub returning the results of the calls of the uninterpreted function
+func (e *Explorer) decodeFunction(model *z3.Model, value smt.SymValue, t types.Type) (reflect.Value, error) {
+	switch value := value.(type) {
+	case closure:
+		if value.fn == nil {
+			return reflect.ValueOf(testgen.Source(fmt.Sprintf("(%s)(nil)", smttypes.GoTypeName(t)))), nil
+		}
+		return reflect.ValueOf(testgen.Source(value.fn.Name)), nil
+	case candidateFunction:
+		choice, isLiteral, ok := model.Eval(value.choice, true).(z3.Int).AsInt64()
+		if !isLiteral || !ok || choice < 0 || int(choice) >= len(value.candidates) {
+			return reflect.Value{}, fmt.Errorf("can't evaluate the choice of the candidate")
+		}
+		return reflect.ValueOf(testgen.Source(value.candidates[choice].Name())), nil
+	case smt.SymFunction:
+		calls, err := value.Decode(model)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		signature := t.Underlying().(*types.Signature)
+		stub := testgen.Stub{}
+		for i := 0; i < signature.Params().Len(); i++ {
+			stub.Params = append(stub.Params, smttypes.GoTypeName(signature.Params().At(i).Type()))
+		}
+		for i := 0; i < signature.Results().Len(); i++ {
+			stub.Results = append(stub.Results, smttypes.GoTypeName(signature.Results().At(i).Type()))
+		}
+		for _, call := range calls {
+			stub.Rows = append(stub.Rows, testgen.StubRow{Arguments: call.Arguments, Results: call.Results})
+		}
+
+		source := stub.Source()
+		if t != t.Underlying() {
+			source = testgen.Source(fmt.Sprintf("%s(%s)", smttypes.GoTypeName(t), source))
+		}
+		return reflect.ValueOf(source), nil
+	default:
+		return reflect.Value{}, fmt.Errorf("%T isn't a function", value)
+	}
+}
+
+// isNilFunction tells that the function value is nil, only closures may be nil
+func isNilFunction(value smt.SymValue) bool {
+	c, ok := value.(closure)
+	return ok && c.fn == nil
+}
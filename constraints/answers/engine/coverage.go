@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"fmt"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// ConditionValue is the value of an atomic condition of a decision on a path
+type ConditionValue int
+
+const (
+	NotEvaluated ConditionValue = iota
+	False
+	True
+)
+
+// Evaluation is an evaluation of a decision on a path. Conditions after the one deciding
+// the outcome aren't evaluated because of short-circuiting
+type Evaluation struct {
+	Decision *Decision
+	Values   []ConditionValue
+	Outcome  bool
+}
+
+// DecisionCoverage tells which conditions of the decision were shown to independently affect
+// its outcome. Pairs hold the numbers of the paths showing it, they're nil for the conditions not shown
+type DecisionCoverage struct {
+	Decision *Decision
+	Pairs    [][]int
+}
+
+func (coverage DecisionCoverage) String() string {
+	builder := strings.Builder{}
+	fmt.Fprintf(&builder, "%s: %s", coverage.Decision.Pos, types.ExprString(coverage.Decision.Expr))
+	for i, condition := range coverage.Decision.Conditions {
+		pair := coverage.Pairs[i]
+		if pair == nil {
+			fmt.Fprintf(&builder, "\n\t%s: not shown", types.ExprString(condition))
+		} else {
+			fmt.Fprintf(&builder, "\n\t%s: paths %d and %d", types.ExprString(condition), pair[0], pair[1])
+		}
+	}
+
+	return builder.String()
+}
+
+// MCDC reports the modified condition/decision coverage of the decisions of the explored function
+// and of the functions it called by the paths. The condition is shown to independently affect
+// the decision by two evaluations with different values of it and different outcomes where
+// the other conditions evaluated by both have the same values
+func (e *Explorer) MCDC(paths []Path) []DecisionCoverage {
+	evaluations := make(map[*Decision][]pathEvaluation)
+	decisions := append([]*Decision(nil), e.target.Decisions...)
+	for i, path := range paths {
+		for _, evaluation := range path.Evaluations {
+			if _, ok := evaluations[evaluation.Decision]; !ok && !e.isTargetDecision(evaluation.Decision) {
+				decisions = append(decisions, evaluation.Decision)
+			}
+			evaluations[evaluation.Decision] = append(evaluations[evaluation.Decision], pathEvaluation{path: i + 1, evaluation: evaluation})
+		}
+	}
+
+	sort.SliceStable(decisions, func(i, j int) bool {
+		a, b := decisions[i].Pos, decisions[j].Pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Offset < b.Offset
+	})
+
+	result := make([]DecisionCoverage, 0, len(decisions))
+	for _, decision := range decisions {
+		coverage := DecisionCoverage{Decision: decision, Pairs: make([][]int, len(decision.Conditions))}
+		for condition := range decision.Conditions {
+			coverage.Pairs[condition] = independencePair(evaluations[decision], condition)
+		}
+		result = append(result, coverage)
+	}
+
+	return result
+}
+
+type pathEvaluation struct {
+	path       int
+	evaluation Evaluation
+}
+
+func (e *Explorer) isTargetDecision(decision *Decision) bool {
+	for _, d := range e.target.Decisions {
+		if d == decision {
+			return true
+		}
+	}
+
+	return false
+}
+
+func independencePair(evaluations []pathEvaluation, condition int) []int {
+	for i, a := range evaluations {
+		for _, b := range evaluations[i+1:] {
+			valueA, valueB := a.evaluation.Values[condition], b.evaluation.Values[condition]
+			if valueA != NotEvaluated && valueB != NotEvaluated && valueA != valueB &&
+				a.evaluation.Outcome != b.evaluation.Outcome && sameOtherConditions(a.evaluation, b.evaluation, condition) {
+				return []int{a.path, b.path}
+			}
+		}
+	}
+
+	return nil
+}
+
+// sameOtherConditions checks that the conditions other than the given one evaluated by both evaluations are equal
+func sameOtherConditions(a Evaluation, b Evaluation, condition int) bool {
+	for i := range a.Values {
+		if i == condition || a.Values[i] == NotEvaluated || b.Values[i] == NotEvaluated {
+			continue
+		}
+		if a.Values[i] != b.Values[i] {
+			return false
+		}
+	}
+
+	return true
+}
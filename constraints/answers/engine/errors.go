@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	"github.com/vldF/symbolic_execution_course/constraints/models"
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+	smttypes "github.com/vldF/symbolic_execution_course/constraints/smt/types"
+	"go/ast"
+	"go/types"
+)
+
+// madeError is the error made by errors.New or fmt.Errorf, it's kept in a cell of its own and the interfaces
+// holding it point to the cell. argTypes are the types of the arguments fmt.Errorf formats
+type madeError struct {
+	smt.SymValueBase
+	made     models.Error
+	argTypes []types.Type
+}
+
+// makeError allocates the error of the call of errors.New or fmt.Errorf, every call makes a new error.
+// The arguments of fmt.Errorf are decoded to format the message, they must be of basic types
+func (e *Explorer) makeError(st *State, node ast.Node, made models.Error, argTypes []types.Type) (smt.SymValue, error) {
+	for _, t := range argTypes {
+		if _, ok := t.Underlying().(*types.Basic); !ok || types.NewMethodSet(t).Len() > 0 {
+			return nil, e.unsupported(node, "fmt.Errorf formats only the values of basic types, not %s", t)
+		}
+	}
+
+	cell := st.alloc(madeError{made: made, argTypes: argTypes})
+	return e.ts.NewInterfaceValue(smttypes.GoTypeName(errorType), smttypes.LibraryError, e.pointerTo(cell))
+}
+
+// newErrorInput is the input error of the type the library makes, errors.New with any message
+func (e *Explorer) newErrorInput(st *State, name string) (smt.SymValue, error) {
+	text, err := e.sCtx.NewBasicArgument(name, types.Typ[types.String])
+	if err != nil {
+		return nil, err
+	}
+	return e.pointerTo(st.alloc(madeError{made: models.Error{Text: text.(smt.SymString)}})), nil
+}
+
+// errorMessage returns the message of the made error the interface points to for the call of its Error method.
+// The message formatted by fmt.Errorf is known only in the model
+func (e *Explorer) errorMessage(st *State, node ast.Node, value smt.SymValue) (smt.SymValue, error) {
+	made, err := e.madeErrorAt(st, value)
+	if err != nil {
+		return nil, e.unsupported(node, "%s", err)
+	}
+	if made.made.Formatted {
+		return nil, e.unsupported(node, "the message of the error made by fmt.Errorf isn't known")
+	}
+
+	return made.made.Text, nil
+}
+
+// callErrorMethod defines the result of the call of the Error method of the made error the interface holds
+func (e *Explorer) callErrorMethod(st *State, node *callNode, iface smttypes.SymInterface, method *types.Func) error {
+	if method.Name() != "Error" {
+		return e.unsupported(node.call, "call of the method %s of the error made by the library", method.Name())
+	}
+	value, _ := iface.DynamicValue(smttypes.LibraryError)
+	message, err := e.errorMessage(st, node.call, value)
+	if err != nil {
+		return err
+	}
+	for _, result := range node.results {
+		st.define(result, message)
+	}
+
+	return nil
+}
+
+func (e *Explorer) madeErrorAt(st *State, value smt.SymValue) (madeError, error) {
+	ref, ok := value.(smt.SymRef)
+	if !ok {
+		return madeError{}, fmt.Errorf("%T isn't an error made by the library", value)
+	}
+	address, isLiteral, ok := ref.Address().AsInt64()
+	if !isLiteral || !ok || address <= 0 || int(address) >= len(st.heap) {
+		return madeError{}, fmt.Errorf("error at %s is out of the heap", ref.Address())
+	}
+	made, ok := st.heap[address].(madeError)
+	if !ok {
+		return madeError{}, fmt.Errorf("%T isn't an error made by the library", st.heap[address])
+	}
+
+	return made, nil
+}
+
+// decodeErrorMessage builds the message of the made error in the model, fmt.Errorf formats the decoded arguments
+func (e *Explorer) decodeErrorMessage(model *z3.Model, st *State, value smt.SymValue) (string, error) {
+	made, err := e.madeErrorAt(st, value)
+	if err != nil {
+		return "", err
+	}
+
+	text, err := decodeBasic(model, made.made.Text, types.Typ[types.String])
+	if err != nil {
+		return "", err
+	}
+	if !made.made.Formatted {
+		return text.String(), nil
+	}
+
+	args := make([]any, 0, len(made.made.Args))
+	for i, arg := range made.made.Args {
+		decoded, err := decodeBasic(model, arg, types.Default(made.argTypes[i]).Underlying().(*types.Basic))
+		if err != nil {
+			return "", err
+		}
+		args = append(args, decoded.Interface())
+	}
+
+	return fmt.Sprintf(text.String(), args...), nil
+}
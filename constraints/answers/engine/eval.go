@@ -0,0 +1,942 @@
+package engine
+
+import (
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+	smttypes "github.com/vldF/symbolic_execution_course/constraints/smt/types"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"math/big"
+)
+
+const nilDereference = "runtime error: invalid memory address or nil pointer dereference"
+
+// eval evaluates the expression in the state. Potential panics fork the state by panicIf,
+// so the evaluation continues on the path where the expression doesn't panic
+func (e *Explorer) eval(st *State, expr ast.Expr) (smt.SymValue, error) {
+	if tv, ok := e.pkg.Info.Types[expr]; ok && tv.Value != nil {
+		return e.constValue(expr, tv.Value, e.instantiate(st, tv.Type))
+	}
+
+	switch expr := expr.(type) {
+	case *ast.ParenExpr:
+		return e.eval(st, expr.X)
+	case *ast.Ident:
+		return e.evalIdent(st, expr)
+	case *ast.BinaryExpr:
+		return e.evalBinary(st, expr)
+	case *ast.UnaryExpr:
+		return e.evalUnary(st, expr)
+	case *ast.StarExpr:
+		address, err := e.deref(st, expr.X)
+		if err != nil {
+			return nil, err
+		}
+		return st.heap[address], nil
+	case *ast.SelectorExpr:
+		return e.evalSelector(st, expr)
+	case *ast.CallExpr:
+		return e.evalCall(st, expr)
+	case *ast.TypeAssertExpr:
+		return e.evalTypeAssert(st, expr)
+	case *ast.CompositeLit:
+		return e.evalCompositeLit(st, expr)
+	case *ast.IndexExpr:
+		return e.evalIndex(st, expr)
+	case *ast.SliceExpr:
+		return e.evalSlice(st, expr)
+	case *ast.FuncLit:
+		return e.evalFuncLit(st, expr)
+	default:
+		return nil, e.unsupported(expr, "unsupported expression %T", expr)
+	}
+}
+
+func (e *Explorer) constValue(expr ast.Expr, value constant.Value, t types.Type) (smt.SymValue, error) {
+	ctx := e.sCtx.Ctx
+
+	basic, ok := types.Default(t).Underlying().(*types.Basic)
+	if !ok {
+		return nil, e.unsupported(expr, "constant of type %s", t)
+	}
+
+	switch {
+	case basic.Info()&types.IsBoolean != 0:
+		return smt.BoolValue(ctx.FromBool(constant.BoolVal(value))), nil
+	case basic.Info()&types.IsInteger != 0:
+		intValue, ok := constant.Val(constant.ToInt(value)).(int64)
+		if !ok {
+			return nil, e.unsupported(expr, "integer constant %s is too big", value)
+		}
+		return smt.IntValue(e.intConst(intValue)), nil
+	case basic.Info()&types.IsFloat != 0:
+		floatValue, _ := constant.Float64Val(constant.ToFloat(value))
+		return smt.FloatValue(ctx.FromFloat64(floatValue, e.floatSort(basic))), nil
+	case basic.Info()&types.IsString != 0:
+		return e.sCtx.NewStringConst(constant.StringVal(value)), nil
+	default:
+		return nil, e.unsupported(expr, "constant of type %s", t)
+	}
+}
+
+func (e *Explorer) evalIdent(st *State, ident *ast.Ident) (smt.SymValue, error) {
+	obj := e.pkg.Info.ObjectOf(ident)
+	if _, ok := obj.(*types.Nil); ok {
+		return untypedNil{}, nil
+	}
+	if fn, ok := obj.(*types.Func); ok {
+		return e.functionValue(ident, fn)
+	}
+
+	address, ok := st.variable(obj)
+	if !ok {
+		return nil, e.unknownVariable(ident, obj)
+	}
+
+	return st.heap[address], nil
+}
+
+// deref evaluates the pointer and returns its address, the nil pointer panics
+func (e *Explorer) deref(st *State, expr ast.Expr) (int, error) {
+	value, err := e.eval(st, expr)
+	if err != nil {
+		return 0, err
+	}
+
+	return e.derefValue(st, expr, value)
+}
+
+func (e *Explorer) derefValue(st *State, node ast.Node, value smt.SymValue) (int, error) {
+	ref, ok := value.(smt.SymRef)
+	if !ok {
+		return 0, e.unsupported(node, "%T isn't a pointer", value)
+	}
+	if input, ok := ref.(inputRef); ok {
+		if err := e.panicIf(st, input.isNil, nilDereference); err != nil {
+			return 0, err
+		}
+		return input.cell, nil
+	}
+
+	address, isLiteral, _ := ref.Address().AsInt64()
+	if !isLiteral {
+		return 0, e.unsupported(node, "pointer with a symbolic address")
+	}
+	if address == 0 {
+		if err := e.panicIf(st, e.sCtx.Ctx.FromBool(true), nilDereference); err != nil {
+			return 0, err
+		}
+	}
+
+	return int(address), nil
+}
+
+func (e *Explorer) evalBinary(st *State, expr *ast.BinaryExpr) (smt.SymValue, error) {
+	if result, ok := st.frame().fn.shortCircuits[expr]; ok {
+		return st.heap[st.frame().locals[result]], nil
+	}
+
+	left, err := e.eval(st, expr.X)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.eval(st, expr.Y)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.binary(st, expr, expr.Op, left, e.typeOf(st, expr.X), right, e.typeOf(st, expr.Y))
+}
+
+// binary applies the operator to the evaluated operands, it's shared by expressions and assignment operators
+func (e *Explorer) binary(st *State, node ast.Node, op token.Token, left smt.SymValue, leftType types.Type, right smt.SymValue, rightType types.Type) (smt.SymValue, error) {
+	if op == token.EQL || op == token.NEQ {
+		eq, err := e.equal(st, node, left, leftType, right, rightType)
+		if err != nil {
+			return nil, err
+		}
+		if op == token.NEQ {
+			eq = eq.Not()
+		}
+		return smt.BoolValue(eq), nil
+	}
+
+	switch left := left.(type) {
+	case smt.SymBool:
+		// && and || of the functions are control flow, only the expressions evaluated outside
+		// of them, like invariants, get here. They have no side effects, so they are formulas
+		switch op {
+		case token.LAND:
+			return smt.BoolValue(left.Bool().And(right.(smt.SymBool).Bool())), nil
+		case token.LOR:
+			return smt.BoolValue(left.Bool().Or(right.(smt.SymBool).Bool())), nil
+		}
+		return nil, e.unsupported(node, "operator %s on booleans", op)
+	case smt.SymInt:
+		count := right.(smt.SymInt).Int()
+		if (op == token.SHL || op == token.SHR) && isSigned(rightType) {
+			if err := e.panicIf(st, count.LT(e.intConst(0)), "runtime error: negative shift amount"); err != nil {
+				return nil, err
+			}
+		}
+		return e.intOp(st, node, op, left.Int(), count, leftType)
+	case smt.SymFloat:
+		return e.floatOp(node, op, left.Float(), right.(smt.SymFloat).Float())
+	case smt.SymString:
+		return e.stringOp(node, op, left, right.(smt.SymString))
+	default:
+		return nil, e.unsupported(node, "operator %s on %T", op, left)
+	}
+}
+
+// equal encodes left == right, the operands are converted to a common type first
+func (e *Explorer) equal(st *State, node ast.Node, left smt.SymValue, leftType types.Type, right smt.SymValue, rightType types.Type) (z3.Bool, error) {
+	if _, ok := left.(untypedNil); ok {
+		left, right = right, left
+		leftType, rightType = rightType, leftType
+	}
+
+	if _, ok := right.(untypedNil); ok {
+		switch left := st.contents(left).(type) {
+		case smttypes.SymInterface:
+			return left.IsNil, nil
+		case smt.SymRef:
+			return left.IsNil(), nil
+		case smt.SymSlice:
+			return e.sCtx.Ctx.FromBool(left.IsNil()), nil
+		case smt.SymMap:
+			return left.IsNil(), nil
+		case closure, candidateFunction, smt.SymFunction:
+			return e.sCtx.Ctx.FromBool(isNilFunction(left)), nil
+		default:
+			return z3.Bool{}, e.unsupported(node, "comparison of %T with nil", left)
+		}
+	}
+
+	if types.IsInterface(leftType) != types.IsInterface(rightType) {
+		if types.IsInterface(rightType) {
+			left, right = right, left
+			rightType = leftType
+		}
+
+		// the interface is equal to the concrete value when it has its type and an equal dynamic value
+		iface := left.(smttypes.SymInterface)
+		hasType, err := e.ts.HasDynamicType(iface, smttypes.GoTypeName(types.Default(rightType)))
+		if err != nil {
+			return z3.Bool{}, err
+		}
+
+		dynamicValue, err := e.dynamicValue(iface, types.Default(rightType))
+		if err != nil {
+			return z3.Bool{}, err
+		}
+
+		eq, err := e.equal(st, node, dynamicValue, rightType, right, rightType)
+		if err != nil {
+			return z3.Bool{}, err
+		}
+
+		return hasType.And(eq), nil
+	}
+
+	switch left := left.(type) {
+	case smttypes.SymInterface:
+		return e.equalInterfaces(st, node, left, right.(smttypes.SymInterface))
+	case smt.SymBool:
+		return left.Bool().Eq(right.(smt.SymBool).Bool()), nil
+	case smt.SymInt:
+		return left.Int().Eq(right.(smt.SymInt).Int()), nil
+	case smt.SymFloat:
+		return left.Float().IEEEEq(right.(smt.SymFloat).Float()), nil
+	case smt.SymString:
+		return left.Eq(right.(smt.SymString)), nil
+	case smt.SymStruct:
+		return left.Eq(right.(smt.SymStruct)), nil
+	case smt.SymRef:
+		return left.Address().Eq(right.(smt.SymRef).Address()), nil
+	default:
+		return z3.Bool{}, e.unsupported(node, "comparison of %T values", left)
+	}
+}
+
+// equalInterfaces encodes that both interfaces are nil or they have the same dynamic type and equal dynamic values.
+// The same dynamic type which isn't comparable panics
+func (e *Explorer) equalInterfaces(st *State, node ast.Node, left smttypes.SymInterface, right smttypes.SymInterface) (z3.Bool, error) {
+	result := left.IsNil.And(right.IsNil)
+	for _, typeName := range e.ts.Hierarchy().Implementations(left.Static) {
+		t, ok := e.typesByName[typeName]
+		if !ok && typeName != smttypes.LibraryError {
+			continue
+		}
+		// the interface without the dynamic value can't have the type
+		leftValue, leftOk := left.DynamicValue(typeName)
+		rightValue, rightOk := right.DynamicValue(typeName)
+		if !leftOk || !rightOk {
+			continue
+		}
+
+		leftHas, err := e.ts.HasDynamicType(left, typeName)
+		if err != nil {
+			return z3.Bool{}, err
+		}
+		rightHas, err := e.ts.HasDynamicType(right, typeName)
+		if err != nil {
+			return z3.Bool{}, err
+		}
+		sameType := leftHas.And(rightHas)
+
+		// the errors made by the library are pointers, equal when made by the same call
+		if typeName == smttypes.LibraryError {
+			leftRef, leftOk := leftValue.(smt.SymRef)
+			rightRef, rightOk := rightValue.(smt.SymRef)
+			if leftOk && rightOk {
+				result = result.Or(sameType.And(leftRef.Address().Eq(rightRef.Address())))
+			}
+			continue
+		}
+
+		if !types.Comparable(t) {
+			if err := e.panicIf(st, sameType, "runtime error: comparing uncomparable type "+typeName); err != nil {
+				return z3.Bool{}, err
+			}
+			continue
+		}
+
+		eq, err := e.equal(st, node, leftValue, t, rightValue, t)
+		if err != nil {
+			return z3.Bool{}, err
+		}
+		result = result.Or(sameType.And(eq))
+	}
+
+	return result, nil
+}
+
+func (e *Explorer) intOp(st *State, node ast.Node, op token.Token, left z3.Int, right z3.Int, t types.Type) (smt.SymValue, error) {
+	switch op {
+	case token.ADD:
+		return smt.IntValue(e.wrapOnce(left.Add(right), t)), nil
+	case token.SUB:
+		return smt.IntValue(e.wrapOnce(left.Sub(right), t)), nil
+	case token.MUL:
+		return smt.IntValue(e.wrap(left.Mul(right), t)), nil
+	case token.QUO, token.REM:
+		if err := e.panicIf(st, right.Eq(e.intConst(0)), "runtime error: integer divide by zero"); err != nil {
+			return nil, err
+		}
+		quotient, remainder := e.truncatedDivision(left, right)
+		if op == token.QUO {
+			// the smallest value divided by -1 overflows
+			return smt.IntValue(e.wrapOnce(quotient, t)), nil
+		}
+		return smt.IntValue(remainder), nil
+	case token.LSS:
+		return smt.BoolValue(left.LT(right)), nil
+	case token.LEQ:
+		return smt.BoolValue(left.LE(right)), nil
+	case token.GTR:
+		return smt.BoolValue(left.GT(right)), nil
+	case token.GEQ:
+		return smt.BoolValue(left.GE(right)), nil
+	}
+
+	// bitwise operations are done on bit vectors of the size of int, the unsigned ones read them back as unsigned
+	size := e.sCtx.TypesCtx.IntSize
+	leftBV, rightBV := left.ToBV(size), right.ToBV(size)
+	toInt := z3.BV.SToInt
+	if isUnsigned(t) {
+		toInt = z3.BV.UToInt
+	}
+	switch op {
+	case token.AND:
+		return smt.IntValue(toInt(leftBV.And(rightBV))), nil
+	case token.OR:
+		return smt.IntValue(toInt(leftBV.Or(rightBV))), nil
+	case token.XOR:
+		return smt.IntValue(toInt(leftBV.Xor(rightBV))), nil
+	case token.AND_NOT:
+		return smt.IntValue(toInt(leftBV.And(rightBV.Not()))), nil
+	case token.SHL:
+		return smt.IntValue(e.wrap(toInt(leftBV.Lsh(rightBV)), t)), nil
+	case token.SHR:
+		if isUnsigned(t) {
+			return smt.IntValue(toInt(leftBV.URsh(rightBV))), nil
+		}
+		return smt.IntValue(toInt(leftBV.SRsh(rightBV))), nil
+	default:
+		return nil, e.unsupported(node, "operator %s on integers", op)
+	}
+}
+
+// intType is the size in bits and the signedness of the integer type, untyped constants have neither
+func (e *Explorer) intType(t types.Type) (int, bool, bool) {
+	basic, ok := t.Underlying().(*types.Basic)
+	if !ok || basic.Info()&types.IsInteger == 0 || basic.Info()&types.IsUntyped != 0 {
+		return 0, false, false
+	}
+
+	switch basic.Kind() {
+	case types.Int8, types.Uint8:
+		return 8, isUnsigned(t), true
+	case types.Int16, types.Uint16:
+		return 16, isUnsigned(t), true
+	case types.Int32, types.Uint32:
+		return 32, isUnsigned(t), true
+	case types.Int64, types.Uint64:
+		return 64, isUnsigned(t), true
+	default:
+		return e.sCtx.TypesCtx.IntSize, isUnsigned(t), true
+	}
+}
+
+// intBounds are the smallest and the largest values of the integer type and the number of its values
+func (e *Explorer) intBounds(t types.Type) (z3.Int, z3.Int, z3.Int, bool) {
+	size, unsigned, ok := e.intType(t)
+	if !ok {
+		return z3.Int{}, z3.Int{}, z3.Int{}, false
+	}
+
+	ctx := e.sCtx.Ctx
+	minValue, maxValue := smt.IntRange(size, unsigned)
+	modulus := new(big.Int).Lsh(big.NewInt(1), uint(size))
+	return ctx.FromBigInt(minValue, ctx.IntSort()).(z3.Int), ctx.FromBigInt(maxValue, ctx.IntSort()).(z3.Int),
+		ctx.FromBigInt(modulus, ctx.IntSort()).(z3.Int), true
+}
+
+// widens tells that every value of the integer type from is a value of the integer type to
+func (e *Explorer) widens(from types.Type, to types.Type) bool {
+	fromSize, fromUnsigned, fromOk := e.intType(from)
+	toSize, toUnsigned, toOk := e.intType(to)
+	if !fromOk || !toOk {
+		return false
+	}
+
+	if fromUnsigned == toUnsigned {
+		return fromSize <= toSize
+	}
+	return fromUnsigned && fromSize < toSize
+}
+
+// wrap reduces the result of the integer operation to the range of the type, as its overflow in Go does
+func (e *Explorer) wrap(value z3.Int, t types.Type) z3.Int {
+	minValue, _, modulus, ok := e.intBounds(t)
+	if !ok {
+		return value
+	}
+
+	return value.Sub(minValue).Mod(modulus).Add(minValue)
+}
+
+// wrapOnce is wrap for the values at most one modulus out of the range, as the sums of two values of the type are.
+// It spares the solver the division
+func (e *Explorer) wrapOnce(value z3.Int, t types.Type) z3.Int {
+	minValue, maxValue, modulus, ok := e.intBounds(t)
+	if !ok {
+		return value
+	}
+
+	return value.GT(maxValue).IfThenElse(value.Sub(modulus), value.LT(minValue).IfThenElse(value.Add(modulus), value)).(z3.Int)
+}
+
+// truncatedDivision divides the integers the Go way: the quotient is truncated towards zero
+// and the remainder has the sign of the dividend, unlike the Euclidean division of the solver
+func (e *Explorer) truncatedDivision(left z3.Int, right z3.Int) (z3.Int, z3.Int) {
+	zero := e.intConst(0)
+	absLeft := left.GE(zero).IfThenElse(left, left.Neg()).(z3.Int)
+	absRight := right.GE(zero).IfThenElse(right, right.Neg()).(z3.Int)
+
+	absQuotient := absLeft.Div(absRight)
+	sameSign := left.GE(zero).Eq(right.GE(zero))
+	quotient := sameSign.IfThenElse(absQuotient, absQuotient.Neg()).(z3.Int)
+
+	return quotient, left.Sub(quotient.Mul(right))
+}
+
+func isUnsigned(t types.Type) bool {
+	basic, ok := t.Underlying().(*types.Basic)
+	return ok && basic.Info()&types.IsUnsigned != 0
+}
+
+// isSigned tells that the type is a signed integer type, the untyped constants are known to be in range
+func isSigned(t types.Type) bool {
+	basic, ok := t.Underlying().(*types.Basic)
+	return ok && basic.Info()&types.IsInteger != 0 && basic.Info()&(types.IsUnsigned|types.IsUntyped) == 0
+}
+
+func (e *Explorer) floatOp(node ast.Node, op token.Token, left z3.Float, right z3.Float) (smt.SymValue, error) {
+	switch op {
+	case token.ADD:
+		return smt.FloatValue(left.Add(right)), nil
+	case token.SUB:
+		return smt.FloatValue(left.Sub(right)), nil
+	case token.MUL:
+		return smt.FloatValue(left.Mul(right)), nil
+	case token.QUO:
+		return smt.FloatValue(left.Div(right)), nil
+	case token.LSS:
+		return smt.BoolValue(left.LT(right)), nil
+	case token.LEQ:
+		return smt.BoolValue(left.LE(right)), nil
+	case token.GTR:
+		return smt.BoolValue(left.GT(right)), nil
+	case token.GEQ:
+		return smt.BoolValue(left.GE(right)), nil
+	default:
+		return nil, e.unsupported(node, "operator %s on floats", op)
+	}
+}
+
+func (e *Explorer) stringOp(node ast.Node, op token.Token, left smt.SymString, right smt.SymString) (smt.SymValue, error) {
+	zero := e.intConst(0)
+	switch op {
+	case token.ADD:
+		return left.Concat(right), nil
+	case token.LSS:
+		return smt.BoolValue(left.Compare(right).LT(zero)), nil
+	case token.LEQ:
+		return smt.BoolValue(left.Compare(right).LE(zero)), nil
+	case token.GTR:
+		return smt.BoolValue(left.Compare(right).GT(zero)), nil
+	case token.GEQ:
+		return smt.BoolValue(left.Compare(right).GE(zero)), nil
+	default:
+		return nil, e.unsupported(node, "operator %s on strings", op)
+	}
+}
+
+func (e *Explorer) evalUnary(st *State, expr *ast.UnaryExpr) (smt.SymValue, error) {
+	if expr.Op == token.AND {
+		return e.addressOf(st, expr.X)
+	}
+
+	operand, err := e.eval(st, expr.X)
+	if err != nil {
+		return nil, err
+	}
+
+	switch operand := operand.(type) {
+	case smt.SymBool:
+		if expr.Op == token.NOT {
+			return smt.BoolValue(operand.Bool().Not()), nil
+		}
+	case smt.SymInt:
+		t := e.typeOf(st, expr)
+		switch expr.Op {
+		case token.SUB:
+			return smt.IntValue(e.wrapOnce(operand.Int().Neg(), t)), nil
+		case token.ADD:
+			return operand, nil
+		case token.XOR:
+			return smt.IntValue(e.wrap(operand.Int().ToBV(e.sCtx.TypesCtx.IntSize).Not().SToInt(), t)), nil
+		}
+	case smt.SymFloat:
+		switch expr.Op {
+		case token.SUB:
+			return smt.FloatValue(operand.Float().Neg()), nil
+		case token.ADD:
+			return operand, nil
+		}
+	}
+
+	return nil, e.unsupported(expr, "operator %s on %T", expr.Op, operand)
+}
+
+// addressOf returns the pointer to the variable or to a new cell with the composite literal
+func (e *Explorer) addressOf(st *State, expr ast.Expr) (smt.SymValue, error) {
+	switch expr := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		obj := e.pkg.Info.ObjectOf(expr)
+		address, ok := st.variable(obj)
+		if !ok {
+			return nil, e.unknownVariable(expr, obj)
+		}
+		return e.pointerTo(address), nil
+	case *ast.CompositeLit:
+		value, err := e.eval(st, expr)
+		if err != nil {
+			return nil, err
+		}
+		return e.pointerTo(st.alloc(value)), nil
+	case *ast.StarExpr:
+		return e.eval(st, expr.X)
+	default:
+		return nil, e.unsupported(expr, "address of %T", expr)
+	}
+}
+
+func (e *Explorer) evalSelector(st *State, expr *ast.SelectorExpr) (smt.SymValue, error) {
+	selection, ok := e.pkg.Info.Selections[expr]
+	if !ok || selection.Kind() != types.FieldVal {
+		return nil, e.unsupported(expr, "selector %s isn't a field", expr.Sel.Name)
+	}
+
+	value, err := e.eval(st, expr.X)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.field(st, expr, value, e.typeOf(st, expr.X), selection.Index())
+}
+
+// field follows the path of field indices, embedded pointers are dereferenced on the way
+func (e *Explorer) field(st *State, node ast.Node, value smt.SymValue, t types.Type, path []int) (smt.SymValue, error) {
+	for _, index := range path {
+		if pointer, ok := t.Underlying().(*types.Pointer); ok {
+			address, err := e.derefValue(st, node, value)
+			if err != nil {
+				return nil, err
+			}
+			value, t = st.heap[address], pointer.Elem()
+		}
+
+		structure, ok := value.(smt.SymStruct)
+		if !ok {
+			return nil, e.unsupported(node, "%T isn't a struct", value)
+		}
+
+		field := t.Underlying().(*types.Struct).Field(index)
+		fieldValue, err := structure.Field(field.Name())
+		if err != nil {
+			return nil, err
+		}
+		value, t = fieldValue, field.Type()
+	}
+
+	return value, nil
+}
+
+func (e *Explorer) evalCall(st *State, call *ast.CallExpr) (smt.SymValue, error) {
+	fun := e.pkg.Info.Types[call.Fun]
+	switch {
+	case fun.IsType():
+		return e.evalConversion(st, call)
+	case fun.IsBuiltin():
+		return e.evalBuiltin(st, call)
+	}
+
+	results, ok := st.frame().fn.callResults[call]
+	if !ok || len(results) != 1 {
+		return nil, e.unsupported(call, "call used as a single value")
+	}
+
+	return st.heap[st.frame().locals[results[0]]], nil
+}
+
+func (e *Explorer) evalConversion(st *State, call *ast.CallExpr) (smt.SymValue, error) {
+	value, err := e.eval(st, call.Args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	from, to := e.typeOf(st, call.Args[0]), e.typeOf(st, call)
+	if types.IsInterface(to) {
+		return e.convert(value, from, to)
+	}
+
+	switch value := st.contents(value).(type) {
+	case smt.SymString:
+		// []byte(s) and []rune(s) copy the bytes or the runes into a new slice
+		if slice, ok := to.Underlying().(*types.Slice); ok {
+			if isRune(slice.Elem()) {
+				return contentsRef{cell: st.alloc(e.sCtx.NewSliceFromArray(value.ToRunes()))}, nil
+			}
+			return contentsRef{cell: st.alloc(e.sCtx.NewSliceFromArray(value.ToBytes()))}, nil
+		}
+	case smt.SymSlice:
+		// the strings longer than MaxStringLength aren't encoded, the paths making them are left out
+		if isString(to) {
+			convert := e.sCtx.StringFromBytes
+			if isRune(from.Underlying().(*types.Slice).Elem()) {
+				convert = e.sCtx.StringFromRunes
+			}
+			result, fits := convert(value.Array())
+			st.assume(fits)
+			return result, nil
+		}
+	case smt.SymInt:
+		if isFloat(to) {
+			return smt.FloatValue(value.Int().ToReal().ToFloat(e.floatSort(to))), nil
+		}
+	case smt.SymFloat:
+		if isFloat(to) {
+			return smt.FloatValue(value.Float().ToFloat(e.floatSort(to))), nil
+		}
+		if isInteger(to) {
+			truncated := value.Float().Round(z3.RoundToZero)
+			return smt.IntValue(truncated.ToSBV(e.sCtx.TypesCtx.IntSize).SToInt()), nil
+		}
+	case untypedNil:
+		return e.zeroValue(to)
+	}
+
+	// conversions between types with the same underlying type keep the value,
+	// the other conversions between integer types wrap it into the range of the new type
+	if types.Identical(from.Underlying(), to.Underlying()) || e.widens(from, to) {
+		return value, nil
+	}
+	if isInteger(from) && isInteger(to) {
+		return smt.IntValue(e.wrap(value.(smt.SymInt).Int(), to)), nil
+	}
+
+	return nil, e.unsupported(call, "conversion from %s to %s", from, to)
+}
+
+func (e *Explorer) floatSort(t types.Type) z3.Sort {
+	if basic, ok := t.Underlying().(*types.Basic); ok && basic.Kind() == types.Float32 {
+		return e.sCtx.Ctx.FloatSort(8, 24)
+	}
+
+	return e.sCtx.Ctx.FloatSort(11, 53)
+}
+
+func isFloat(t types.Type) bool {
+	basic, ok := t.Underlying().(*types.Basic)
+	return ok && basic.Info()&types.IsFloat != 0
+}
+
+func isString(t types.Type) bool {
+	basic, ok := t.Underlying().(*types.Basic)
+	return ok && basic.Info()&types.IsString != 0
+}
+
+// isRune tells the runes from the bytes, the elements of the slices converted to and from strings
+func isRune(t types.Type) bool {
+	basic, ok := t.Underlying().(*types.Basic)
+	return ok && basic.Kind() == types.Int32
+}
+
+func isInteger(t types.Type) bool {
+	basic, ok := t.Underlying().(*types.Basic)
+	return ok && basic.Info()&types.IsInteger != 0
+}
+
+func (e *Explorer) evalBuiltin(st *State, call *ast.CallExpr) (smt.SymValue, error) {
+	name := ast.Unparen(call.Fun).(*ast.Ident).Name
+	switch name {
+	case "len":
+		value, err := e.eval(st, call.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		switch value := st.contents(value).(type) {
+		case smt.SymString:
+			return smt.IntValue(value.Len()), nil
+		case smt.SymSlice:
+			return smt.IntValue(value.Len()), nil
+		case smt.SymMap:
+			return smt.IntValue(value.Len()), nil
+		default:
+			return nil, e.unsupported(call, "len of %T", value)
+		}
+	case "real", "imag":
+		value, err := e.eval(st, call.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		c, ok := value.(smt.SymComplex)
+		if !ok {
+			return nil, e.unsupported(call, "%s of %T", name, value)
+		}
+		if name == "real" {
+			return smt.FloatValue(c.Real()), nil
+		}
+		return smt.FloatValue(c.Imag()), nil
+	case "panic":
+		value, err := e.eval(st, call.Args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		t := e.typeOf(st, call.Args[0])
+		message, err := e.panicMessage(st, value, t)
+		if err != nil {
+			return nil, err
+		}
+		if value, err = e.convert(value, t, anyType); err != nil {
+			return nil, err
+		}
+		if err := e.raise(st, value, message); err != nil {
+			return nil, err
+		}
+		return nil, errStopped
+	case "recover":
+		return e.recover(st)
+	default:
+		return nil, e.unsupported(call, "builtin %s", name)
+	}
+}
+
+// panicMessage describes the value passed to panic, constant strings are the most common
+func (e *Explorer) panicMessage(st *State, value smt.SymValue, t types.Type) (string, error) {
+	if str, ok := value.(smt.SymString); ok {
+		solver := e.sCtx.Solver
+		solver.Push()
+		defer solver.Pop()
+
+		solver.Assert(st.condition(e.sCtx.Ctx))
+		if sat, err := e.check(); err != nil || !sat {
+			return "panic", err
+		}
+
+		message, err := str.Decode(solver.Model())
+		return "panic: " + message, err
+	}
+
+	return fmt.Sprintf("panic with a value of type %s", t), nil
+}
+
+func (e *Explorer) evalTypeAssert(st *State, expr *ast.TypeAssertExpr) (smt.SymValue, error) {
+	iface, result, holds, err := e.typeAssertion(st, expr)
+	if err != nil {
+		return nil, err
+	}
+
+	message := fmt.Sprintf("interface conversion: %s is not %s", iface.Static, smttypes.GoTypeName(e.typeOf(st, expr.Type)))
+	if err := e.panicIf(st, holds.Not(), message); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// typeAssertion evaluates the interface of x.(T), the value x.(T) has when it succeeds
+// and the condition of its success
+func (e *Explorer) typeAssertion(st *State, expr *ast.TypeAssertExpr) (smttypes.SymInterface, smt.SymValue, z3.Bool, error) {
+	value, err := e.eval(st, expr.X)
+	if err != nil {
+		return smttypes.SymInterface{}, nil, z3.Bool{}, err
+	}
+	iface := value.(smttypes.SymInterface)
+
+	target := e.typeOf(st, expr.Type)
+	if types.IsInterface(target) {
+		static, err := interfaceName(target)
+		if err != nil {
+			return iface, nil, z3.Bool{}, err
+		}
+
+		holds, err := e.ts.Implements(iface, static)
+		return iface, iface.WithStatic(static), holds, err
+	}
+
+	holds, err := e.ts.HasDynamicType(iface, smttypes.GoTypeName(target))
+	if err != nil {
+		return iface, nil, z3.Bool{}, err
+	}
+
+	result, err := e.dynamicValue(iface, target)
+	return iface, result, holds, err
+}
+
+func (e *Explorer) evalCompositeLit(st *State, lit *ast.CompositeLit) (smt.SymValue, error) {
+	t := e.typeOf(st, lit)
+	structType, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return nil, e.unsupported(lit, "composite literal of type %s", t)
+	}
+
+	value, err := e.zeroValue(t)
+	if err != nil {
+		return nil, err
+	}
+	result := value.(smt.SymStruct)
+
+	for i, elt := range lit.Elts {
+		field := structType.Field(i)
+		valueExpr := elt
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			field = e.pkg.Info.ObjectOf(kv.Key.(*ast.Ident)).(*types.Var)
+			valueExpr = kv.Value
+		}
+
+		fieldValue, err := e.eval(st, valueExpr)
+		if err != nil {
+			return nil, err
+		}
+		fieldValue, err = e.convert(fieldValue, e.typeOf(st, valueExpr), field.Type())
+		if err != nil {
+			return nil, err
+		}
+
+		result, err = result.WithField(field.Name(), fieldValue)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func (e *Explorer) evalIndex(st *State, expr *ast.IndexExpr) (smt.SymValue, error) {
+	value, err := e.eval(st, expr.X)
+	if err != nil {
+		return nil, err
+	}
+	index, err := e.eval(st, expr.Index)
+	if err != nil {
+		return nil, err
+	}
+
+	switch value := st.contents(value).(type) {
+	case smt.SymString:
+		b, outOfRange := value.At(index.(smt.SymInt).Int())
+		if err := e.panicIf(st, outOfRange, "runtime error: index out of range"); err != nil {
+			return nil, err
+		}
+		return smt.IntValue(b), nil
+	case smt.SymSlice:
+		element, outOfRange := value.At(index.(smt.SymInt).Int())
+		if err := e.panicIf(st, outOfRange, "runtime error: index out of range"); err != nil {
+			return nil, err
+		}
+		return element, nil
+	case smt.SymMap:
+		key, err := value.Key(index)
+		if err != nil {
+			return nil, err
+		}
+		st.assume(value.Touch(key))
+		element, _ := value.Lookup(key)
+		return smt.ScalarValue(element)
+	default:
+		return nil, e.unsupported(expr, "indexing of %T", value)
+	}
+}
+
+func (e *Explorer) evalSlice(st *State, expr *ast.SliceExpr) (smt.SymValue, error) {
+	value, err := e.eval(st, expr.X)
+	if err != nil {
+		return nil, err
+	}
+
+	str, ok := value.(smt.SymString)
+	if !ok || expr.Slice3 {
+		return nil, e.unsupported(expr, "slicing of %T", value)
+	}
+
+	low, high := e.intConst(0), str.Len()
+	if expr.Low != nil {
+		value, err := e.eval(st, expr.Low)
+		if err != nil {
+			return nil, err
+		}
+		low = value.(smt.SymInt).Int()
+	}
+	if expr.High != nil {
+		value, err := e.eval(st, expr.High)
+		if err != nil {
+			return nil, err
+		}
+		high = value.(smt.SymInt).Int()
+	}
+
+	result, outOfRange := str.Slice(low, high)
+	if err := e.panicIf(st, outOfRange, "runtime error: slice bounds out of range"); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,1103 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	"github.com/vldF/symbolic_execution_course/constraints/models"
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+	smttypes "github.com/vldF/symbolic_execution_course/constraints/smt/types"
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// step runs the next node of the state or the terminator of its block and returns the successors
+func (e *Explorer) step(st *State) ([]*State, error) {
+	fr := st.frame()
+	switch {
+	case fr.panicking:
+		return e.unwind(st)
+	case fr.returned:
+		return e.completeReturn(st)
+	}
+
+	if fr.index < len(fr.block.Nodes) {
+		node := fr.block.Nodes[fr.index]
+		fr.index++
+		return e.execNode(st, node)
+	}
+
+	switch term := fr.block.Term.(type) {
+	case *jump:
+		if err := e.enter(st, term.target); err != nil {
+			return nil, err
+		}
+		return []*State{st}, nil
+	case *branch:
+		return e.execBranch(st, term)
+	case *ret:
+		return e.execReturn(st, term)
+	default:
+		return nil, e.unsupported(term, "unsupported terminator %T", term)
+	}
+}
+
+// enter moves the state to the beginning of the block. Entering the body of a loop starts a new iteration,
+// the path is incomplete when the loop needs more iterations than the bound allows
+func (e *Explorer) enter(st *State, block *Block) error {
+	fr := st.frame()
+	fr.block = block
+	fr.index = 0
+
+	if e.watch != nil && len(st.frames) == 1 {
+		if err := e.watch(st, block); err != nil {
+			return err
+		}
+	}
+
+	if block.loop == nil {
+		return nil
+	}
+
+	fr.iterations[block.loop]++
+	if fr.iterations[block.loop] <= e.LoopBound {
+		return nil
+	}
+
+	message := fmt.Sprintf("loop at %s needs more than %d iterations", block.loop.Pos, e.LoopBound)
+	if err := e.finish(st, Incomplete, nil, message); err != nil {
+		return err
+	}
+	return errStopped
+}
+
+func (e *Explorer) execNode(st *State, node Node) ([]*State, error) {
+	var err error
+	switch node := node.(type) {
+	case *callNode:
+		return e.execCall(st, node)
+	case *deferNode:
+		err = e.execDefer(st, node)
+	case *rangeNode:
+		err = e.execRange(st, node)
+	case *elementNode:
+		err = e.execElement(st, node)
+	case *advanceNode:
+		e.execAdvance(st, node)
+	case *ast.AssignStmt:
+		return e.execAssign(st, node)
+	case *ast.IncDecStmt:
+		err = e.execIncDec(st, node)
+	case *ast.DeclStmt:
+		err = e.execDecl(st, node.Decl.(*ast.GenDecl))
+	case *ast.ExprStmt:
+		_, err = e.eval(st, node.X)
+	case *defineNode:
+		var value smt.SymValue
+		if value, err = e.eval(st, node.value); err == nil {
+			st.define(node.target, value)
+		}
+	case *bindNode:
+		err = e.execBind(st, node)
+	case *boolNode:
+		st.define(node.target, smt.BoolValue(e.sCtx.Ctx.FromBool(node.value)))
+	case *loopNode:
+		if e.SummarizeLoops && node.loop.summary != nil {
+			if err = e.applyLoopSummary(st, node); err == nil {
+				err = e.enter(st, node.loop.exit)
+			}
+			break
+		}
+		st.frame().iterations[node.loop] = 0
+	default:
+		err = e.unsupported(node, "unsupported node %T", node)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return []*State{st}, nil
+}
+
+func (e *Explorer) execAssign(st *State, stmt *ast.AssignStmt) ([]*State, error) {
+	if stmt.Tok != token.ASSIGN && stmt.Tok != token.DEFINE {
+		return []*State{st}, e.execAssignOp(st, stmt)
+	}
+
+	if len(stmt.Lhs) != len(stmt.Rhs) {
+		switch rhs := ast.Unparen(stmt.Rhs[0]).(type) {
+		case *ast.TypeAssertExpr:
+			return e.execCommaOk(st, stmt, rhs)
+		case *ast.IndexExpr:
+			return []*State{st}, e.execLookup(st, stmt, rhs)
+		case *ast.CallExpr:
+			values := make([]smt.SymValue, 0, len(stmt.Lhs))
+			for _, result := range st.frame().fn.callResults[rhs] {
+				values = append(values, st.heap[st.frame().locals[result]])
+			}
+			return []*State{st}, e.assign(st, stmt, values)
+		default:
+			return nil, e.unsupported(stmt, "assignment of %T", rhs)
+		}
+	}
+
+	// the right hand side is evaluated before any variable is assigned
+	values := make([]smt.SymValue, 0, len(stmt.Rhs))
+	for _, rhs := range stmt.Rhs {
+		value, err := e.eval(st, rhs)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	return []*State{st}, e.assign(st, stmt, values)
+}
+
+// assign stores the values into the targets of the assignment, := defines the new variables
+func (e *Explorer) assign(st *State, stmt *ast.AssignStmt, values []smt.SymValue) error {
+	for i, lhs := range stmt.Lhs {
+		if ident, ok := lhs.(*ast.Ident); ok && ident.Name == "_" {
+			continue
+		}
+
+		if stmt.Tok == token.DEFINE {
+			if obj := e.pkg.Info.Defs[lhs.(*ast.Ident)]; obj != nil {
+				value, err := e.convert(values[i], e.rhsType(st, stmt, i), e.instantiate(st, obj.Type()))
+				if err != nil {
+					return err
+				}
+				st.define(obj, value)
+				continue
+			}
+		}
+
+		value, err := e.convert(values[i], e.rhsType(st, stmt, i), e.typeOf(st, lhs))
+		if err != nil {
+			return err
+		}
+		if err := e.store(st, lhs, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *Explorer) rhsType(st *State, stmt *ast.AssignStmt, i int) types.Type {
+	if len(stmt.Lhs) == len(stmt.Rhs) {
+		return e.typeOf(st, stmt.Rhs[i])
+	}
+
+	// calls and comma-ok expressions have tuple types
+	return e.typeOf(st, stmt.Rhs[0]).(*types.Tuple).At(i).Type()
+}
+
+// execCommaOk forks v, ok := x.(T) into the path where the assertion holds and the path
+// where v gets the zero value
+func (e *Explorer) execCommaOk(st *State, stmt *ast.AssignStmt, assert *ast.TypeAssertExpr) ([]*State, error) {
+	_, value, holds, err := e.typeAssertion(st, assert)
+	if err != nil {
+		return nil, err
+	}
+
+	zero, err := e.zeroValue(e.typeOf(st, assert.Type))
+	if err != nil {
+		return nil, err
+	}
+
+	outcomes := []struct {
+		cond  z3.Bool
+		value smt.SymValue
+		ok    bool
+	}{
+		{holds, value, true},
+		{holds.Not(), zero, false},
+	}
+
+	successors := make([]*State, 0, len(outcomes))
+	for _, i := range e.fork(st, outcomes[0].cond, outcomes[1].cond) {
+		outcome := outcomes[i]
+		successor := st.clone()
+		successor.take(i)
+		successor.assume(outcome.cond)
+		ok := smt.BoolValue(e.sCtx.Ctx.FromBool(outcome.ok))
+		if err := e.assign(successor, stmt, []smt.SymValue{outcome.value, ok}); err != nil {
+			return nil, err
+		}
+		successors = append(successors, successor)
+	}
+
+	return successors, nil
+}
+
+var assignOps = map[token.Token]token.Token{
+	token.ADD_ASSIGN:     token.ADD,
+	token.SUB_ASSIGN:     token.SUB,
+	token.MUL_ASSIGN:     token.MUL,
+	token.QUO_ASSIGN:     token.QUO,
+	token.REM_ASSIGN:     token.REM,
+	token.AND_ASSIGN:     token.AND,
+	token.OR_ASSIGN:      token.OR,
+	token.XOR_ASSIGN:     token.XOR,
+	token.SHL_ASSIGN:     token.SHL,
+	token.SHR_ASSIGN:     token.SHR,
+	token.AND_NOT_ASSIGN: token.AND_NOT,
+}
+
+// execLookup assigns the value of the map element and whether the key is present, v, ok := m[key]
+func (e *Explorer) execLookup(st *State, stmt *ast.AssignStmt, index *ast.IndexExpr) error {
+	value, err := e.eval(st, index.X)
+	if err != nil {
+		return err
+	}
+	m, ok := st.contents(value).(smt.SymMap)
+	if !ok {
+		return e.unsupported(index, "comma-ok indexing of %T", value)
+	}
+
+	key, err := e.eval(st, index.Index)
+	if err != nil {
+		return err
+	}
+	term, err := m.Key(key)
+	if err != nil {
+		return err
+	}
+
+	st.assume(m.Touch(term))
+	element, present := m.Lookup(term)
+	elementValue, err := smt.ScalarValue(element)
+	if err != nil {
+		return err
+	}
+	return e.assign(st, stmt, []smt.SymValue{elementValue, smt.BoolValue(present)})
+}
+
+func (e *Explorer) execAssignOp(st *State, stmt *ast.AssignStmt) error {
+	op, ok := assignOps[stmt.Tok]
+	if !ok {
+		return e.unsupported(stmt, "assignment operator %s", stmt.Tok)
+	}
+
+	left, err := e.eval(st, stmt.Lhs[0])
+	if err != nil {
+		return err
+	}
+	right, err := e.eval(st, stmt.Rhs[0])
+	if err != nil {
+		return err
+	}
+
+	value, err := e.binary(st, stmt, op, left, e.typeOf(st, stmt.Lhs[0]), right, e.typeOf(st, stmt.Rhs[0]))
+	if err != nil {
+		return err
+	}
+
+	return e.store(st, stmt.Lhs[0], value)
+}
+
+func (e *Explorer) execIncDec(st *State, stmt *ast.IncDecStmt) error {
+	value, err := e.eval(st, stmt.X)
+	if err != nil {
+		return err
+	}
+
+	var one smt.SymValue
+	switch value := value.(type) {
+	case smt.SymInt:
+		one = smt.IntValue(e.intConst(1))
+	case smt.SymFloat:
+		one = smt.FloatValue(e.sCtx.Ctx.FromFloat64(1, value.Float().Sort()))
+	default:
+		return e.unsupported(stmt, "%s of %T", stmt.Tok, value)
+	}
+
+	op := token.ADD
+	if stmt.Tok == token.DEC {
+		op = token.SUB
+	}
+
+	t := e.typeOf(st, stmt.X)
+	result, err := e.binary(st, stmt, op, value, t, one, t)
+	if err != nil {
+		return err
+	}
+
+	return e.store(st, stmt.X, result)
+}
+
+func (e *Explorer) execDecl(st *State, decl *ast.GenDecl) error {
+	for _, spec := range decl.Specs {
+		spec := spec.(*ast.ValueSpec)
+
+		values := make([]smt.SymValue, len(spec.Names))
+		valueTypes := make([]types.Type, len(spec.Names))
+		switch {
+		case len(spec.Values) == len(spec.Names):
+			for i, expr := range spec.Values {
+				value, err := e.eval(st, expr)
+				if err != nil {
+					return err
+				}
+				values[i], valueTypes[i] = value, e.typeOf(st, expr)
+			}
+		case len(spec.Values) == 1:
+			call, ok := ast.Unparen(spec.Values[0]).(*ast.CallExpr)
+			if !ok {
+				return e.unsupported(spec, "declaration of %T", spec.Values[0])
+			}
+			for i, result := range st.frame().fn.callResults[call] {
+				values[i], valueTypes[i] = st.heap[st.frame().locals[result]], e.instantiate(st, result.Type())
+			}
+		}
+
+		for i, name := range spec.Names {
+			obj := e.pkg.Info.Defs[name]
+			if obj == nil {
+				continue
+			}
+
+			var value smt.SymValue
+			var err error
+			if values[i] == nil {
+				value, err = e.zeroValue(e.instantiate(st, obj.Type()))
+			} else {
+				value, err = e.convert(values[i], valueTypes[i], e.instantiate(st, obj.Type()))
+			}
+			if err != nil {
+				return err
+			}
+			st.define(obj, value)
+		}
+	}
+
+	return nil
+}
+
+// execBind defines the variable of the type switch clause
+func (e *Explorer) execBind(st *State, node *bindNode) error {
+	value := st.heap[st.frame().locals[node.value]]
+	if len(node.types) != 1 || node.types[0] == nil {
+		st.define(node.target, value)
+		return nil
+	}
+
+	iface := value.(smttypes.SymInterface)
+	t := e.instantiate(st, node.types[0])
+	if types.IsInterface(t) {
+		static, err := interfaceName(t)
+		if err != nil {
+			return err
+		}
+		st.define(node.target, iface.WithStatic(static))
+		return nil
+	}
+
+	dynamicValue, err := e.dynamicValue(iface, t)
+	if err != nil {
+		return err
+	}
+	st.define(node.target, dynamicValue)
+
+	return nil
+}
+
+// store writes the value into the variable, the field or the pointee the expression denotes
+func (e *Explorer) store(st *State, target ast.Expr, value smt.SymValue) error {
+	switch target := ast.Unparen(target).(type) {
+	case *ast.Ident:
+		if target.Name == "_" {
+			return nil
+		}
+
+		obj := e.pkg.Info.ObjectOf(target)
+		address, ok := st.variable(obj)
+		if !ok {
+			return e.unknownVariable(target, obj)
+		}
+		st.heap[address] = value
+	case *ast.StarExpr:
+		address, err := e.deref(st, target.X)
+		if err != nil {
+			return err
+		}
+		st.heap[address] = value
+	case *ast.SelectorExpr:
+		selection, ok := e.pkg.Info.Selections[target]
+		if !ok || selection.Kind() != types.FieldVal {
+			return e.unsupported(target, "assignment to %s", target.Sel.Name)
+		}
+
+		container, err := e.eval(st, target.X)
+		if err != nil {
+			return err
+		}
+
+		t := e.typeOf(st, target.X)
+		if pointer, ok := t.Underlying().(*types.Pointer); ok {
+			address, err := e.derefValue(st, target, container)
+			if err != nil {
+				return err
+			}
+
+			updated, err := e.withField(st, target, st.heap[address], pointer.Elem(), selection.Index(), value)
+			if err != nil {
+				return err
+			}
+			st.heap[address] = updated
+			return nil
+		}
+
+		updated, err := e.withField(st, target, container, t, selection.Index(), value)
+		if err != nil {
+			return err
+		}
+		return e.store(st, target.X, updated)
+	case *ast.IndexExpr:
+		return e.storeElement(st, target, value)
+	default:
+		return e.unsupported(target, "assignment to %T", target)
+	}
+
+	return nil
+}
+
+// storeElement encodes s[i] = value and m[key] = value. The contents of the referenced slices and maps
+// are updated in their cell, the slices and the maps sharing it see the write. Arrays and nil slices
+// and maps are stored back into the indexed expression
+func (e *Explorer) storeElement(st *State, target *ast.IndexExpr, value smt.SymValue) error {
+	container, err := e.eval(st, target.X)
+	if err != nil {
+		return err
+	}
+	storeBack := func(updated smt.SymValue) error {
+		if ref, ok := container.(contentsRef); ok {
+			st.heap[ref.cell] = updated
+			return nil
+		}
+		return e.store(st, target.X, updated)
+	}
+	index, err := e.eval(st, target.Index)
+	if err != nil {
+		return err
+	}
+	term, err := smt.ScalarTerm(value)
+	if err != nil {
+		return e.unsupported(target, "assignment of %T to an element", value)
+	}
+
+	switch contents := st.contents(container).(type) {
+	case smt.SymSlice:
+		i := index.(smt.SymInt).Int()
+		_, outOfRange := contents.At(i)
+		if err := e.panicIf(st, outOfRange, "runtime error: index out of range"); err != nil {
+			return err
+		}
+		return storeBack(contents.Store(i, term))
+	case smt.SymMap:
+		key, err := contents.Key(index)
+		if err != nil {
+			return err
+		}
+		st.assume(contents.Touch(key))
+		updated, isNil := contents.Store(key, term)
+		if err := e.panicIf(st, isNil, "assignment to entry in nil map"); err != nil {
+			return err
+		}
+		return storeBack(updated)
+	default:
+		return e.unsupported(target, "assignment to elements of %T", contents)
+	}
+}
+
+// withField returns the struct with the field at the path replaced. Embedded pointers on the path
+// are stored through, so the struct holding them stays the same
+func (e *Explorer) withField(st *State, node ast.Node, container smt.SymValue, t types.Type, path []int, value smt.SymValue) (smt.SymValue, error) {
+	structure, ok := container.(smt.SymStruct)
+	if !ok {
+		return nil, e.unsupported(node, "%T isn't a struct", container)
+	}
+
+	field := t.Underlying().(*types.Struct).Field(path[0])
+	if len(path) == 1 {
+		return structure.WithField(field.Name(), value)
+	}
+
+	fieldValue, err := structure.Field(field.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	if pointer, ok := field.Type().Underlying().(*types.Pointer); ok {
+		address, err := e.derefValue(st, node, fieldValue)
+		if err != nil {
+			return nil, err
+		}
+
+		updated, err := e.withField(st, node, st.heap[address], pointer.Elem(), path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		st.heap[address] = updated
+		return structure, nil
+	}
+
+	updated, err := e.withField(st, node, fieldValue, field.Type(), path[1:], value)
+	if err != nil {
+		return nil, err
+	}
+	return structure.WithField(field.Name(), updated)
+}
+
+func (e *Explorer) execBranch(st *State, term *branch) ([]*State, error) {
+	var cond z3.Bool
+	switch test := term.cond.(type) {
+	case *typeTest:
+		var err error
+		if cond, err = e.typeTestCondition(st, test); err != nil {
+			return nil, err
+		}
+	case *caseTest:
+		var err error
+		if cond, err = e.caseTestCondition(st, test); err != nil {
+			return nil, err
+		}
+	case *rangeTest:
+		cond = e.rangeTestCondition(st, test)
+	case ast.Expr:
+		value, err := e.eval(st, test)
+		if err != nil {
+			return nil, err
+		}
+		cond = value.(smt.SymBool).Bool()
+	}
+
+	targets := []struct {
+		value bool
+		cond  z3.Bool
+		block *Block
+	}{{true, cond, term.then}, {false, cond.Not(), term.els}}
+
+	successors := make([]*State, 0, len(targets))
+	for _, i := range e.fork(st, targets[0].cond, targets[1].cond) {
+		target := targets[i]
+		successor := st.clone()
+		successor.take(i)
+		successor.assume(target.cond)
+		if term.decision != nil {
+			successor.evaluate(term.decision, term.condition, target.value, target.block)
+		}
+		if err := e.enter(successor, target.block); err != nil {
+			if errors.Is(err, errStopped) {
+				continue
+			}
+			return nil, err
+		}
+		successors = append(successors, successor)
+	}
+
+	return successors, nil
+}
+
+// typeTestCondition encodes that the interface matches any of the types of the case
+func (e *Explorer) typeTestCondition(st *State, test *typeTest) (z3.Bool, error) {
+	iface := st.heap[st.frame().locals[test.value]].(smttypes.SymInterface)
+
+	conds := make([]z3.Bool, 0, len(test.types))
+	for _, t := range test.types {
+		if t != nil {
+			t = e.instantiate(st, t)
+		}
+
+		switch {
+		case t == nil:
+			conds = append(conds, iface.IsNil)
+		case types.IsInterface(t):
+			static, err := interfaceName(t)
+			if err != nil {
+				return z3.Bool{}, err
+			}
+
+			cond, err := e.ts.Implements(iface, static)
+			if err != nil {
+				return z3.Bool{}, err
+			}
+			conds = append(conds, cond)
+		default:
+			cond, err := e.ts.HasDynamicType(iface, smttypes.GoTypeName(t))
+			if err != nil {
+				return z3.Bool{}, err
+			}
+			conds = append(conds, cond)
+		}
+	}
+
+	return e.sCtx.Ctx.FromBool(false).Or(conds...), nil
+}
+
+// caseTestCondition encodes that the tag of the switch equals the expression of the case
+func (e *Explorer) caseTestCondition(st *State, test *caseTest) (z3.Bool, error) {
+	value, err := e.eval(st, test.value)
+	if err != nil {
+		return z3.Bool{}, err
+	}
+
+	tag := st.heap[st.frame().locals[test.tag]]
+	return e.equal(st, test.value, tag, e.instantiate(st, test.tag.Type()), value, e.typeOf(st, test.value))
+}
+
+func (e *Explorer) execReturn(st *State, term *ret) ([]*State, error) {
+	fr := st.frame()
+	resultVars := fr.fn.Signature.Results()
+
+	results := make([]smt.SymValue, resultVars.Len())
+	switch {
+	case len(term.results) == resultVars.Len():
+		for i, expr := range term.results {
+			value, err := e.eval(st, expr)
+			if err != nil {
+				return nil, err
+			}
+
+			results[i], err = e.convert(value, e.typeOf(st, expr), e.instantiate(st, resultVars.At(i).Type()))
+			if err != nil {
+				return nil, err
+			}
+		}
+	case len(term.results) == 1:
+		// return f() with f returning several values
+		call := ast.Unparen(term.results[0]).(*ast.CallExpr)
+		for i, result := range fr.fn.callResults[call] {
+			value, err := e.convert(st.heap[fr.locals[result]], e.instantiate(st, result.Type()), e.instantiate(st, resultVars.At(i).Type()))
+			if err != nil {
+				return nil, err
+			}
+			results[i] = value
+		}
+	default:
+		// the naked return returns the named results
+		for i := range results {
+			results[i] = st.heap[fr.locals[resultVars.At(i)]]
+		}
+	}
+
+	// the named results get the values, so the deferred calls see and change them
+	for i, result := range results {
+		if address, ok := fr.locals[resultVars.At(i)]; ok {
+			st.heap[address] = result
+		}
+	}
+	fr.returned, fr.returnValues = true, results
+
+	return e.completeReturn(st)
+}
+
+// completeReturn runs the next deferred call of the returned frame. When none is left, the frame
+// returns the values of the named results or the values of the return statement
+func (e *Explorer) completeReturn(st *State) ([]*State, error) {
+	fr := st.frame()
+	if len(fr.defers) > 0 {
+		return []*State{st}, e.callDeferred(st)
+	}
+
+	resultVars := fr.fn.Signature.Results()
+	results := make([]smt.SymValue, resultVars.Len())
+	for i := range results {
+		if address, ok := fr.locals[resultVars.At(i)]; ok {
+			results[i] = st.heap[address]
+			continue
+		}
+		if i < len(fr.returnValues) {
+			results[i] = fr.returnValues[i]
+			continue
+		}
+
+		// the recovered function returns zero values
+		value, err := e.zeroValue(e.instantiate(st, resultVars.At(i).Type()))
+		if err != nil {
+			return nil, err
+		}
+		results[i] = value
+	}
+
+	st.popFrame()
+	if len(st.frames) == 0 {
+		status, message := Returned, ""
+		if st.recovered != nil {
+			status, message = Recovered, st.recovered.message
+		}
+		if err := e.finish(st, status, results, message); err != nil {
+			return nil, err
+		}
+		return nil, errStopped
+	}
+
+	for i, result := range fr.results {
+		st.define(result, results[i])
+	}
+	return []*State{st}, nil
+}
+
+// execCall runs the call node: it evaluates the arguments and enters the callee, the method
+// called on an interface forks the state for every dynamic type the receiver may have
+func (e *Explorer) execCall(st *State, node *callNode) ([]*State, error) {
+	call := node.call
+	fun := e.calledFunction(call)
+	if e.isFunctionValue(fun) {
+		return e.callValue(st, node, fun)
+	}
+
+	switch fun := fun.(type) {
+	case *ast.Ident:
+		return e.callFunction(st, node, fun)
+	case *ast.SelectorExpr:
+		selection, ok := e.pkg.Info.Selections[fun]
+		if !ok {
+			return e.callFunction(st, node, fun.Sel)
+		}
+
+		if selection.Kind() != types.MethodVal {
+			return nil, e.unsupported(call, "call of the field %s", fun.Sel.Name)
+		}
+		if len(selection.Index()) > 1 {
+			return nil, e.unsupported(call, "call of the promoted method %s", fun.Sel.Name)
+		}
+
+		method := selection.Obj().(*types.Func)
+		recvType := e.instantiate(st, selection.Recv())
+		if types.IsInterface(recvType) {
+			args, err := e.callArguments(st, call, method.Origin().Type().(*types.Signature), nil)
+			if err != nil {
+				return nil, err
+			}
+			return e.dispatch(st, node, fun, method, args)
+		}
+
+		// the method of the type parameter's constraint is the method of the type argument
+		if _, ok := selection.Recv().(*types.TypeParam); ok {
+			obj, index, _ := types.LookupFieldOrMethod(recvType, true, e.pkg.Package, method.Name())
+			if method, ok = obj.(*types.Func); !ok || len(index) > 1 {
+				return nil, e.unsupported(call, "call of the promoted method %s", fun.Sel.Name)
+			}
+		}
+
+		typeArgs := e.calleeTypeArgs(st, fun.Sel, method, recvType)
+		args, err := e.callArguments(st, call, method.Origin().Type().(*types.Signature), typeArgs)
+		if err != nil {
+			return nil, err
+		}
+
+		recv, err := e.receiver(st, fun, method, recvType)
+		if err != nil {
+			return nil, err
+		}
+		return []*State{st}, e.invoke(st, node, method, recv, args, typeArgs)
+	default:
+		return nil, e.unsupported(call, "call of %T", fun)
+	}
+}
+
+// calledFunction returns the called expression, the explicit instantiation F[int](...) calls F
+func (e *Explorer) calledFunction(call *ast.CallExpr) ast.Expr {
+	fun := ast.Unparen(call.Fun)
+	switch index := fun.(type) {
+	case *ast.IndexExpr:
+		// fs[i](...) calls the element
+		if _, ok := e.pkg.Info.TypeOf(index.X).(*types.Signature); ok {
+			fun = index.X
+		}
+	case *ast.IndexListExpr:
+		fun = index.X
+	}
+
+	return fun
+}
+
+func (e *Explorer) callFunction(st *State, node *callNode, ident *ast.Ident) ([]*State, error) {
+	callee, ok := e.pkg.Info.Uses[ident].(*types.Func)
+	if !ok {
+		return nil, e.unsupported(node.call, "call of the function value %s", ident.Name)
+	}
+
+	if model, ok := e.Models[callee.Origin().FullName()]; ok && !e.hasPolicy(callee) {
+		modeled, args, err := e.runModel(st, node.call, callee, model)
+		if err != nil {
+			return nil, err
+		}
+		if modeled.Approximate {
+			if err := e.approximate(st, node, callee, nil, args, modeled.Results); err != nil {
+				return nil, err
+			}
+		}
+		for _, fact := range modeled.Facts {
+			st.assume(fact)
+		}
+		for i, result := range node.results {
+			st.define(result, modeled.Results[i])
+		}
+		return []*State{st}, nil
+	}
+
+	typeArgs := e.calleeTypeArgs(st, ident, callee, nil)
+	args, err := e.callArguments(st, node.call, callee.Origin().Type().(*types.Signature), typeArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	if fn, err := e.function(callee.Origin()); err == nil {
+		s, err := e.summaryOf(fn)
+		if err != nil {
+			return nil, err
+		}
+		if s != nil {
+			return e.callSummary(st, node, s, callee, args)
+		}
+	}
+
+	return []*State{st}, e.invoke(st, node, callee, nil, args, typeArgs)
+}
+
+// runModel evaluates the arguments of the call of the library function and runs its model. The arguments
+// of the variadic parameter are passed one by one as they are. The errors the model makes are allocated
+func (e *Explorer) runModel(st *State, call *ast.CallExpr, callee *types.Func, model models.Model) (models.Call, []smt.SymValue, error) {
+	signature := callee.Origin().Type().(*types.Signature)
+	var args []smt.SymValue
+	var variadicTypes []types.Type
+	if signature.Variadic() {
+		if call.Ellipsis.IsValid() {
+			return models.Call{}, nil, e.unsupported(call, "call of the variadic function %s with a slice", callee.FullName())
+		}
+
+		fixed := signature.Params().Len() - 1
+		for i, arg := range call.Args {
+			value, err := e.eval(st, arg)
+			if err != nil {
+				return models.Call{}, nil, err
+			}
+			t := e.typeOf(st, arg)
+			if i < fixed {
+				if value, err = e.convert(value, t, signature.Params().At(i).Type()); err != nil {
+					return models.Call{}, nil, err
+				}
+			} else {
+				variadicTypes = append(variadicTypes, t)
+			}
+			args = append(args, value)
+		}
+	} else {
+		var err error
+		if args, err = e.callArguments(st, call, signature, nil); err != nil {
+			return models.Call{}, nil, err
+		}
+	}
+
+	modeled, err := model(e.sCtx, args)
+	if err != nil {
+		return models.Call{}, nil, e.unsupported(call, "model of %s: %s", callee.FullName(), err)
+	}
+	for i, result := range modeled.Results {
+		if made, ok := result.(models.Error); ok {
+			if modeled.Results[i], err = e.makeError(st, call, made, variadicTypes); err != nil {
+				return models.Call{}, nil, err
+			}
+		}
+	}
+
+	return modeled, args, nil
+}
+
+// callArguments evaluates the arguments and converts them to the types of the parameters
+func (e *Explorer) callArguments(st *State, call *ast.CallExpr, signature *types.Signature, typeArgs map[*types.TypeParam]types.Type) ([]smt.SymValue, error) {
+	if signature.Variadic() {
+		return nil, e.unsupported(call, "call of the variadic function %s", types.ExprString(call.Fun))
+	}
+
+	params := signature.Params()
+	values := make([]smt.SymValue, 0, params.Len())
+	valueTypes := make([]types.Type, 0, params.Len())
+	if len(call.Args) == 1 && params.Len() > 1 {
+		// f(g()) with g returning several values
+		for _, result := range st.frame().fn.callResults[ast.Unparen(call.Args[0]).(*ast.CallExpr)] {
+			values = append(values, st.heap[st.frame().locals[result]])
+			valueTypes = append(valueTypes, e.instantiate(st, result.Type()))
+		}
+	} else {
+		for _, arg := range call.Args {
+			value, err := e.eval(st, arg)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+			valueTypes = append(valueTypes, e.typeOf(st, arg))
+		}
+	}
+
+	for i := range values {
+		value, err := e.convert(values[i], valueTypes[i], e.substitute(params.At(i).Type(), typeArgs))
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+
+	return values, nil
+}
+
+// receiver evaluates the receiver of the static method call, taking the address of the variable
+// or dereferencing the pointer when the receiver of the method needs that
+func (e *Explorer) receiver(st *State, fun *ast.SelectorExpr, method *types.Func, recvType types.Type) (smt.SymValue, error) {
+	_, wantsPointer := method.Type().(*types.Signature).Recv().Type().(*types.Pointer)
+	_, isPointer := recvType.Underlying().(*types.Pointer)
+
+	switch {
+	case wantsPointer && !isPointer:
+		return e.addressOf(st, fun.X)
+	case !wantsPointer && isPointer:
+		address, err := e.deref(st, fun.X)
+		if err != nil {
+			return nil, err
+		}
+		return st.heap[address], nil
+	default:
+		return e.eval(st, fun.X)
+	}
+}
+
+// dispatch calls the method of the interface. The nil interface panics, otherwise every
+// feasible dynamic type gets its own state calling the method of that type
+func (e *Explorer) dispatch(st *State, node *callNode, fun *ast.SelectorExpr, method *types.Func, args []smt.SymValue) ([]*State, error) {
+	value, err := e.eval(st, fun.X)
+	if err != nil {
+		return nil, err
+	}
+	iface := value.(smttypes.SymInterface)
+
+	if err := e.panicIf(st, iface.IsNil, nilDereference); err != nil {
+		return nil, err
+	}
+
+	typeNames := make([]string, 0)
+	conds := make([]z3.Bool, 0)
+	for _, typeName := range e.ts.Hierarchy().Implementations(iface.Static) {
+		if _, ok := e.typesByName[typeName]; !ok && typeName != smttypes.LibraryError {
+			continue
+		}
+
+		cond, err := e.ts.HasDynamicType(iface, typeName)
+		if err != nil {
+			return nil, err
+		}
+		typeNames = append(typeNames, typeName)
+		conds = append(conds, cond)
+	}
+
+	successors := make([]*State, 0, len(conds))
+	for _, i := range e.fork(st, conds...) {
+		typeName, t := typeNames[i], e.typesByName[typeNames[i]]
+		successor := st.clone()
+		successor.take(i)
+		successor.assume(conds[i])
+
+		if typeName == smttypes.LibraryError {
+			if err := e.callErrorMethod(successor, node, iface, method); err != nil {
+				return nil, err
+			}
+			successors = append(successors, successor)
+			continue
+		}
+
+		obj, index, _ := types.LookupFieldOrMethod(t, true, e.pkg.Package, method.Name())
+		concrete, ok := obj.(*types.Func)
+		if !ok || len(index) > 1 {
+			return nil, e.unsupported(node.call, "call of the promoted method %s.%s", typeName, method.Name())
+		}
+
+		recv, err := e.dynamicValue(iface, t)
+		if err != nil {
+			return nil, err
+		}
+
+		// the method with the value receiver called on the pointer dereferences it
+		_, wantsPointer := concrete.Type().(*types.Signature).Recv().Type().(*types.Pointer)
+		if _, isPointer := t.(*types.Pointer); isPointer && !wantsPointer {
+			address, err := e.derefValue(successor, fun, recv)
+			if err != nil {
+				if errors.Is(err, errInfeasible) {
+					continue
+				}
+				return nil, err
+			}
+			recv = successor.heap[address]
+		}
+
+		if err := e.invoke(successor, node, concrete, recv, args, nil); err != nil {
+			return nil, err
+		}
+		successors = append(successors, successor)
+	}
+
+	return successors, nil
+}
+
+// invoke enters the function, its results are stored into the temporaries of the call node on return
+func (e *Explorer) invoke(st *State, node *callNode, callee *types.Func, recv smt.SymValue, args []smt.SymValue, typeArgs map[*types.TypeParam]types.Type) error {
+	fn, err := e.function(callee.Origin())
+	if err != nil {
+		return e.callUnknown(st, node, callee, recv, args, err)
+	}
+	e.follow(callee.Origin())
+	if err := e.checkBounds(st, node, fn); err != nil {
+		return err
+	}
+
+	return e.enterFunction(st, fn, node.results, recv, args, typeArgs)
+}
+
+// checkBounds finishes the path as incomplete and stops the state when the call of the function exceeds the bounds
+func (e *Explorer) checkBounds(st *State, node *callNode, fn *Function) error {
+	if message := e.exceedsBounds(st, fn); message != "" {
+		if err := e.finish(st, Incomplete, nil, fmt.Sprintf("%s: %s", e.pkg.Fset.Position(node.Pos()), message)); err != nil {
+			return err
+		}
+		return errStopped
+	}
+
+	return nil
+}
+
+// enterFunction pushes the frame of the function with the receiver and the arguments
+func (e *Explorer) enterFunction(st *State, fn *Function, results []*types.Var, recv smt.SymValue, args []smt.SymValue, typeArgs map[*types.TypeParam]types.Type) error {
+	st.pushFrame(fn, results, typeArgs)
+	if recv != nil {
+		st.define(fn.Signature.Recv(), recv)
+	}
+
+	params := fn.Signature.Params()
+	for i, arg := range args {
+		st.define(params.At(i), arg)
+	}
+
+	return e.defineResults(st, fn)
+}
+
+// exceedsBounds tells why the call of the function can't be followed on the path: the stack
+// is as deep as allowed or the function is already active as many times as allowed
+func (e *Explorer) exceedsBounds(st *State, fn *Function) string {
+	if len(st.frames) >= e.CallDepth {
+		return fmt.Sprintf("call of %s is deeper than %d calls", fn.Name, e.CallDepth)
+	}
+
+	active := 0
+	for _, fr := range st.frames {
+		if fr.fn == fn {
+			active++
+		}
+	}
+	if active > e.RecursionBound {
+		return fmt.Sprintf("recursion of %s is deeper than %d calls", fn.Name, e.RecursionBound)
+	}
+
+	return ""
+}
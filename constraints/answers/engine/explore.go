@@ -0,0 +1,732 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	"github.com/vldF/symbolic_execution_course/constraints/models"
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+	smttypes "github.com/vldF/symbolic_execution_course/constraints/smt/types"
+	"github.com/vldF/symbolic_execution_course/constraints/testgen"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Status int
+
+const (
+	Returned Status = iota
+	// Recovered paths return after a panic stopped by recover, Panicked paths end with a panic no frame recovers
+	Recovered
+	Panicked
+	// Incomplete paths are cut off by the bounds on loops and calls, they are feasible up to the cut.
+	// The paths the solver gives up on are incomplete too, they have no inputs
+	Incomplete
+	Unsupported
+)
+
+func (status Status) String() string {
+	switch status {
+	case Returned:
+		return "returned"
+	case Recovered:
+		return "recovered"
+	case Panicked:
+		return "panicked"
+	case Incomplete:
+		return "incomplete"
+	default:
+		return "unsupported"
+	}
+}
+
+// Path is an explored path of the function with the inputs leading to it
+type Path struct {
+	Status    Status
+	Message   string
+	Condition z3.Bool
+
+	// TypeArguments are the names of the types the generic function is instantiated with
+	TypeArguments []string
+	// Arguments of a method start with its receiver, Globals are the package variables when they're symbolic
+	Arguments   []smt.DecodedArgument
+	Globals     []smt.DecodedArgument
+	Results     []reflect.Value
+	Evaluations []Evaluation
+	// UnknownCalls are the functions which can't be explored the path has called, with their policies.
+	// ApproximatedCalls are the functions with the uninterpreted models the path has called
+	UnknownCalls      []UnknownCall
+	ApproximatedCalls []string
+	Test              testgen.Case
+}
+
+func (path Path) String() string {
+	arguments := make([]string, 0, len(path.Arguments))
+	for _, arg := range path.Arguments {
+		arguments = append(arguments, fmt.Sprintf("%s = %s", arg.Name, testgen.Literal(arg.Value)))
+	}
+
+	outcome := path.Status.String()
+	switch path.Status {
+	case Returned, Recovered:
+		results := make([]string, 0, len(path.Results))
+		for _, result := range path.Results {
+			results = append(results, testgen.Literal(result))
+		}
+		outcome = "returned"
+		if len(results) > 0 {
+			outcome += " " + strings.Join(results, ", ")
+		}
+		if path.Status == Recovered {
+			outcome += " after recovering from " + path.Message
+		}
+	default:
+		outcome += ": " + path.Message
+	}
+
+	result := fmt.Sprintf("(%s) %s", strings.Join(arguments, ", "), outcome)
+	if len(path.Globals) > 0 {
+		globals := make([]string, 0, len(path.Globals))
+		for _, global := range path.Globals {
+			globals = append(globals, fmt.Sprintf("%s = %s", global.Name, testgen.Literal(global.Value)))
+		}
+		result = fmt.Sprintf("(%s) with %s %s", strings.Join(arguments, ", "), strings.Join(globals, ", "), outcome)
+	}
+	if len(path.TypeArguments) > 0 {
+		result = fmt.Sprintf("[%s] %s", strings.Join(path.TypeArguments, ", "), result)
+	}
+	calls := make([]string, 0, len(path.UnknownCalls)+len(path.ApproximatedCalls)+1)
+	for _, call := range path.UnknownCalls {
+		calls = append(calls, call.String())
+	}
+	for _, call := range path.ApproximatedCalls {
+		calls = append(calls, call+" approximated")
+	}
+	if len(path.Test.Unchecked) > 0 {
+		calls = append(calls, "unconfirmed")
+	}
+	if len(calls) > 0 {
+		result += fmt.Sprintf(" (%s)", strings.Join(calls, ", "))
+	}
+
+	return result
+}
+
+// The bounds used by default: iterations of every loop, nested calls, recursive calls of a function,
+// pointers followed from an input and the time of a solver check
+const (
+	DefaultLoopBound      = 10
+	DefaultCallDepth      = 16
+	DefaultRecursionBound = 5
+	DefaultPointerDepth   = 3
+	DefaultSolverTimeout  = 10 * time.Second
+)
+
+// Explorer explores paths of the functions of a type checked package
+type Explorer struct {
+	// LoopBound is the number of iterations of a loop explored on a path
+	LoopBound int
+	// SummarizeLoops runs the loops with affine updates at once, the other loops are unrolled
+	SummarizeLoops bool
+	// CallDepth is the number of frames on the stack, the explored function takes one
+	CallDepth int
+	// RecursionBound is the number of the recursive calls of a function active at once
+	RecursionBound int
+	// PointerDepth is the number of pointers followed from an input to fresh cells, the deeper pointers are nil
+	PointerDepth int
+	// SolverTimeout bounds every check of a path condition, the path whose check runs out of it is incomplete.
+	// Zero waits for the solver
+	SolverTimeout time.Duration
+	// Summaries keep the summaries of the called functions, nil turns them off and a callee is explored at every call
+	Summaries *SummaryCache
+	// Candidates are the functions of the package a function parameter of the explored function may be,
+	// by the name of the parameter. The function parameters without candidates are uninterpreted functions
+	Candidates map[string][]string
+	// SymbolicGlobals starts the explored function with any values of the package variables,
+	// otherwise they have the values of their initializers
+	SymbolicGlobals bool
+	// Models replace the calls of the library functions by their symbolic models, by the full names of the functions
+	Models models.Registry
+	// Policies decide what happens at the calls of the functions which can't be explored, by the full names
+	// of the functions. A policy set for a function with a model overrides the model. The functions without
+	// policies have the default one
+	Policies      map[string]Policy
+	DefaultPolicy Policy
+	// Implementations are the functions run for the concretized calls, by their full names. NewExplorer sets
+	// StandardImplementations, the other functions are added here
+	Implementations map[string]any
+
+	sCtx *smt.SymContext
+	pkg  *smttypes.LoadedPackage
+	ts   *smttypes.TypeSystem
+
+	decls       map[*types.Func]*ast.FuncDecl
+	functions   map[*types.Func]*Function
+	typesByName map[string]types.Type
+	structs     map[types.Type]*smt.StructDescriptor
+	instances   *types.Context
+
+	// the state of the current exploration
+	target        *Function
+	instantiation instantiation
+	worklist      []*State
+	arguments     []argument
+	globals       []argument
+	globalErrors  map[types.Object]error
+	initial       *State
+	paths         []Path
+	// stringInputs is set when the inputs of the explored function may hold strings
+	stringInputs bool
+
+	// watch is called when a state of the explored function enters a block, it may stop the state
+	watch func(st *State, block *Block) error
+
+	// decoding are the cells whose values are being decoded, a pointer back to one of them makes a cycle
+	decoding []int
+
+	// summary is the summary being computed, summarizing are the functions whose summaries are being computed
+	summary     *Summary
+	summarizing map[*Function]bool
+}
+
+type argument struct {
+	obj   *types.Var
+	t     types.Type
+	value smt.SymValue
+}
+
+// errInfeasible stops the state whose path condition became unsatisfiable,
+// errStopped stops the state which has been finished already
+var (
+	errInfeasible = errors.New("infeasible path")
+	errStopped    = errors.New("stopped path")
+)
+
+func NewExplorer(sCtx *smt.SymContext, pkg *smttypes.LoadedPackage) (*Explorer, error) {
+	ts, err := smttypes.Encode(sCtx, smttypes.FromPackage(pkg.Package))
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Explorer{
+		LoopBound:       DefaultLoopBound,
+		SummarizeLoops:  true,
+		CallDepth:       DefaultCallDepth,
+		RecursionBound:  DefaultRecursionBound,
+		PointerDepth:    DefaultPointerDepth,
+		SolverTimeout:   DefaultSolverTimeout,
+		Summaries:       NewSummaryCache(),
+		Models:          models.Default(),
+		Implementations: StandardImplementations(),
+		sCtx:            sCtx,
+		pkg:             pkg,
+		ts:              ts,
+		decls:           make(map[*types.Func]*ast.FuncDecl),
+		functions:       make(map[*types.Func]*Function),
+		typesByName:     make(map[string]types.Type),
+		structs:         make(map[types.Type]*smt.StructDescriptor),
+		instances:       types.NewContext(),
+		summarizing:     make(map[*Function]bool),
+	}
+
+	for _, file := range pkg.Files {
+		for _, decl := range file.Decls {
+			if funcDecl, ok := decl.(*ast.FuncDecl); ok && funcDecl.Body != nil {
+				e.decls[pkg.Info.Defs[funcDecl.Name].(*types.Func)] = funcDecl
+			}
+		}
+	}
+
+	scope := pkg.Package.Scope()
+	for _, name := range scope.Names() {
+		if typeName, ok := scope.Lookup(name).(*types.TypeName); ok {
+			e.typesByName[smttypes.GoTypeName(typeName.Type())] = typeName.Type()
+			e.typesByName[smttypes.GoTypeName(types.NewPointer(typeName.Type()))] = types.NewPointer(typeName.Type())
+		}
+	}
+	// the predeclared types of the hierarchy: basic types, error and any
+	for _, t := range ts.Hierarchy().Types {
+		if typeName, ok := types.Universe.Lookup(t.Name).(*types.TypeName); ok {
+			e.typesByName[t.Name] = typeName.Type()
+		}
+	}
+
+	return e, nil
+}
+
+// Explore runs the function of the package on symbolic arguments and returns all its feasible paths
+func (e *Explorer) Explore(name string) ([]Path, error) {
+	fn, err := e.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	instantiations, err := e.instantiations(fn)
+	if err != nil {
+		return nil, err
+	}
+
+	e.target = fn
+	e.paths = nil
+	e.stringInputs = false
+	for _, inst := range instantiations {
+		if err := e.exploreInstantiation(fn, inst); err != nil {
+			return nil, err
+		}
+	}
+
+	return e.paths, nil
+}
+
+// Notes describe the inputs the last exploration has left out by the bounds of the encoding,
+// the generated tests state them
+func (e *Explorer) Notes() []string {
+	if !e.stringInputs {
+		return nil
+	}
+
+	bound := e.sCtx.TypesCtx.MaxStringLength
+	return []string{fmt.Sprintf("The strings of the inputs are at most %d bytes long, the longer strings aren't explored.", bound)}
+}
+
+// exploreInstantiation explores the paths of the function with the type arguments of the instantiation,
+// every instantiation has arguments of its own
+func (e *Explorer) exploreInstantiation(fn *Function, inst instantiation) error {
+	st := newState()
+	st.pushFrame(fn, nil, inst.typeArgs)
+	st.assume(inst.cond)
+	e.instantiation = inst
+	e.arguments = nil
+	e.initGlobals(st)
+
+	// the receiver of the method is its first argument
+	if recv := fn.Signature.Recv(); recv != nil {
+		name := recv.Name()
+		if name == "" || name == "_" {
+			name = "recv"
+		}
+
+		value, err := e.newArgument(st, name, recv.Type())
+		if err != nil {
+			return err
+		}
+		e.arguments = append(e.arguments, argument{obj: recv, t: recv.Type(), value: value})
+		st.define(recv, value)
+	}
+
+	params := fn.Signature.Params()
+	for i := 0; i < params.Len(); i++ {
+		param := params.At(i)
+		name := param.Name()
+		if name == "" || name == "_" {
+			name = fmt.Sprintf("arg%d", i)
+		}
+
+		t := e.instantiate(st, param.Type())
+		value, err := e.newArgument(st, name, t)
+		if err != nil {
+			return err
+		}
+
+		e.arguments = append(e.arguments, argument{obj: param, t: t, value: value})
+		st.define(param, value)
+	}
+	if err := e.defineResults(st, fn); err != nil {
+		return err
+	}
+
+	inputs := e.arguments
+	if e.SymbolicGlobals {
+		inputs = append(slices.Clip(inputs), e.globals...)
+	}
+	for _, input := range inputs {
+		e.stringInputs = e.stringInputs || e.holdsStrings(input.t, make(map[types.Type]bool))
+	}
+
+	e.initial = st.clone()
+	return e.run(st)
+}
+
+// run explores the paths starting from the state until all of them are finished
+func (e *Explorer) run(st *State) error {
+	e.worklist = []*State{st}
+	for len(e.worklist) > 0 {
+		st := e.worklist[len(e.worklist)-1]
+		e.worklist = e.worklist[:len(e.worklist)-1]
+
+		successors, err := e.step(st)
+		switch {
+		case errors.Is(err, errInfeasible), errors.Is(err, errStopped):
+		case gaveUp(err):
+			e.giveUp(st, err)
+		case err != nil:
+			if finishErr := e.finish(st, Unsupported, nil, err.Error()); finishErr != nil {
+				return finishErr
+			}
+		default:
+			// the first successor is explored first
+			for i := len(successors) - 1; i >= 0; i-- {
+				e.worklist = append(e.worklist, successors[i])
+			}
+		}
+	}
+
+	return nil
+}
+
+// defineResults creates the named results of the function, they start with zero values
+func (e *Explorer) defineResults(st *State, fn *Function) error {
+	results := fn.Signature.Results()
+	for i := 0; i < results.Len(); i++ {
+		result := results.At(i)
+		if result.Name() == "" {
+			continue
+		}
+
+		value, err := e.zeroValue(e.instantiate(st, result.Type()))
+		if err != nil {
+			return err
+		}
+		st.define(result, value)
+	}
+
+	return nil
+}
+
+func (e *Explorer) function(obj *types.Func) (*Function, error) {
+	if fn, ok := e.functions[obj]; ok {
+		return fn, nil
+	}
+
+	decl, ok := e.decls[obj]
+	if !ok {
+		return nil, fmt.Errorf("function %s has no body in %s", obj.FullName(), e.pkg.Package.Name())
+	}
+
+	fn, err := buildFunction(e.pkg.Fset, e.pkg.Info, obj, decl.Body)
+	if err != nil {
+		return nil, err
+	}
+	e.functions[obj] = fn
+
+	return fn, nil
+}
+
+// feasible checks that the path condition of the state together with cond is satisfiable,
+// the condition the solver gives up on isn't
+func (e *Explorer) feasible(st *State, cond z3.Bool) bool {
+	sat, err := e.satisfiable(st, cond)
+	return err == nil && sat
+}
+
+// satisfiable checks the path condition of the state together with cond
+func (e *Explorer) satisfiable(st *State, cond z3.Bool) (bool, error) {
+	if value, isLiteral := cond.AsBool(); isLiteral {
+		if !value {
+			return false, nil
+		}
+		if len(st.pc) == 0 {
+			return true, nil
+		}
+	}
+
+	solver := e.sCtx.Solver
+	solver.Push()
+	defer solver.Pop()
+
+	solver.Assert(st.condition(e.sCtx.Ctx))
+	solver.Assert(cond)
+	return e.check()
+}
+
+// check runs the solver on its assertions. The solver is interrupted after SolverTimeout, the check
+// gives up with *z3.ErrSatUnknown then
+func (e *Explorer) check() (bool, error) {
+	if e.SolverTimeout <= 0 {
+		return e.sCtx.Solver.Check()
+	}
+
+	timer := time.AfterFunc(e.SolverTimeout, e.sCtx.Ctx.Interrupt)
+	sat, err := e.sCtx.Solver.Check()
+	if !timer.Stop() && gaveUp(err) {
+		err = &z3.ErrSatUnknown{Reason: fmt.Sprintf("no answer in %s", e.SolverTimeout)}
+	}
+
+	return sat, err
+}
+
+// seeds are the simple values of the scalar inputs confirm tries: all of them are zeros, then one of them
+// is 1 or -1 and the others are zeros
+func (e *Explorer) seeds() []z3.Bool {
+	ctx := e.sCtx.Ctx
+	inputs := make([]z3.Value, 0)
+	values := make([]smt.SymValue, 0, len(e.arguments))
+	if e.summary != nil {
+		values = append(values, e.summary.Params...)
+	}
+	for _, arg := range e.arguments {
+		values = append(values, arg.value)
+	}
+	if e.SymbolicGlobals {
+		for _, global := range e.globals {
+			values = append(values, global.value)
+		}
+	}
+	for _, value := range values {
+		switch value := value.(type) {
+		case smt.SymBool:
+			inputs = append(inputs, value.Bool())
+		case smt.SymInt:
+			inputs = append(inputs, value.Int())
+		case smt.SymFloat:
+			inputs = append(inputs, value.Float())
+		case smt.SymComplex:
+			inputs = append(inputs, value.Real(), value.Imag())
+		}
+	}
+	if len(inputs) == 0 {
+		return nil
+	}
+
+	// floats are fixed by their bits, so the zeros are +0
+	pin := func(input z3.Value, n int) z3.Bool {
+		switch input := input.(type) {
+		case z3.Bool:
+			return input.Eq(ctx.FromBool(n != 0))
+		case z3.Float:
+			return input.Eq(ctx.FromFloat64(float64(n), input.Sort()))
+		default:
+			return input.(z3.Int).Eq(ctx.FromInt(int64(n), input.Sort()).(z3.Int))
+		}
+	}
+	zeros := make([]z3.Bool, len(inputs))
+	for i, input := range inputs {
+		zeros[i] = pin(input, 0)
+	}
+
+	result := []z3.Bool{ctx.FromBool(true).And(zeros...)}
+	for i, input := range inputs {
+		for _, n := range []int{1, -1} {
+			if _, ok := input.(z3.Bool); ok && n < 0 {
+				continue
+			}
+
+			seed := append(slices.Clone(zeros), pin(input, n))
+			seed = slices.Delete(seed, i, i+1)
+			result = append(result, ctx.FromBool(true).And(seed...))
+		}
+	}
+
+	return result
+}
+
+func gaveUp(err error) bool {
+	var unknown *z3.ErrSatUnknown
+	return errors.As(err, &unknown)
+}
+
+// giveUp records the path of the state the solver has given up on as incomplete, the path has no model to
+// decode the inputs from
+func (e *Explorer) giveUp(st *State, err error) {
+	condition := st.condition(e.sCtx.Ctx)
+	message := "the solver gave up on the path: " + err.Error()
+	if e.summary != nil {
+		e.summary.Paths = append(e.summary.Paths, SummaryPath{
+			Status: Incomplete, Message: message, Condition: condition, choices: st.choices})
+		return
+	}
+
+	e.paths = append(e.paths, Path{
+		Status:            Incomplete,
+		Message:           message,
+		Condition:         condition,
+		TypeArguments:     e.instantiation.names,
+		Evaluations:       st.evaluations,
+		UnknownCalls:      st.unknownCalls,
+		ApproximatedCalls: st.approximatedCalls,
+	})
+}
+
+// fork returns the indices of the conditions the state goes on with: the feasible ones when the path
+// is explored and the one the path has taken when a summary is replayed, which needs no solver.
+// The alternatives the solver gives up on end as incomplete paths
+func (e *Explorer) fork(st *State, conds ...z3.Bool) []int {
+	if alternative, ok := st.replayed(); ok {
+		return []int{alternative}
+	}
+
+	result := make([]int, 0, len(conds))
+	for i, cond := range conds {
+		sat, err := e.satisfiable(st, cond)
+		switch {
+		case gaveUp(err):
+			unknown := st.clone()
+			unknown.take(i)
+			unknown.assume(cond)
+			e.giveUp(unknown, err)
+		case err == nil && sat:
+			result = append(result, i)
+		}
+	}
+
+	return result
+}
+
+// panicIf forks the state into the path where cond holds and the function panics with the runtime error,
+// the state itself continues with cond not holding
+func (e *Explorer) panicIf(st *State, cond z3.Bool, message string) error {
+	continues := false
+	for _, alternative := range e.fork(st, cond, cond.Not()) {
+		if alternative == 1 {
+			continues = true
+			continue
+		}
+
+		panicking := st.clone()
+		panicking.take(alternative)
+		panicking.assume(cond)
+		if err := e.raise(panicking, nil, message); err != nil {
+			return err
+		}
+	}
+
+	if !continues {
+		return errInfeasible
+	}
+	st.take(1)
+	st.assume(cond.Not())
+
+	return nil
+}
+
+// finish records the path of the state
+func (e *Explorer) finish(st *State, status Status, results []smt.SymValue, message string) error {
+	solver := e.sCtx.Solver
+	solver.Push()
+	defer solver.Pop()
+
+	condition := st.condition(e.sCtx.Ctx)
+	solver.Assert(condition)
+	sat, err := e.check()
+	if gaveUp(err) {
+		e.giveUp(st, err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !sat {
+		return nil
+	}
+	if e.summary != nil {
+		e.summary.Paths = append(e.summary.Paths, SummaryPath{
+			Status: status, Message: message, Condition: condition, Results: results, choices: st.choices})
+		return nil
+	}
+	model := solver.Model()
+	// the test of the path through the uninterpreted results checks its outcome only when it's confirmed
+	var unchecked []string
+	if len(st.applications) > 0 {
+		confirmed, err := e.confirm(st)
+		if err != nil {
+			return err
+		}
+		if confirmed != nil {
+			model = confirmed
+		} else {
+			unchecked = appliedFunctions(st.applications)
+		}
+	}
+
+	path := Path{
+		Status:            status,
+		Message:           message,
+		Condition:         condition,
+		TypeArguments:     e.instantiation.names,
+		Evaluations:       st.evaluations,
+		UnknownCalls:      st.unknownCalls,
+		ApproximatedCalls: st.approximatedCalls,
+	}
+	if path.Arguments, err = e.decodeArguments(model); err != nil {
+		return err
+	}
+	globals, err := e.decodeGlobals(model)
+	if err != nil {
+		return err
+	}
+	if e.SymbolicGlobals {
+		path.Globals = globals
+	}
+
+	// the tests check only whether the error results are nil and their dynamic types
+	expected := make([]reflect.Value, 0, len(results))
+	resultTypes := e.target.Signature.Results()
+	for i, result := range results {
+		t := e.instantiate(e.initial, resultTypes.At(i).Type())
+		value, err := e.decode(model, st, result, t)
+		if err != nil {
+			return fmt.Errorf("result %d: %w", i, err)
+		}
+		path.Results = append(path.Results, value)
+
+		if isError(t) {
+			if value, err = e.decodeError(model, st, result); err != nil {
+				return fmt.Errorf("result %d: %w", i, err)
+			}
+		}
+		if _, ok := t.Underlying().(*types.Signature); ok {
+			value = reflect.ValueOf(testgen.Function{Nil: isNilFunction(result)})
+		}
+		expected = append(expected, value)
+	}
+
+	name := e.target.Name
+	testName := name
+	arguments := path.Arguments
+	if recv := e.target.Signature.Recv(); recv != nil {
+		testName = recvTypeName(recv.Type()) + name
+	}
+	path.Test = testgen.Case{
+		Name:      fmt.Sprintf("Test%s%s%d", strings.ToUpper(testName[:1]), testName[1:], len(e.paths)+1),
+		Function:  testFunction(name, path.TypeArguments),
+		Results:   expected,
+		Panics:    status == Panicked,
+		Unchecked: unchecked,
+	}
+	if e.target.Signature.Recv() != nil {
+		path.Test.Receiver, arguments = arguments[0].Value, arguments[1:]
+	}
+	for _, arg := range arguments {
+		path.Test.Arguments = append(path.Test.Arguments, arg.Value)
+	}
+	// the tests set the initialized variables too, the calls of the other tests may have changed them
+	for _, global := range globals {
+		path.Test.Globals = append(path.Test.Globals, testgen.Global{Name: global.Name, Value: global.Value})
+	}
+
+	e.paths = append(e.paths, path)
+	return nil
+}
+
+// decodeArguments builds the arguments of the explored function in the model
+func (e *Explorer) decodeArguments(model *z3.Model) ([]smt.DecodedArgument, error) {
+	result := make([]smt.DecodedArgument, 0, len(e.arguments))
+	for _, arg := range e.arguments {
+		value, err := e.decode(model, e.initial, arg.value, arg.t)
+		if err != nil {
+			return nil, fmt.Errorf("argument %s: %w", arg.obj.Name(), err)
+		}
+		result = append(result, smt.DecodedArgument{Name: arg.obj.Name(), Value: value})
+	}
+
+	return result, nil
+}
+
+func (e *Explorer) unsupported(node interface{ Pos() token.Pos }, format string, args ...any) error {
+	return &UnsupportedError{Pos: e.pkg.Fset.Position(node.Pos()), Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,372 @@
+package engine
+
+import (
+	"github.com/aclements/go-z3/z3"
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+	smttypes "github.com/vldF/symbolic_execution_course/constraints/smt/types"
+	"github.com/vldF/symbolic_execution_course/constraints/testgen"
+	"math"
+	"math/bits"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newSymContext() *smt.SymContext {
+	ctx := z3.NewContext(&z3.Config{})
+	return &smt.SymContext{
+		Solver: z3.NewSolver(ctx),
+		Ctx:    ctx,
+		TypesCtx: smt.TypesContext{
+			MaxInt:      math.MaxInt32,
+			MinInt:      math.MinInt32,
+			IntSize:     bits.UintSize,
+			MaxFloat64:  math.MaxFloat64,
+			MinFloat64:  -math.MaxFloat64,
+			Float64Size: 64,
+
+			MaxStringLength: 4,
+			MaxSliceLength:  3,
+		},
+	}
+}
+
+// exploreAndRun writes the source into a package of its own, explores the functions and runs the tests
+// generated for their paths against them. Every path must be complete and its test must pass
+func exploreAndRun(t *testing.T, source string, names ...string) map[string][]Path {
+	t.Helper()
+	return exploreAndRunWith(t, source, func(*Explorer) {}, names...)
+}
+
+// exploreAndRunWith is exploreAndRun by the explorers with the settings made by configure
+func exploreAndRunWith(t *testing.T, source string, configure func(explorer *Explorer), names ...string) map[string][]Path {
+	t.Helper()
+
+	goCommand, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("the go command isn't available to run the generated tests")
+	}
+
+	dir := t.TempDir()
+	write := func(name string, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("go.mod", "module probe\n\ngo 1.22\n")
+	write("probe.go", source)
+
+	pkg, err := smttypes.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	explored := make(map[string][]Path)
+	cases := make([]testgen.Case, 0)
+	for _, name := range names {
+		explorer, err := NewExplorer(newSymContext(), pkg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		configure(explorer)
+
+		paths, err := explorer.Explore(name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for i, path := range paths {
+			if path.Status == Incomplete || path.Status == Unsupported {
+				t.Fatalf("%s: path %d is %s", name, i+1, path)
+			}
+			cases = append(cases, path.Test)
+		}
+		explored[name] = paths
+	}
+
+	file := testgen.RenderFile(pkg.Package.Name(), cases)
+	write("probe_test.go", file)
+
+	test := exec.Command(goCommand, "test", ".")
+	test.Dir = dir
+	test.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOWORK=off")
+	if output, err := test.CombinedOutput(); err != nil {
+		t.Fatalf("the generated tests fail: %v\n%s\n%s", err, output, file)
+	}
+
+	return explored
+}
+
+// outcomes are the outcomes of the paths as the paths print them: the results or the panic messages
+func outcomes(paths []Path) []string {
+	result := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if path.Status != Returned {
+			result = append(result, path.Status.String()+": "+path.Message)
+			continue
+		}
+
+		results := make([]string, 0, len(path.Results))
+		for _, value := range path.Results {
+			results = append(results, testgen.Literal(value))
+		}
+		result = append(result, strings.TrimSpace("returned "+strings.Join(results, ", ")))
+	}
+	return result
+}
+
+func hasOutcome(paths []Path, outcome string) bool {
+	for _, o := range outcomes(paths) {
+		if o == outcome {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAliasing(t *testing.T) {
+	const source = `package probe
+
+func Copied(s []int) int {
+	t := s
+	t[0] = 5
+	if s[0] == 5 {
+		return 1
+	}
+	return 0
+}
+
+func set(s []int) {
+	s[0] = 7
+}
+
+func Passed(s []int) int {
+	set(s)
+	return s[0]
+}
+
+func Counted(m map[string]int) int {
+	n := m
+	n["a"] = 1
+	return m["a"]
+}
+
+func Detached(a [2]int) int {
+	b := a
+	b[0] = 5
+	return a[0] - b[0]
+}
+`
+	explored := exploreAndRun(t, source, "Copied", "Passed", "Counted", "Detached")
+
+	if hasOutcome(explored["Copied"], "returned 0") {
+		t.Errorf("Copied: the write through the copy isn't seen: %v", outcomes(explored["Copied"]))
+	}
+	if !hasOutcome(explored["Passed"], "returned 7") {
+		t.Errorf("Passed: the write of the callee isn't seen: %v", outcomes(explored["Passed"]))
+	}
+	if !hasOutcome(explored["Counted"], "returned 1") {
+		t.Errorf("Counted: the write through the copy isn't seen: %v", outcomes(explored["Counted"]))
+	}
+}
+
+func TestNestedStruct(t *testing.T) {
+	const source = `package probe
+
+type Inner struct {
+	N int
+}
+
+type Outer struct {
+	In Inner
+}
+
+func Assigned(o Outer, i Inner) int {
+	o.In = i
+	if o.In.N > 3 {
+		return 1
+	}
+	return 0
+}
+
+func Literal(o Outer) int {
+	o.In = Inner{N: 4}
+	return o.In.N
+}
+`
+	explored := exploreAndRun(t, source, "Assigned", "Literal")
+
+	if len(explored["Assigned"]) != 2 {
+		t.Errorf("Assigned: got %v, want both branches", outcomes(explored["Assigned"]))
+	}
+}
+
+func TestNilComparison(t *testing.T) {
+	const source = `package probe
+
+type Shape interface {
+	Area() int
+}
+
+type Square struct {
+	Side int
+}
+
+func (s Square) Area() int {
+	return s.Side * s.Side
+}
+
+func NilMap(m map[int]int) int {
+	if m == nil {
+		return -1
+	}
+	return len(m)
+}
+
+func Same(a Shape, b Shape) bool {
+	if a == b {
+		return true
+	}
+	return false
+}
+`
+	explored := exploreAndRun(t, source, "NilMap", "Same")
+
+	if !hasOutcome(explored["NilMap"], "returned -1") {
+		t.Errorf("NilMap: the nil map isn't found: %v", outcomes(explored["NilMap"]))
+	}
+	if !hasOutcome(explored["Same"], "returned true") || !hasOutcome(explored["Same"], "returned false") {
+		t.Errorf("Same: got %v, want both results", outcomes(explored["Same"]))
+	}
+}
+
+func TestShift(t *testing.T) {
+	const source = `package probe
+
+func Shift(x int, n int) int {
+	return x >> n
+}
+
+func Unsigned(x int, n uint) int {
+	return x << n
+}
+`
+	explored := exploreAndRun(t, source, "Shift", "Unsigned")
+
+	if !hasOutcome(explored["Shift"], "panicked: runtime error: negative shift amount") {
+		t.Errorf("Shift: the negative count doesn't panic: %v", outcomes(explored["Shift"]))
+	}
+	if len(explored["Unsigned"]) != 1 {
+		t.Errorf("Unsigned: got %v, want the unsigned count to never panic", outcomes(explored["Unsigned"]))
+	}
+}
+
+func TestStringConversions(t *testing.T) {
+	const source = `package probe
+
+func Capital(s string) string {
+	b := []byte(s)
+	if len(b) > 0 && b[0] >= 'a' && b[0] <= 'z' {
+		b[0] -= 'a' - 'A'
+	}
+	return string(b)
+}
+
+func Runes(s string) int {
+	return len([]rune(s))
+}
+
+func Joined(r []rune) bool {
+	if string(r) == "hé" {
+		return true
+	}
+	return false
+}
+`
+	explored := exploreAndRun(t, source, "Capital", "Runes", "Joined")
+
+	if !hasOutcome(explored["Joined"], "returned true") {
+		t.Errorf("Joined: the runes of the string aren't found: %v", outcomes(explored["Joined"]))
+	}
+}
+
+func TestMadeErrors(t *testing.T) {
+	const source = `package probe
+
+import (
+	"errors"
+	"fmt"
+)
+
+var errNegative = errors.New("negative")
+
+func Half(x int) (int, error) {
+	if x < 0 {
+		return 0, errNegative
+	}
+	if x > 100 {
+		return 0, fmt.Errorf("%d is too large", x)
+	}
+	if x%2 == 1 {
+		return 0, errors.New("odd")
+	}
+	return x / 2, nil
+}
+
+func Sentinel(x int) string {
+	_, err := Half(x)
+	if err == errNegative {
+		return "negative"
+	}
+	if err != nil {
+		return "other"
+	}
+	return "ok"
+}
+
+func Message(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
+`
+	explored := exploreAndRun(t, source, "Half", "Sentinel", "Message")
+
+	for _, outcome := range []string{"returned \"negative\"", "returned \"other\"", "returned \"ok\""} {
+		if !hasOutcome(explored["Sentinel"], outcome) {
+			t.Errorf("Sentinel: %s is missing in %v", outcome, outcomes(explored["Sentinel"]))
+		}
+	}
+}
+
+func TestConcretizedError(t *testing.T) {
+	const source = `package probe
+
+import (
+	"strconv"
+	"strings"
+)
+
+func Number(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+func Unsigned(s string) (int, error) {
+	return strconv.Atoi(strings.TrimPrefix(s, "-"))
+}
+`
+	explored := exploreAndRunWith(t, source, func(explorer *Explorer) {
+		explorer.DefaultPolicy = Concretize
+		explorer.Implementations["strings.TrimPrefix"] = strings.TrimPrefix
+	}, "Number", "Unsigned")
+
+	if !hasOutcome(explored["Number"], "returned -1") {
+		t.Errorf("Number: the error of strconv.Atoi isn't returned: %v", outcomes(explored["Number"]))
+	}
+}
@@ -0,0 +1,192 @@
+package engine
+
+import (
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	smttypes "github.com/vldF/symbolic_execution_course/constraints/smt/types"
+	"go/ast"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// instantiation is a choice of type arguments for the type parameters of the explored function.
+// The type parameters are type variables of the solver, cond binds them to the chosen types
+type instantiation struct {
+	typeArgs map[*types.TypeParam]types.Type
+	names    []string
+	cond     z3.Bool
+}
+
+// typeOf returns the type of the expression with the type parameters of the current function instantiated
+func (e *Explorer) typeOf(st *State, expr ast.Expr) types.Type {
+	return e.instantiate(st, e.pkg.Info.TypeOf(expr))
+}
+
+func (e *Explorer) instantiate(st *State, t types.Type) types.Type {
+	return e.substitute(t, st.frame().typeArgs)
+}
+
+// substitute replaces the type parameters in the type with the type arguments
+func (e *Explorer) substitute(t types.Type, typeArgs map[*types.TypeParam]types.Type) types.Type {
+	if len(typeArgs) == 0 {
+		return t
+	}
+
+	switch t := t.(type) {
+	case *types.TypeParam:
+		if arg, ok := typeArgs[t]; ok {
+			return arg
+		}
+	case *types.Pointer:
+		return types.NewPointer(e.substitute(t.Elem(), typeArgs))
+	case *types.Slice:
+		return types.NewSlice(e.substitute(t.Elem(), typeArgs))
+	case *types.Array:
+		return types.NewArray(e.substitute(t.Elem(), typeArgs), t.Len())
+	case *types.Map:
+		return types.NewMap(e.substitute(t.Key(), typeArgs), e.substitute(t.Elem(), typeArgs))
+	case *types.Tuple:
+		vars := make([]*types.Var, t.Len())
+		for i := range vars {
+			v := t.At(i)
+			vars[i] = types.NewVar(v.Pos(), v.Pkg(), v.Name(), e.substitute(v.Type(), typeArgs))
+		}
+		return types.NewTuple(vars...)
+	case *types.Named:
+		if t.TypeArgs().Len() == 0 {
+			return t
+		}
+
+		args := make([]types.Type, t.TypeArgs().Len())
+		for i := range args {
+			args[i] = e.substitute(t.TypeArgs().At(i), typeArgs)
+		}
+
+		// the shared context makes identical instances the same object, so caches keyed by types work
+		instance, err := types.Instantiate(e.instances, t.Origin(), args, false)
+		if err == nil {
+			return instance
+		}
+	}
+
+	return t
+}
+
+// instantiations returns every choice of type arguments satisfying the constraints of the type parameters.
+// A non-generic function has the only empty instantiation
+func (e *Explorer) instantiations(fn *Function) ([]instantiation, error) {
+	result := []instantiation{{cond: e.sCtx.Ctx.FromBool(true)}}
+
+	params := fn.Signature.TypeParams()
+	for i := 0; i < params.Len(); i++ {
+		param := params.At(i)
+		candidates := e.typeSet(param)
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("type parameter %s of %s has no instantiations among the types of %s", param, fn.Name, e.pkg.Package.Name())
+		}
+
+		// [ can't appear in the declared type names, so the variable doesn't clash with the types
+		variable := e.ts.NewTypeVar(fmt.Sprintf("[%s]", param.Obj().Name()))
+		chosen := make([]z3.Bool, len(candidates))
+		for j, candidate := range candidates {
+			t, err := e.ts.Type(smttypes.GoTypeName(candidate))
+			if err != nil {
+				return nil, err
+			}
+			chosen[j] = variable.Eq(t)
+		}
+		e.sCtx.Solver.Assert(e.sCtx.Ctx.FromBool(false).Or(chosen...))
+
+		extended := make([]instantiation, 0, len(result)*len(candidates))
+		for _, inst := range result {
+			for j, candidate := range candidates {
+				typeArgs := make(map[*types.TypeParam]types.Type, len(inst.typeArgs)+1)
+				for tp, arg := range inst.typeArgs {
+					typeArgs[tp] = arg
+				}
+				typeArgs[param] = candidate
+
+				extended = append(extended, instantiation{
+					typeArgs: typeArgs,
+					names:    append(append([]string(nil), inst.names...), smttypes.GoTypeName(candidate)),
+					cond:     inst.cond.And(chosen[j]),
+				})
+			}
+		}
+		result = extended
+	}
+
+	return result, nil
+}
+
+// typeSet returns the concrete types of the hierarchy satisfying the constraint of the type parameter,
+// like ~int | ~float64, comparable or an interface with methods, sorted by name
+func (e *Explorer) typeSet(param *types.TypeParam) []types.Type {
+	constraint := param.Constraint().Underlying().(*types.Interface)
+
+	names := make([]string, 0, len(e.typesByName))
+	for name := range e.typesByName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]types.Type, 0)
+	for _, name := range names {
+		t := e.typesByName[name]
+		if types.IsInterface(t) || isGeneric(t) {
+			continue
+		}
+
+		if types.Satisfies(t, constraint) {
+			result = append(result, t)
+		}
+	}
+
+	return result
+}
+
+func isGeneric(t types.Type) bool {
+	if pointer, ok := t.(*types.Pointer); ok {
+		t = pointer.Elem()
+	}
+
+	named, ok := t.(*types.Named)
+	return ok && named.TypeParams().Len() > 0 && named.TypeArgs().Len() == 0
+}
+
+// calleeTypeArgs instantiates the type parameters of the called function or of the receiver of the called method.
+// The type arguments come from the caller, so its own type parameters are instantiated too
+func (e *Explorer) calleeTypeArgs(st *State, ident *ast.Ident, callee *types.Func, recv types.Type) map[*types.TypeParam]types.Type {
+	signature := callee.Origin().Type().(*types.Signature)
+
+	var params *types.TypeParamList
+	var args *types.TypeList
+	switch {
+	case signature.TypeParams().Len() > 0:
+		params, args = signature.TypeParams(), e.pkg.Info.Instances[ident].TypeArgs
+	case signature.RecvTypeParams().Len() > 0:
+		if pointer, ok := recv.(*types.Pointer); ok {
+			recv = pointer.Elem()
+		}
+		params, args = signature.RecvTypeParams(), recv.(*types.Named).TypeArgs()
+	default:
+		return nil
+	}
+
+	result := make(map[*types.TypeParam]types.Type, params.Len())
+	for i := 0; i < params.Len(); i++ {
+		result[params.At(i)] = e.instantiate(st, args.At(i))
+	}
+
+	return result
+}
+
+// testFunction is the name the generated tests call the function by, with the type arguments if it's generic
+func testFunction(name string, typeArgs []string) string {
+	if len(typeArgs) == 0 {
+		return name
+	}
+
+	return fmt.Sprintf("%s[%s]", name, strings.Join(typeArgs, ", "))
+}
@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+	smttypes "github.com/vldF/symbolic_execution_course/constraints/smt/types"
+	"go/ast"
+	"go/types"
+)
+
+// initGlobals creates the package variables of the state. Symbolic globals are inputs of the explored function,
+// otherwise they start with the values of their initializers. The variables whose initial values can't be
+// built are left out, the paths using them are unsupported
+func (e *Explorer) initGlobals(st *State) {
+	st.globals = make(map[types.Object]int)
+	e.globals = nil
+	e.globalErrors = make(map[types.Object]error)
+
+	vars := make([]*types.Var, 0)
+	scope := e.pkg.Package.Scope()
+	for _, name := range scope.Names() {
+		if v, ok := scope.Lookup(name).(*types.Var); ok {
+			vars = append(vars, v)
+		}
+	}
+	for _, v := range vars {
+		name := v.Name()
+
+		var value smt.SymValue
+		var err error
+		if e.SymbolicGlobals {
+			value, err = e.newArgument(st, name, v.Type())
+		} else {
+			value, err = e.zeroValue(v.Type())
+		}
+		if err != nil {
+			e.globalErrors[v] = err
+			continue
+		}
+
+		st.globals[v] = st.alloc(value)
+	}
+	if !e.SymbolicGlobals {
+		e.runInitializers(st)
+	}
+
+	for _, v := range vars {
+		if address, ok := st.globals[v]; ok {
+			e.globals = append(e.globals, argument{obj: v, t: v.Type(), value: st.heap[address]})
+		}
+	}
+}
+
+// runInitializers assigns the values of their initializers to the package variables in the order of their dependencies
+func (e *Explorer) runInitializers(st *State) {
+	for _, initializer := range e.pkg.Info.InitOrder {
+		if len(initializer.Lhs) != 1 {
+			for _, v := range initializer.Lhs {
+				e.globalErrors[v] = fmt.Errorf("%s is initialized by a call returning several values", v.Name())
+				delete(st.globals, v)
+			}
+			continue
+		}
+
+		v := initializer.Lhs[0]
+		address, ok := st.globals[v]
+		if !ok {
+			continue
+		}
+
+		value, err := e.initialValue(st, initializer.Rhs)
+		if err == nil {
+			value, err = e.convert(value, e.pkg.Info.TypeOf(initializer.Rhs), v.Type())
+		}
+		if err != nil {
+			e.globalErrors[v] = err
+			delete(st.globals, v)
+			continue
+		}
+		st.heap[address] = value
+	}
+}
+
+// initialValue evaluates the initializer. Of the calls only the calls of the modeled library functions are run,
+// as errors.New making the sentinel errors
+func (e *Explorer) initialValue(st *State, rhs ast.Expr) (smt.SymValue, error) {
+	call, ok := ast.Unparen(rhs).(*ast.CallExpr)
+	if !ok {
+		return e.eval(st, rhs)
+	}
+	var ident *ast.Ident
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		ident = fun
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	}
+	callee, ok := e.pkg.Info.Uses[ident].(*types.Func)
+	if !ok || callee.Type().(*types.Signature).Recv() != nil {
+		return e.eval(st, rhs)
+	}
+
+	model, ok := e.Models[callee.Origin().FullName()]
+	if !ok || e.hasPolicy(callee) {
+		return nil, fmt.Errorf("the initializer calls %s, only the calls of the modeled library functions are run", callee.FullName())
+	}
+	modeled, _, err := e.runModel(st, call, callee, model)
+	if err != nil {
+		return nil, err
+	}
+	if modeled.Approximate {
+		return nil, fmt.Errorf("the initializer calls %s, its model is approximate", callee.FullName())
+	}
+	for _, fact := range modeled.Facts {
+		st.assume(fact)
+	}
+
+	return modeled.Results[0], nil
+}
+
+// unknownVariable is the error of the variable which is neither local nor a package variable with an initial value
+func (e *Explorer) unknownVariable(ident *ast.Ident, obj types.Object) error {
+	if err, ok := e.globalErrors[obj]; ok {
+		return e.unsupported(ident, "package variable %s has no initial value: %s", ident.Name, err)
+	}
+	return e.unsupported(ident, "variable %s isn't local", ident.Name)
+}
+
+// decodeGlobals builds the initial values of the package variables in the model, the tests set them before
+// the call. The initialized variables which can't be decoded are left to their initializers
+func (e *Explorer) decodeGlobals(model *z3.Model) ([]smt.DecodedArgument, error) {
+	result := make([]smt.DecodedArgument, 0, len(e.globals))
+	for _, global := range e.globals {
+		// the errors made by the initializers keep their identity, the tests don't replace them
+		if iface, ok := global.value.(smttypes.SymInterface); ok && !e.SymbolicGlobals {
+			if _, made := iface.DynamicValue(smttypes.LibraryError); made {
+				continue
+			}
+		}
+
+		value, err := e.decode(model, e.initial, global.value, global.t)
+		if err != nil && !e.SymbolicGlobals {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("package variable %s: %w", global.obj.Name(), err)
+		}
+		result = append(result, smt.DecodedArgument{Name: global.obj.Name(), Value: value})
+	}
+
+	return result, nil
+}
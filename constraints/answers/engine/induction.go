@@ -0,0 +1,416 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+	"github.com/vldF/symbolic_execution_course/constraints/testgen"
+	"go/ast"
+	"go/parser"
+	"go/types"
+	"strings"
+)
+
+// invariantPrefix starts the comments stating invariants of the loops right below them:
+//
+//	//invariant: sum >= 0 && i <= n
+//	for i := 0; i < n; i++ {
+const invariantPrefix = "invariant:"
+
+// InvariantResult is the outcome of proving the invariant of the loop by k-induction.
+// The invariant is checked every time the loop is about to check its condition
+type InvariantResult struct {
+	Loop      *Loop
+	Invariant string
+	K         int
+	Proved    bool
+
+	// Counterexample are the arguments of the function for which the invariant doesn't hold
+	// after Iteration iterations. The base case fails then
+	Counterexample []smt.DecodedArgument
+	Iteration      int
+
+	// Induction are the values of the variables the loop changes from which the invariant holds
+	// K times in a row but doesn't hold after that. The inductive step fails then,
+	// the invariant may need a larger K or a strengthening
+	Induction []smt.DecodedArgument
+}
+
+func (result InvariantResult) String() string {
+	prefix := fmt.Sprintf("%s: %s", result.Loop.Pos, result.Invariant)
+	switch {
+	case result.Proved:
+		return fmt.Sprintf("%s: proved by %d-induction", prefix, result.K)
+	case result.Counterexample != nil:
+		return fmt.Sprintf("%s: fails after %d iterations for (%s)", prefix, result.Iteration, describeValues(result.Counterexample))
+	default:
+		return fmt.Sprintf("%s: isn't %d-inductive, from (%s) it holds for k = %d checks in a row but not for the next one, increase k or strengthen the invariant",
+			prefix, result.K, describeValues(result.Induction), result.K)
+	}
+}
+
+func describeValues(values []smt.DecodedArgument) string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		result = append(result, fmt.Sprintf("%s = %s", value.Name, testgen.Literal(value.Value)))
+	}
+
+	return strings.Join(result, ", ")
+}
+
+// ProveInvariants proves the invariants stated by the comments above the loops of the function
+func (e *Explorer) ProveInvariants(name string, k int) ([]InvariantResult, error) {
+	fn, err := e.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]InvariantResult, 0)
+	for i, loop := range fn.Loops {
+		for _, invariant := range e.annotations(loop) {
+			proof, err := e.ProveInvariant(name, i, invariant, k)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, proof)
+		}
+	}
+
+	return result, nil
+}
+
+// annotations returns the invariants stated in the comment group ending on the line above the loop
+func (e *Explorer) annotations(loop *Loop) []string {
+	result := make([]string, 0)
+	for _, file := range e.pkg.Files {
+		if file.Pos() > loop.stmt.Pos() || loop.stmt.Pos() >= file.End() {
+			continue
+		}
+
+		for _, group := range file.Comments {
+			if e.pkg.Fset.Position(group.End()).Line != loop.Pos.Line-1 {
+				continue
+			}
+
+			for _, comment := range group.List {
+				text := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+				if invariant, ok := strings.CutPrefix(text, invariantPrefix); ok {
+					result = append(result, strings.TrimSpace(invariant))
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// ProveInvariant proves the invariant of the loop of the function by k-induction. Loops are numbered
+// in the source order from zero. The base case checks the invariant for the first k iterations
+// on all paths reaching the loop. The inductive step starts from the loop with arbitrary values
+// of the variables the loop changes, assumes the invariant for k iterations and checks it after them
+func (e *Explorer) ProveInvariant(name string, loopIndex int, invariant string, k int) (InvariantResult, error) {
+	fn, err := e.lookup(name)
+	if err != nil {
+		return InvariantResult{}, err
+	}
+	if loopIndex < 0 || loopIndex >= len(fn.Loops) {
+		return InvariantResult{}, fmt.Errorf("function %s has no loop %d", name, loopIndex)
+	}
+	if k < 1 {
+		return InvariantResult{}, fmt.Errorf("k must be positive")
+	}
+
+	loop := fn.Loops[loopIndex]
+	expr, err := e.parseInvariant(loop, invariant)
+	if err != nil {
+		return InvariantResult{}, err
+	}
+	changed, err := e.changedVariables(loop)
+	if err != nil {
+		return InvariantResult{}, err
+	}
+
+	instantiations, err := e.instantiations(fn)
+	if err != nil {
+		return InvariantResult{}, err
+	}
+
+	// the loop is run iteration by iteration, no path is cut before the k-th iteration
+	defer func(bound int, summarize bool, paths []Path) {
+		e.LoopBound, e.SummarizeLoops, e.paths = bound, summarize, paths
+		e.watch = nil
+	}(e.LoopBound, e.SummarizeLoops, e.paths)
+	e.LoopBound = max(e.LoopBound, k)
+	e.SummarizeLoops = false
+	e.target = fn
+	e.paths = nil
+
+	result := InvariantResult{Loop: loop, Invariant: invariant, K: k}
+	for _, inst := range instantiations {
+		// the base case: the invariant holds for the first k checks of the condition
+		starts := make([]*State, 0)
+		e.watch = func(st *State, block *Block) error {
+			switch {
+			case block == loop.exit:
+				return errStopped
+			case block != loop.header:
+				return nil
+			}
+
+			iteration := st.frame().iterations[loop]
+			if iteration == 0 {
+				starts = append(starts, st.clone())
+			}
+			if iteration >= k {
+				return errStopped
+			}
+
+			holds, err := e.evalInvariant(st, expr)
+			if err != nil {
+				return err
+			}
+			if result.Counterexample == nil {
+				counterexample, err := e.model(st, holds.Not(), e.decodeArguments)
+				if err != nil {
+					return err
+				}
+				if counterexample != nil {
+					result.Counterexample, result.Iteration = counterexample, iteration
+				}
+			}
+
+			return e.assumeFeasible(st, holds)
+		}
+		if err := e.exploreInstantiation(fn, inst); err != nil {
+			return result, err
+		}
+		if result.Counterexample != nil {
+			return result, e.checkPaths()
+		}
+
+		// the inductive step: from any state satisfying the invariant k times in a row, it holds after that
+		for _, start := range starts {
+			if err := e.havoc(start, changed); err != nil {
+				return result, err
+			}
+			// the start is changed by the exploration, the values are decoded from its copy
+			havocked := start.clone()
+
+			e.watch = func(st *State, block *Block) error {
+				switch {
+				case block == loop.exit:
+					return errStopped
+				case block != loop.header:
+					return nil
+				}
+
+				holds, err := e.evalInvariant(st, expr)
+				if err != nil {
+					return err
+				}
+
+				if st.frame().iterations[loop] < k {
+					return e.assumeFeasible(st, holds)
+				}
+
+				if result.Induction == nil {
+					induction, err := e.model(st, holds.Not(), func(model *z3.Model) ([]smt.DecodedArgument, error) {
+						return e.decodeVariables(model, havocked, changed)
+					})
+					if err != nil {
+						return err
+					}
+					result.Induction = induction
+				}
+				return errStopped
+			}
+
+			if err := e.watch(start, loop.header); err != nil {
+				if errors.Is(err, errInfeasible) || errors.Is(err, errStopped) {
+					continue
+				}
+				return result, err
+			}
+			if err := e.run(start); err != nil {
+				return result, err
+			}
+			if result.Induction != nil {
+				return result, e.checkPaths()
+			}
+		}
+	}
+
+	result.Proved = true
+	return result, e.checkPaths()
+}
+
+func (e *Explorer) lookup(name string) (*Function, error) {
+	if strings.Contains(name, ".") {
+		return e.lookupMethod(name)
+	}
+
+	obj, ok := e.pkg.Package.Scope().Lookup(name).(*types.Func)
+	if !ok {
+		return nil, fmt.Errorf("function %s isn't found in %s", name, e.pkg.Package.Name())
+	}
+
+	return e.function(obj)
+}
+
+// parseInvariant type checks the invariant in the scope of the loop body
+func (e *Explorer) parseInvariant(loop *Loop, invariant string) (ast.Expr, error) {
+	expr, err := parser.ParseExprFrom(e.pkg.Fset, "invariant", invariant, 0)
+	if err != nil {
+		return nil, fmt.Errorf("invariant %s: %w", invariant, err)
+	}
+
+	if err := types.CheckExpr(e.pkg.Fset, e.pkg.Package, loop.stmt.Body.Lbrace, expr, e.pkg.Info); err != nil {
+		return nil, fmt.Errorf("invariant %s: %w", invariant, err)
+	}
+	if !types.Identical(e.pkg.Info.TypeOf(expr).Underlying(), types.Typ[types.Bool]) &&
+		!types.Identical(e.pkg.Info.TypeOf(expr), types.Typ[types.UntypedBool]) {
+		return nil, fmt.Errorf("invariant %s isn't a boolean expression", invariant)
+	}
+
+	return expr, nil
+}
+
+func (e *Explorer) evalInvariant(st *State, expr ast.Expr) (z3.Bool, error) {
+	value, err := e.eval(st, expr)
+	if err != nil {
+		return z3.Bool{}, err
+	}
+
+	return value.(smt.SymBool).Bool(), nil
+}
+
+// model decodes the values in the model of the path condition of the state together with cond.
+// It returns nil when cond can't hold on the path
+func (e *Explorer) model(st *State, cond z3.Bool, decode func(model *z3.Model) ([]smt.DecodedArgument, error)) ([]smt.DecodedArgument, error) {
+	solver := e.sCtx.Solver
+	solver.Push()
+	defer solver.Pop()
+
+	solver.Assert(st.condition(e.sCtx.Ctx))
+	solver.Assert(cond)
+	sat, err := e.check()
+	if err != nil || !sat {
+		return nil, err
+	}
+
+	return decode(solver.Model())
+}
+
+func (e *Explorer) assumeFeasible(st *State, cond z3.Bool) error {
+	if !e.feasible(st, cond) {
+		return errInfeasible
+	}
+	st.assume(cond)
+
+	return nil
+}
+
+// changedVariables returns the variables declared outside of the loop which the loop assigns.
+// Loops changing memory in other ways can't start from arbitrary values of the variables
+func (e *Explorer) changedVariables(loop *Loop) ([]*types.Var, error) {
+	result := make([]*types.Var, 0)
+	seen := make(map[*types.Var]bool)
+	add := func(expr ast.Expr) error {
+		ident, ok := ast.Unparen(expr).(*ast.Ident)
+		if !ok {
+			return e.unsupported(expr, "the loop changes memory other than variables")
+		}
+
+		obj, ok := e.pkg.Info.Uses[ident].(*types.Var)
+		if ok && !seen[obj] && obj.Pos() < loop.stmt.Body.Lbrace {
+			seen[obj] = true
+			result = append(result, obj)
+		}
+		return nil
+	}
+
+	var err error
+	inspect := func(node ast.Node) bool {
+		if err != nil {
+			return false
+		}
+
+		switch node := node.(type) {
+		case *ast.AssignStmt:
+			for _, lhs := range node.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok && ident.Name == "_" {
+					continue
+				}
+				if err = add(lhs); err != nil {
+					return false
+				}
+			}
+		case *ast.IncDecStmt:
+			err = add(node.X)
+		case *ast.CallExpr:
+			if fun := e.pkg.Info.Types[node.Fun]; !fun.IsType() && !fun.IsBuiltin() {
+				err = e.unsupported(node, "the loop calls functions")
+			}
+		case *ast.FuncLit:
+			return false
+		}
+		return err == nil
+	}
+
+	for _, node := range []ast.Node{loop.stmt.Cond, loop.stmt.Post, loop.stmt.Body} {
+		if node != nil {
+			ast.Inspect(node, inspect)
+		}
+	}
+
+	return result, err
+}
+
+// havoc gives the variables fresh values, any values they may have at the loop
+func (e *Explorer) havoc(st *State, variables []*types.Var) error {
+	for _, v := range variables {
+		address, ok := st.variable(v)
+		if !ok {
+			continue
+		}
+
+		value, err := e.newArgument(st, v.Name()+"'", e.instantiate(st, v.Type()))
+		if err != nil {
+			return err
+		}
+		st.heap[address] = value
+	}
+	st.frame().iterations = make(map[*Loop]int)
+
+	return nil
+}
+
+func (e *Explorer) decodeVariables(model *z3.Model, st *State, variables []*types.Var) ([]smt.DecodedArgument, error) {
+	result := make([]smt.DecodedArgument, 0, len(variables))
+	for _, v := range variables {
+		address, ok := st.variable(v)
+		if !ok {
+			continue
+		}
+
+		value, err := e.decode(model, st, st.heap[address], e.instantiate(st, v.Type()))
+		if err != nil {
+			return nil, fmt.Errorf("variable %s: %w", v.Name(), err)
+		}
+		result = append(result, smt.DecodedArgument{Name: v.Name(), Value: value})
+	}
+
+	return result, nil
+}
+
+// checkPaths fails when some path was cut or couldn't be explored, the proof doesn't cover it then
+func (e *Explorer) checkPaths() error {
+	for _, path := range e.paths {
+		if path.Status == Incomplete || path.Status == Unsupported {
+			return fmt.Errorf("path %s isn't checked", path)
+		}
+	}
+
+	return nil
+}
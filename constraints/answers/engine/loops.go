@@ -0,0 +1,334 @@
+package engine
+
+import (
+	"github.com/aclements/go-z3/z3"
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+)
+
+// loopSummary is the closed form of a loop over an integer counter changed by a constant step,
+// whose body only adds values affine in the counter to integer variables:
+//
+//	for i := 0; i < n; i += 2 {
+//		sum += 3*i + k
+//		count++
+//	}
+//
+// The number of iterations follows from the bounds of the counter, the sum of an affine
+// sequence is known, so the loop is run at once whatever its trip count is
+type loopSummary struct {
+	counter *types.Var
+	// op compares the counter with the bound, the counter is on the left
+	op    token.Token
+	bound ast.Expr
+	step  int64
+
+	updates []update
+}
+
+// update adds the value to the variable on every iteration, the nil value stands for 1
+type update struct {
+	target *types.Var
+	sign   int64
+	value  ast.Expr
+}
+
+// summarizeLoop returns the summary of the loop or nil when the loop doesn't fit the pattern
+func (b *builder) summarizeLoop(stmt *ast.ForStmt) *loopSummary {
+	if stmt.Post == nil || stmt.Cond == nil {
+		return nil
+	}
+
+	counter, step, ok := b.increment(stmt.Post)
+	if !ok || step == 0 {
+		return nil
+	}
+	result := &loopSummary{counter: counter, step: step}
+
+	cond, ok := ast.Unparen(stmt.Cond).(*ast.BinaryExpr)
+	if !ok {
+		return nil
+	}
+	switch {
+	case b.isVar(cond.X, counter):
+		result.op, result.bound = cond.Op, cond.Y
+	case b.isVar(cond.Y, counter):
+		result.op, result.bound = mirrored[cond.Op], cond.X
+	default:
+		return nil
+	}
+
+	// the counter must move towards the bound
+	switch result.op {
+	case token.LSS, token.LEQ:
+		if step < 0 {
+			return nil
+		}
+	case token.GTR, token.GEQ:
+		if step > 0 {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	assigned := map[*types.Var]bool{counter: true}
+	for _, stmt := range stmt.Body.List {
+		update, ok := b.update(stmt)
+		if !ok || assigned[update.target] {
+			return nil
+		}
+		assigned[update.target] = true
+		result.updates = append(result.updates, update)
+	}
+
+	if !b.isAffine(result.bound, nil, assigned) {
+		return nil
+	}
+	for _, update := range result.updates {
+		if update.value != nil && !b.isAffine(update.value, counter, assigned) {
+			return nil
+		}
+	}
+
+	return result
+}
+
+var mirrored = map[token.Token]token.Token{
+	token.LSS: token.GTR,
+	token.LEQ: token.GEQ,
+	token.GTR: token.LSS,
+	token.GEQ: token.LEQ,
+}
+
+// increment recognizes i++, i--, i += c and i -= c with the constant c
+func (b *builder) increment(stmt ast.Stmt) (*types.Var, int64, bool) {
+	switch stmt := stmt.(type) {
+	case *ast.IncDecStmt:
+		counter, ok := b.integerVar(stmt.X)
+		if stmt.Tok == token.DEC {
+			return counter, -1, ok
+		}
+		return counter, 1, ok
+	case *ast.AssignStmt:
+		if len(stmt.Lhs) != 1 || len(stmt.Rhs) != 1 || stmt.Tok != token.ADD_ASSIGN && stmt.Tok != token.SUB_ASSIGN {
+			return nil, 0, false
+		}
+
+		counter, ok := b.integerVar(stmt.Lhs[0])
+		value := b.info.Types[stmt.Rhs[0]].Value
+		if !ok || value == nil {
+			return nil, 0, false
+		}
+
+		step, exact := constant.Int64Val(constant.ToInt(value))
+		if !exact {
+			return nil, 0, false
+		}
+		if stmt.Tok == token.SUB_ASSIGN {
+			step = -step
+		}
+		return counter, step, true
+	default:
+		return nil, 0, false
+	}
+}
+
+// update recognizes x++, x--, x += e, x -= e, x = x + e, x = e + x and x = x - e
+func (b *builder) update(stmt ast.Stmt) (update, bool) {
+	switch stmt := stmt.(type) {
+	case *ast.IncDecStmt:
+		target, ok := b.integerVar(stmt.X)
+		if stmt.Tok == token.DEC {
+			return update{target: target, sign: -1}, ok
+		}
+		return update{target: target, sign: 1}, ok
+	case *ast.AssignStmt:
+		if len(stmt.Lhs) != 1 || len(stmt.Rhs) != 1 {
+			return update{}, false
+		}
+
+		target, ok := b.integerVar(stmt.Lhs[0])
+		if !ok {
+			return update{}, false
+		}
+
+		switch stmt.Tok {
+		case token.ADD_ASSIGN:
+			return update{target: target, sign: 1, value: stmt.Rhs[0]}, true
+		case token.SUB_ASSIGN:
+			return update{target: target, sign: -1, value: stmt.Rhs[0]}, true
+		case token.ASSIGN:
+			sum, ok := ast.Unparen(stmt.Rhs[0]).(*ast.BinaryExpr)
+			switch {
+			case !ok:
+			case sum.Op == token.ADD && b.isVar(sum.X, target):
+				return update{target: target, sign: 1, value: sum.Y}, true
+			case sum.Op == token.ADD && b.isVar(sum.Y, target):
+				return update{target: target, sign: 1, value: sum.X}, true
+			case sum.Op == token.SUB && b.isVar(sum.X, target):
+				return update{target: target, sign: -1, value: sum.Y}, true
+			}
+		}
+	}
+
+	return update{}, false
+}
+
+func (b *builder) integerVar(expr ast.Expr) (*types.Var, bool) {
+	ident, ok := ast.Unparen(expr).(*ast.Ident)
+	if !ok {
+		return nil, false
+	}
+
+	obj, ok := b.info.ObjectOf(ident).(*types.Var)
+	if !ok {
+		return nil, false
+	}
+
+	basic, ok := obj.Type().Underlying().(*types.Basic)
+	return obj, ok && basic.Info()&types.IsInteger != 0
+}
+
+func (b *builder) isVar(expr ast.Expr, v *types.Var) bool {
+	ident, ok := ast.Unparen(expr).(*ast.Ident)
+	return ok && b.info.ObjectOf(ident) == v
+}
+
+// isAffine checks that the integer expression is affine in the counter and reads no variables assigned by the loop
+// except the counter. The nil counter means the expression must not depend on the loop at all
+func (b *builder) isAffine(expr ast.Expr, counter *types.Var, assigned map[*types.Var]bool) bool {
+	tv := b.info.Types[expr]
+	if tv.Value != nil {
+		return true
+	}
+	if basic, ok := tv.Type.Underlying().(*types.Basic); !ok || basic.Info()&types.IsInteger == 0 {
+		return false
+	}
+
+	switch expr := expr.(type) {
+	case *ast.ParenExpr:
+		return b.isAffine(expr.X, counter, assigned)
+	case *ast.Ident:
+		obj, ok := b.info.ObjectOf(expr).(*types.Var)
+		return ok && (obj == counter || !assigned[obj])
+	case *ast.UnaryExpr:
+		return (expr.Op == token.ADD || expr.Op == token.SUB) && b.isAffine(expr.X, counter, assigned)
+	case *ast.BinaryExpr:
+		switch expr.Op {
+		case token.ADD, token.SUB:
+			return b.isAffine(expr.X, counter, assigned) && b.isAffine(expr.Y, counter, assigned)
+		case token.MUL:
+			// one of the factors is constant, so the product stays affine
+			return b.isAffine(expr.X, counter, assigned) && b.isAffine(expr.Y, counter, assigned) &&
+				(b.info.Types[expr.X].Value != nil || b.info.Types[expr.Y].Value != nil)
+		}
+	case *ast.CallExpr:
+		// len of the variables the loop doesn't assign
+		ident, ok := ast.Unparen(expr.Fun).(*ast.Ident)
+		if ok && ident.Name == "len" && b.info.Types[expr.Fun].IsBuiltin() {
+			return b.isInvariant(expr.Args[0], assigned)
+		}
+	}
+
+	return false
+}
+
+// isInvariant checks that the variable isn't assigned by the loop
+func (b *builder) isInvariant(expr ast.Expr, assigned map[*types.Var]bool) bool {
+	ident, ok := ast.Unparen(expr).(*ast.Ident)
+	if !ok {
+		return false
+	}
+
+	obj, ok := b.info.ObjectOf(ident).(*types.Var)
+	return ok && !assigned[obj]
+}
+
+// applyLoopSummary runs the summarized loop: the counter gets its value after the last iteration
+// and every updated variable gets the sum of its increments
+func (e *Explorer) applyLoopSummary(st *State, node *loopNode) error {
+	s := node.loop.summary
+	fr := st.frame()
+
+	counterAddress, ok := fr.locals[s.counter]
+	if !ok {
+		return e.unsupported(node, "counter %s isn't local", s.counter.Name())
+	}
+	initial := st.heap[counterAddress].(smt.SymInt).Int()
+
+	bound, err := e.eval(st, s.bound)
+	if err != nil {
+		return err
+	}
+	count := e.tripCount(s, initial, bound.(smt.SymInt).Int())
+
+	// the values are affine in the counter: value(i) = first + slope*i
+	sums := make([]z3.Int, 0, len(s.updates))
+	for _, update := range s.updates {
+		if update.value == nil {
+			sums = append(sums, count)
+			continue
+		}
+
+		st.heap[counterAddress] = smt.IntValue(e.intConst(0))
+		first, err := e.eval(st, update.value)
+		if err != nil {
+			return err
+		}
+		st.heap[counterAddress] = smt.IntValue(e.intConst(1))
+		second, err := e.eval(st, update.value)
+		if err != nil {
+			return err
+		}
+		st.heap[counterAddress] = smt.IntValue(initial)
+
+		firstInt, secondInt := first.(smt.SymInt).Int(), second.(smt.SymInt).Int()
+		slope := secondInt.Sub(firstInt)
+
+		// the counter takes the values initial + step*k for k < count, their sum is
+		// count*initial + step*count*(count-1)/2
+		pairs := count.Mul(count.Sub(e.intConst(1))).Div(e.intConst(2))
+		counterSum := count.Mul(initial).Add(e.intConst(s.step).Mul(pairs))
+		sums = append(sums, count.Mul(firstInt).Add(slope.Mul(counterSum)))
+	}
+
+	for i, update := range s.updates {
+		address, ok := st.variable(update.target)
+		if !ok {
+			return e.unsupported(node, "variable %s isn't local", update.target.Name())
+		}
+
+		value := st.heap[address].(smt.SymInt).Int()
+		st.heap[address] = smt.IntValue(value.Add(e.intConst(update.sign).Mul(sums[i])))
+	}
+	st.heap[counterAddress] = smt.IntValue(initial.Add(e.intConst(s.step).Mul(count)))
+
+	return nil
+}
+
+// tripCount encodes the number of iterations of the loop from the initial value of the counter and the bound
+func (e *Explorer) tripCount(s *loopSummary, initial z3.Int, bound z3.Int) z3.Int {
+	zero := e.intConst(0)
+
+	var distance z3.Int
+	step := s.step
+	if step > 0 {
+		distance = bound.Sub(initial)
+	} else {
+		distance = initial.Sub(bound)
+		step = -step
+	}
+
+	// the strict comparison stops on the bound, the non-strict one makes one more iteration
+	if s.op == token.LSS || s.op == token.GTR {
+		count := distance.Add(e.intConst(step - 1)).Div(e.intConst(step))
+		return distance.GT(zero).IfThenElse(count, zero).(z3.Int)
+	}
+
+	count := distance.Div(e.intConst(step)).Add(e.intConst(1))
+	return distance.GE(zero).IfThenElse(count, zero).(z3.Int)
+}
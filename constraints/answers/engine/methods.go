@@ -0,0 +1,45 @@
+package engine
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+// lookupMethod finds the method by the name of its type and its own name, as Circle.Area or (*Circle).Scale.
+// Promoted methods and methods of generic types aren't explored
+func (e *Explorer) lookupMethod(name string) (*Function, error) {
+	typeName, methodName, _ := strings.Cut(strings.NewReplacer("(", "", ")", "", "*", "").Replace(name), ".")
+
+	obj, ok := e.pkg.Package.Scope().Lookup(typeName).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("type %s isn't found in %s", typeName, e.pkg.Package.Name())
+	}
+	named, ok := obj.Type().(*types.Named)
+	if !ok {
+		return nil, fmt.Errorf("type %s has no methods", typeName)
+	}
+	if named.TypeParams().Len() > 0 {
+		return nil, fmt.Errorf("methods of the generic type %s aren't supported", typeName)
+	}
+
+	for i := 0; i < named.NumMethods(); i++ {
+		if method := named.Method(i); method.Name() == methodName {
+			return e.function(method)
+		}
+	}
+
+	return nil, fmt.Errorf("method %s isn't found in %s", methodName, typeName)
+}
+
+// recvTypeName is the name of the type of the receiver without the pointer
+func recvTypeName(t types.Type) string {
+	if pointer, ok := t.(*types.Pointer); ok {
+		t = pointer.Elem()
+	}
+	if named, ok := t.(*types.Named); ok {
+		return named.Obj().Name()
+	}
+
+	return t.String()
+}
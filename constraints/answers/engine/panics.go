@@ -0,0 +1,169 @@
+package engine
+
+import (
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+	"go/ast"
+	"go/types"
+)
+
+// deferred is a call evaluated by the defer statement. The function literal gets the variables
+// of the frame deferring it, they're shared as the cells of the heap
+type deferred struct {
+	fn       *Function
+	recv     smt.SymValue
+	args     []smt.SymValue
+	typeArgs map[*types.TypeParam]types.Type
+	captured map[types.Object]int
+}
+
+var anyType = types.Universe.Lookup("any").Type()
+
+// execDefer evaluates the function, the receiver and the arguments of the deferred call
+// and pushes the call onto the defer stack of the frame
+func (e *Explorer) execDefer(st *State, node *deferNode) error {
+	fr := st.frame()
+	if node.literal != nil {
+		args, err := e.callArguments(st, node.call, node.literal.Signature, fr.typeArgs)
+		if err != nil {
+			return err
+		}
+
+		fr.defers = append(fr.defers, deferred{fn: node.literal, args: args, typeArgs: fr.typeArgs, captured: capture(fr)})
+		return nil
+	}
+
+	fun := e.calledFunction(node.call)
+	if e.isFunctionValue(fun) {
+		return e.deferValue(st, node, fun)
+	}
+
+	var ident *ast.Ident
+	var recv smt.SymValue
+	var recvType types.Type
+	switch fun := fun.(type) {
+	case *ast.Ident:
+		ident = fun
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+		if selection, ok := e.pkg.Info.Selections[fun]; ok {
+			if selection.Kind() != types.MethodVal || len(selection.Index()) > 1 {
+				return e.unsupported(node.call, "deferred call of %s", types.ExprString(fun))
+			}
+
+			recvType = e.instantiate(st, selection.Recv())
+			if types.IsInterface(recvType) {
+				return e.unsupported(node.call, "deferred call of the interface method %s", fun.Sel.Name)
+			}
+
+			var err error
+			if recv, err = e.receiver(st, fun, selection.Obj().(*types.Func), recvType); err != nil {
+				return err
+			}
+		}
+	default:
+		return e.unsupported(node.call, "deferred call of %T", fun)
+	}
+
+	callee, ok := e.pkg.Info.Uses[ident].(*types.Func)
+	if !ok {
+		return e.unsupported(node.call, "deferred call of %s", ident.Name)
+	}
+	fn, err := e.function(callee.Origin())
+	if err != nil {
+		return e.unsupported(node.call, "deferred call of %s: %s", callee.FullName(), err)
+	}
+
+	typeArgs := e.calleeTypeArgs(st, ident, callee, recvType)
+	args, err := e.callArguments(st, node.call, callee.Origin().Type().(*types.Signature), typeArgs)
+	if err != nil {
+		return err
+	}
+
+	fr.defers = append(fr.defers, deferred{fn: fn, recv: recv, args: args, typeArgs: typeArgs})
+	return nil
+}
+
+// callDeferred pops the last deferred call of the frame and enters it, the frame goes on returning
+// or unwinding when the call returns
+func (e *Explorer) callDeferred(st *State) error {
+	fr := st.frame()
+	d := fr.defers[len(fr.defers)-1]
+	fr.defers = fr.defers[:len(fr.defers)-1]
+	e.follow(d.fn.Object)
+
+	if err := e.enterFunction(st, d.fn, nil, d.recv, d.args, d.typeArgs); err != nil {
+		return err
+	}
+
+	callee := st.frame()
+	callee.deferred = true
+	for obj, address := range d.captured {
+		callee.locals[obj] = address
+	}
+
+	return nil
+}
+
+// raise starts the panic of the state with the value, the nil value is the runtime error with the message.
+// The frames run their deferred calls from the innermost one and the path panics when none of them
+// recovers. The unwinding state goes to the worklist, without deferred calls the path panics at once
+func (e *Explorer) raise(st *State, value smt.SymValue, message string) error {
+	unwinds := false
+	for _, fr := range st.frames {
+		unwinds = unwinds || len(fr.defers) > 0
+	}
+	if !unwinds {
+		return e.finish(st, Panicked, nil, message)
+	}
+
+	// runtime errors are recovered as their messages
+	if value == nil {
+		var err error
+		if value, err = e.convert(e.sCtx.NewStringConst(message), types.Typ[types.String], anyType); err != nil {
+			return err
+		}
+	}
+
+	st.panic = &panicState{value: value, message: message}
+	st.frame().panicking = true
+	e.worklist = append(e.worklist, st)
+	return nil
+}
+
+// unwind runs the next deferred call of the panicking frame. When none is left, the frame is dropped
+// and its caller goes on unwinding, the path panics when no frame is left
+func (e *Explorer) unwind(st *State) ([]*State, error) {
+	fr := st.frame()
+	if len(fr.defers) > 0 {
+		return []*State{st}, e.callDeferred(st)
+	}
+
+	st.popFrame()
+	if len(st.frames) == 0 {
+		if err := e.finish(st, Panicked, nil, st.panic.message); err != nil {
+			return nil, err
+		}
+		return nil, errStopped
+	}
+
+	caller := st.frame()
+	caller.panicking, caller.returned = true, false
+	return []*State{st}, nil
+}
+
+// recover stops the panic when it's called by a deferred call run by the panicking frame and returns
+// the value of the panic. The frame returns normally after its remaining deferred calls then.
+// Otherwise recover returns nil
+func (e *Explorer) recover(st *State) (smt.SymValue, error) {
+	n := len(st.frames)
+	if st.panic == nil || n < 2 || !st.frames[n-1].deferred || !st.frames[n-2].panicking {
+		return e.zeroValue(anyType)
+	}
+
+	recovered := st.frames[n-2]
+	recovered.panicking, recovered.returned, recovered.returnValues = false, true, nil
+
+	value := st.panic.value
+	st.recovered, st.panic = st.panic, nil
+	return value, nil
+}
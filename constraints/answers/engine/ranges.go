@@ -0,0 +1,147 @@
+package engine
+
+import (
+	"github.com/aclements/go-z3/z3"
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+	"go/ast"
+	"go/types"
+	"slices"
+)
+
+// iteration is the state of a range loop kept in its temporary: the ranged value, the position
+// of the current element and the keys of the map visited before it. The contents of the referenced
+// slices and maps are read at every step, the writes of the body are seen by the next elements. The position in a string
+// is the index of the byte the current rune starts at, the other positions count the elements
+type iteration struct {
+	smt.SymValueBase
+
+	value    smt.SymValue
+	t        types.Type
+	position z3.Int
+	visited  []z3.Value
+}
+
+// length is the position the iteration stops at
+func (it iteration) length(st *State) z3.Int {
+	switch value := st.contents(it.value).(type) {
+	case smt.SymSlice:
+		return value.Len()
+	case smt.SymString:
+		return value.Len()
+	case smt.SymMap:
+		return value.Len()
+	default:
+		return value.(smt.SymInt).Int()
+	}
+}
+
+// elementTypes returns the types of the keys and of the values the range over the type produces
+func elementTypes(t types.Type) (types.Type, types.Type) {
+	switch underlying := t.Underlying().(type) {
+	case *types.Slice:
+		return types.Typ[types.Int], underlying.Elem()
+	case *types.Array:
+		return types.Typ[types.Int], underlying.Elem()
+	case *types.Map:
+		return underlying.Key(), underlying.Elem()
+	case *types.Basic:
+		if underlying.Info()&types.IsString != 0 {
+			return types.Typ[types.Int], types.Typ[types.Rune]
+		}
+	}
+
+	return types.Default(t), nil
+}
+
+func (e *Explorer) execRange(st *State, node *rangeNode) error {
+	value, err := e.eval(st, node.value)
+	if err != nil {
+		return err
+	}
+
+	switch st.contents(value).(type) {
+	case smt.SymSlice, smt.SymString, smt.SymMap, smt.SymInt:
+	default:
+		return e.unsupported(node.value, "range over %T", value)
+	}
+
+	st.define(node.iteration, iteration{value: value, t: e.typeOf(st, node.value), position: e.intConst(0)})
+	return nil
+}
+
+func (e *Explorer) rangeTestCondition(st *State, test *rangeTest) z3.Bool {
+	it := st.heap[st.frame().locals[test.iteration]].(iteration)
+	return it.position.LT(it.length(st))
+}
+
+// execElement assigns the current element to the iteration variables. The next key of a map is any key
+// not visited yet, the path is infeasible when the map has no such key
+func (e *Explorer) execElement(st *State, node *elementNode) error {
+	address := st.frame().locals[node.iteration]
+	it := st.heap[address].(iteration)
+
+	key := smt.SymValue(smt.IntValue(it.position))
+	var value smt.SymValue
+	switch ranged := st.contents(it.value).(type) {
+	case smt.SymSlice:
+		value, _ = ranged.At(it.position)
+	case smt.SymString:
+		r, _ := ranged.DecodeRune(it.position)
+		value = smt.IntValue(r)
+	case smt.SymMap:
+		next, exists := ranged.NextKey(it.visited)
+		if err := e.assumeFeasible(st, exists); err != nil {
+			return err
+		}
+		it.visited = append(slices.Clip(it.visited), next)
+		st.heap[address] = it
+
+		element, _ := ranged.Lookup(next)
+		var err error
+		if key, err = ranged.KeyValue(next); err != nil {
+			return err
+		}
+		if value, err = smt.ScalarValue(element); err != nil {
+			return err
+		}
+	}
+
+	keyType, valueType := elementTypes(it.t)
+	if err := e.assignIterationVariable(st, node, node.key, key, keyType); err != nil {
+		return err
+	}
+	return e.assignIterationVariable(st, node, node.value, value, valueType)
+}
+
+func (e *Explorer) assignIterationVariable(st *State, node *elementNode, target ast.Expr, value smt.SymValue, t types.Type) error {
+	if target == nil {
+		return nil
+	}
+	if ident, ok := target.(*ast.Ident); ok && ident.Name == "_" {
+		return nil
+	}
+
+	if node.define {
+		st.define(e.pkg.Info.Defs[target.(*ast.Ident)], value)
+		return nil
+	}
+
+	value, err := e.convert(value, t, e.typeOf(st, target))
+	if err != nil {
+		return err
+	}
+	return e.store(st, target, value)
+}
+
+// execAdvance moves the iteration to the next element, the next rune of a string starts after the bytes of the current one
+func (e *Explorer) execAdvance(st *State, node *advanceNode) {
+	address := st.frame().locals[node.iteration]
+	it := st.heap[address].(iteration)
+
+	width := e.intConst(1)
+	if s, ok := it.value.(smt.SymString); ok {
+		_, width = s.DecodeRune(it.position)
+	}
+	it.position = it.position.Add(width)
+	st.heap[address] = it
+}
@@ -0,0 +1,243 @@
+package engine
+
+import (
+	"github.com/aclements/go-z3/z3"
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+	"go/types"
+)
+
+// State is a point of a path: the path condition, the call stack and the heap.
+// Variables live in the heap too, so pointers to them and closures share the cells
+type State struct {
+	pc     []z3.Bool
+	frames []*frame
+	heap   []smt.SymValue
+
+	// globals are the cells of the package variables, they're created before the explored function runs
+	globals map[types.Object]int
+
+	// evaluations are the completed evaluations of decisions on the path
+	evaluations []Evaluation
+
+	// choices are the alternatives taken by the forks of the path, replays are the summaries being replayed
+	choices []int
+	replays []replay
+
+	// unknownCalls are the calls the path has taken by the policies of their functions
+	unknownCalls []UnknownCall
+
+	// approximatedCalls are the functions whose models the path has taken are uninterpreted, applications are
+	// the calls of the uninterpreted functions the test of the path is confirmed on
+	approximatedCalls []string
+	applications      []application
+
+	// panic is the panic being unwound, recovered is the last panic stopped by recover
+	panic     *panicState
+	recovered *panicState
+}
+
+// panicState is a panic: the value passed to panic converted to any and the message of the path
+type panicState struct {
+	value   smt.SymValue
+	message string
+}
+
+// replay follows a path of the summary of a function. It ends when the frame of the function returns
+type replay struct {
+	choices []int
+	next    int
+	depth   int
+}
+
+type frame struct {
+	fn     *Function
+	block  *Block
+	index  int
+	locals map[types.Object]int
+
+	// typeArgs instantiate the type parameters of the generic function
+	typeArgs map[*types.TypeParam]types.Type
+
+	// pending are the values of the conditions of the decisions being evaluated
+	pending map[*Decision][]ConditionValue
+	// iterations are the numbers of the started iterations of the loops
+	iterations map[*Loop]int
+
+	// results are the temporaries of the caller which get the results, they're nil for the entry function
+	results []*types.Var
+
+	// defers are the deferred calls, the last one runs first. deferred marks the frame of a deferred call
+	defers   []deferred
+	deferred bool
+	// returned is set when the function runs its deferred calls after returning or recovering,
+	// panicking is set when it runs them unwinding the panic of the state
+	returned  bool
+	panicking bool
+	// returnValues are the values returned by the return statement, the named results are read after the deferred calls
+	returnValues []smt.SymValue
+}
+
+func newState() *State {
+	// the zero address is nil
+	return &State{heap: make([]smt.SymValue, 1)}
+}
+
+func (st *State) clone() *State {
+	result := &State{
+		pc:     append([]z3.Bool(nil), st.pc...),
+		frames: make([]*frame, len(st.frames)),
+		heap:   append([]smt.SymValue(nil), st.heap...),
+
+		// the cells of the package variables never change, so they may be shared
+		globals: st.globals,
+
+		evaluations: append([]Evaluation(nil), st.evaluations...),
+
+		choices: append([]int(nil), st.choices...),
+		replays: append([]replay(nil), st.replays...),
+
+		unknownCalls: append([]UnknownCall(nil), st.unknownCalls...),
+
+		approximatedCalls: append([]string(nil), st.approximatedCalls...),
+		applications:      append([]application(nil), st.applications...),
+
+		panic:     st.panic,
+		recovered: st.recovered,
+	}
+
+	for i, fr := range st.frames {
+		copied := *fr
+		copied.locals = make(map[types.Object]int, len(fr.locals))
+		for obj, address := range fr.locals {
+			copied.locals[obj] = address
+		}
+		// the values are never changed in place, so they may be shared
+		copied.pending = make(map[*Decision][]ConditionValue, len(fr.pending))
+		for decision, values := range fr.pending {
+			copied.pending[decision] = values
+		}
+		copied.defers = append([]deferred(nil), fr.defers...)
+		copied.iterations = make(map[*Loop]int, len(fr.iterations))
+		for loop, count := range fr.iterations {
+			copied.iterations[loop] = count
+		}
+		result.frames[i] = &copied
+	}
+
+	return result
+}
+
+func (st *State) frame() *frame {
+	return st.frames[len(st.frames)-1]
+}
+
+func (st *State) assume(cond z3.Bool) {
+	st.pc = append(st.pc, cond)
+}
+
+func (st *State) alloc(value smt.SymValue) int {
+	st.heap = append(st.heap, value)
+	return len(st.heap) - 1
+}
+
+// contents returns the slice or the map the value refers to, the other values are the contents themselves
+func (st *State) contents(value smt.SymValue) smt.SymValue {
+	if ref, ok := value.(contentsRef); ok {
+		return st.heap[ref.cell]
+	}
+	return value
+}
+
+func (st *State) pushFrame(fn *Function, results []*types.Var, typeArgs map[*types.TypeParam]types.Type) *frame {
+	fr := &frame{
+		fn:       fn,
+		block:    fn.Entry,
+		locals:   make(map[types.Object]int),
+		typeArgs: typeArgs,
+		pending:  make(map[*Decision][]ConditionValue),
+		results:  results,
+
+		iterations: make(map[*Loop]int),
+	}
+	st.frames = append(st.frames, fr)
+
+	return fr
+}
+
+func (st *State) popFrame() *frame {
+	fr := st.frame()
+	st.frames = st.frames[:len(st.frames)-1]
+
+	// the replayed function has returned
+	for len(st.replays) > 0 && st.replays[len(st.replays)-1].depth > len(st.frames) {
+		st.replays = st.replays[:len(st.replays)-1]
+	}
+
+	return fr
+}
+
+// take records the alternative taken by the fork. The forks of the replayed summaries aren't recorded,
+// the path only takes the path of the summary
+func (st *State) take(alternative int) {
+	if len(st.replays) == 0 {
+		st.choices = append(st.choices, alternative)
+	}
+}
+
+// startReplay starts following the choices of the path of the summary in the current frame
+func (st *State) startReplay(choices []int) {
+	st.replays = append(st.replays, replay{choices: choices, depth: len(st.frames)})
+}
+
+// replayed returns the alternative the replayed path takes at the next fork, ok is false when no summary is replayed
+func (st *State) replayed() (alternative int, ok bool) {
+	if len(st.replays) == 0 {
+		return 0, false
+	}
+
+	r := &st.replays[len(st.replays)-1]
+	alternative = r.choices[r.next]
+	r.next++
+	return alternative, true
+}
+
+// variable returns the cell of the local variable of the current frame or of the package variable
+func (st *State) variable(obj types.Object) (int, bool) {
+	if address, ok := st.frame().locals[obj]; ok {
+		return address, true
+	}
+
+	address, ok := st.globals[obj]
+	return address, ok
+}
+
+// define creates a new variable of the current frame
+func (st *State) define(obj types.Object, value smt.SymValue) {
+	st.frame().locals[obj] = st.alloc(value)
+}
+
+// condition is the conjunction of the path condition
+func (st *State) condition(ctx *z3.Context) z3.Bool {
+	return ctx.FromBool(true).And(st.pc...)
+}
+
+// evaluate records the value of the condition of the decision. When the branch goes to one
+// of the targets of the decision, the evaluation of the decision is complete
+func (st *State) evaluate(decision *Decision, condition int, value bool, target *Block) {
+	fr := st.frame()
+
+	values := make([]ConditionValue, len(decision.Conditions))
+	copy(values, fr.pending[decision])
+	values[condition] = False
+	if value {
+		values[condition] = True
+	}
+
+	if target != decision.then && target != decision.els {
+		fr.pending[decision] = values
+		return
+	}
+
+	delete(fr.pending, decision)
+	st.evaluations = append(st.evaluations, Evaluation{Decision: decision, Values: values, Outcome: target == decision.then})
+}
@@ -0,0 +1,328 @@
+package engine
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+	"go/ast"
+	"go/printer"
+	"go/types"
+	"slices"
+	"strings"
+)
+
+// Summary is the disjunction of the paths of a function over its parameters: every path has the condition
+// leading to it and the results it returns. A path is instantiated at a call site by replaying the choices
+// it makes at the forks with the arguments of the call, so the callee is explored once for all its calls
+type Summary struct {
+	Function string
+	Params   []smt.SymValue
+	Paths    []SummaryPath
+
+	// sources are the hashes of the sources of the functions the paths follow, settings are the bounds
+	// and the types of the package the summary is computed with
+	sources  map[string]string
+	settings string
+}
+
+type SummaryPath struct {
+	Status    Status
+	Message   string
+	Condition z3.Bool
+	Results   []smt.SymValue
+
+	choices []int
+}
+
+func (s *Summary) String() string {
+	var result strings.Builder
+	result.WriteString(s.Function)
+	for _, path := range s.Paths {
+		condition := path.Condition.Context().Simplify(path.Condition, nil)
+		outcome := path.Status.String()
+		switch path.Status {
+		case Returned, Recovered:
+			results := make([]string, 0, len(path.Results))
+			for _, value := range path.Results {
+				results = append(results, describe(value))
+			}
+			if len(results) > 0 {
+				outcome += " " + strings.Join(results, ", ")
+			}
+		default:
+			outcome += ": " + path.Message
+		}
+		fmt.Fprintf(&result, "\n  %s => %s", strings.Join(strings.Fields(condition.String()), " "), outcome)
+	}
+
+	return result.String()
+}
+
+// describe prints the term of the scalar value
+func describe(value smt.SymValue) string {
+	switch value := value.(type) {
+	case smt.SymBool:
+		return value.Bool().Context().Simplify(value.Bool(), nil).String()
+	case smt.SymInt:
+		return value.Int().Context().Simplify(value.Int(), nil).String()
+	case smt.SymFloat:
+		return value.Float().Context().Simplify(value.Float(), nil).String()
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// complete tells that every path of the function is in the summary, no path was cut or unsupported
+func (s *Summary) complete() bool {
+	for _, path := range s.Paths {
+		if path.Status == Incomplete || path.Status == Unsupported {
+			return false
+		}
+	}
+	return true
+}
+
+// SummaryCache keeps the summaries of functions. It may outlive the explorer and serve the explorers
+// of the next versions of the package: a summary is computed again when the source of any function
+// its paths follow changes
+type SummaryCache struct {
+	// Computed is the number of the summaries computed, the others are taken from the cache
+	Computed int
+
+	summaries map[string]*Summary
+}
+
+func NewSummaryCache() *SummaryCache {
+	return &SummaryCache{summaries: make(map[string]*Summary)}
+}
+
+// Summarize returns the summary of the function of the package
+func (e *Explorer) Summarize(name string) (*Summary, error) {
+	fn, err := e.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if !summarizable(fn) {
+		return nil, fmt.Errorf("function %s takes parameters which aren't booleans or numbers", name)
+	}
+	if e.Summaries == nil {
+		return e.summarize(fn)
+	}
+
+	return e.cachedSummary(fn)
+}
+
+// summarizable tells that the paths of the function can be explored on unbounded parameters, so its summary covers
+// every call. Those are the non-generic functions whose parameters are booleans, integers and floats
+func summarizable(fn *Function) bool {
+	if fn.Signature.Recv() != nil || fn.Signature.TypeParams().Len() > 0 {
+		return false
+	}
+
+	params := fn.Signature.Params()
+	for i := 0; i < params.Len(); i++ {
+		basic, ok := params.At(i).Type().Underlying().(*types.Basic)
+		if !ok || basic.Info()&(types.IsBoolean|types.IsInteger|types.IsFloat) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// summaryOf returns the summary used by the calls of the function. Nil means the callee is explored at the call:
+// summaries are turned off, the function can't be summarized or some of its paths are incomplete
+func (e *Explorer) summaryOf(fn *Function) (*Summary, error) {
+	if e.Summaries == nil || e.summarizing[fn] || !summarizable(fn) {
+		return nil, nil
+	}
+
+	s, err := e.cachedSummary(fn)
+	if err != nil || !s.complete() {
+		return nil, err
+	}
+
+	// the summary being computed follows the functions this one follows
+	if e.summary != nil {
+		for name, hash := range s.sources {
+			e.summary.sources[name] = hash
+		}
+	}
+
+	return s, nil
+}
+
+func (e *Explorer) cachedSummary(fn *Function) (*Summary, error) {
+	name := fn.Object.FullName()
+	if s, ok := e.Summaries.summaries[name]; ok && e.upToDate(s) {
+		return s, nil
+	}
+
+	s, err := e.summarize(fn)
+	if err != nil {
+		return nil, err
+	}
+	e.Summaries.summaries[name] = s
+	e.Summaries.Computed++
+
+	return s, nil
+}
+
+// upToDate checks that the summary is computed with the current bounds and types and that the sources
+// of the functions it follows haven't changed
+func (e *Explorer) upToDate(s *Summary) bool {
+	if s.settings != e.summarySettings() {
+		return false
+	}
+
+	for name, hash := range s.sources {
+		if e.sourceHash(name) != hash {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Explorer) summarySettings() string {
+	names := make([]string, 0, len(e.typesByName))
+	for name := range e.typesByName {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return fmt.Sprintf("loops %d %t, calls %d %d, types %s",
+		e.LoopBound, e.SummarizeLoops, e.CallDepth, e.RecursionBound, strings.Join(names, " "))
+}
+
+// sourceHash hashes the declaration of the function with the full name, the empty hash means there is no such function
+func (e *Explorer) sourceHash(name string) string {
+	for obj, decl := range e.decls {
+		if obj.FullName() == name {
+			return e.hashDecl(decl)
+		}
+	}
+	return ""
+}
+
+func (e *Explorer) hashDecl(decl *ast.FuncDecl) string {
+	var source bytes.Buffer
+	if err := printer.Fprint(&source, e.pkg.Fset, decl); err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(source.Bytes())
+	return hex.EncodeToString(sum[:])
+}
+
+// follow records that the summary being computed follows the function, it's recomputed when the function changes
+func (e *Explorer) follow(obj *types.Func) {
+	if e.summary == nil {
+		return
+	}
+
+	if decl, ok := e.decls[obj]; ok {
+		e.summary.sources[obj.FullName()] = e.hashDecl(decl)
+	}
+}
+
+// summarize explores the function on unbounded parameters in an exploration of its own and keeps its paths
+func (e *Explorer) summarize(fn *Function) (*Summary, error) {
+	defer func(target *Function, inst instantiation, worklist []*State, arguments []argument, initial *State, paths []Path,
+		watch func(*State, *Block) error, summary *Summary) {
+		e.target, e.instantiation, e.worklist, e.arguments, e.initial, e.paths = target, inst, worklist, arguments, initial, paths
+		e.watch, e.summary = watch, summary
+		delete(e.summarizing, fn)
+	}(e.target, e.instantiation, e.worklist, e.arguments, e.initial, e.paths, e.watch, e.summary)
+
+	s := &Summary{Function: fn.Object.FullName(), sources: make(map[string]string), settings: e.summarySettings()}
+	e.target, e.instantiation, e.arguments, e.paths = fn, instantiation{}, nil, nil
+	e.watch, e.summary = nil, s
+	e.summarizing[fn] = true
+	e.follow(fn.Object)
+
+	st := newState()
+	st.pushFrame(fn, nil, nil)
+	params := fn.Signature.Params()
+	for i := 0; i < params.Len(); i++ {
+		value, err := e.sCtx.NewBasicParameter(fmt.Sprintf("%s.%s", fn.Name, params.At(i).Name()), params.At(i).Type().Underlying().(*types.Basic))
+		if err != nil {
+			return nil, err
+		}
+		s.Params = append(s.Params, value)
+		st.define(params.At(i), value)
+	}
+	if err := e.defineResults(st, fn); err != nil {
+		return nil, err
+	}
+
+	e.initial = st.clone()
+	if err := e.run(st); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// callSummary forks the state calling the function into a state for every path of its summary. The path
+// is replayed with the arguments, the states whose instantiated path conditions are satisfiable go on
+// after the call. A replayed path takes the path its own summary has recorded
+func (e *Explorer) callSummary(st *State, node *callNode, s *Summary, callee *types.Func, args []smt.SymValue) ([]*State, error) {
+	// the call cut by the bounds is the same for every path
+	if fn, _ := e.function(callee); e.exceedsBounds(st, fn) != "" {
+		return []*State{st}, e.invoke(st, node, callee, nil, args, nil)
+	}
+
+	alternatives := make([]int, 0, len(s.Paths))
+	if alternative, ok := st.replayed(); ok {
+		alternatives = append(alternatives, alternative)
+	} else {
+		for i := range s.Paths {
+			alternatives = append(alternatives, i)
+		}
+	}
+
+	successors := make([]*State, 0, len(alternatives))
+	for _, i := range alternatives {
+		successor := st.clone()
+		successor.take(i)
+		if err := e.invoke(successor, node, callee, nil, args, nil); err != nil {
+			return nil, err
+		}
+		successor.startReplay(s.Paths[i].choices)
+
+		returned, err := e.replay(successor)
+		switch {
+		case errors.Is(err, errInfeasible), errors.Is(err, errStopped):
+			continue
+		case err != nil:
+			return nil, err
+		}
+
+		if len(st.replays) > 0 || e.feasible(returned, e.sCtx.Ctx.FromBool(true)) {
+			successors = append(successors, returned)
+		}
+	}
+
+	return successors, nil
+}
+
+// replay steps the state until the replayed function returns, the panicking paths are finished on the way
+func (e *Explorer) replay(st *State) (*State, error) {
+	depth := len(st.frames)
+	for len(st.frames) >= depth {
+		successors, err := e.step(st)
+		if err != nil {
+			return nil, err
+		}
+		if len(successors) == 0 {
+			return nil, errStopped
+		}
+		st = successors[0]
+	}
+
+	return st, nil
+}
@@ -0,0 +1,494 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	"github.com/vldF/symbolic_execution_course/constraints/models"
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+	"go/types"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Policy is what the explorer does at the call of a function it can't explore: the function has no body
+// in the package and no model, as cgo, reflection and the library functions do
+type Policy int
+
+const (
+	// Stop finishes the path as unsupported at the call
+	Stop Policy = iota
+	// Abstract returns uninterpreted functions of the arguments, the calls with equal arguments return equal
+	// results and nothing else is known of them. The paths are confirmed by running the implementations,
+	// the tests of the unconfirmed paths don't check the outcome.
+	// The parameters and the results must be booleans, integers, floats or strings
+	Abstract
+	// Concretize fixes the arguments to their values in the model of the path and runs the real function,
+	// the path goes on with its results. The other values of the arguments aren't explored
+	Concretize
+)
+
+func (policy Policy) String() string {
+	switch policy {
+	case Abstract:
+		return "abstracted"
+	case Concretize:
+		return "concretized"
+	default:
+		return "stopped"
+	}
+}
+
+// ParsePolicy parses the policy by its name: stop, abstract or concretize
+func ParsePolicy(name string) (Policy, error) {
+	switch name {
+	case "stop":
+		return Stop, nil
+	case "abstract":
+		return Abstract, nil
+	case "concretize":
+		return Concretize, nil
+	default:
+		return Stop, fmt.Errorf("unknown policy %s, want stop, abstract or concretize", name)
+	}
+}
+
+// UnknownCall is the call of the function the path has taken by its policy, the path lists every function once
+type UnknownCall struct {
+	Function string
+	Policy   Policy
+}
+
+func (call UnknownCall) String() string {
+	return call.Function + " " + call.Policy.String()
+}
+
+// StandardImplementations are the library functions the explorer can run for the concretized calls.
+// The calls of other functions can't be concretized, teams add their functions to Explorer.Implementations
+func StandardImplementations() map[string]any {
+	return map[string]any{
+		"math.Pow":          math.Pow,
+		"math.Exp":          math.Exp,
+		"math.Log":          math.Log,
+		"math.Sin":          math.Sin,
+		"math.Cos":          math.Cos,
+		"math.Sqrt":         math.Sqrt,
+		"math.Hypot":        math.Hypot,
+		"math.Mod":          math.Mod,
+		"strconv.Itoa":      strconv.Itoa,
+		"strconv.Atoi":      strconv.Atoi,
+		"strconv.Quote":     strconv.Quote,
+		"strings.ToUpper":   strings.ToUpper,
+		"strings.ToLower":   strings.ToLower,
+		"strings.TrimSpace": strings.TrimSpace,
+		"strings.Repeat":    strings.Repeat,
+		"strings.Contains":  strings.Contains,
+		"strings.Index":     strings.Index,
+		"unicode.IsDigit":   unicode.IsDigit,
+		"unicode.IsLetter":  unicode.IsLetter,
+		"unicode.IsUpper":   unicode.IsUpper,
+		"unicode.ToUpper":   unicode.ToUpper,
+	}
+}
+
+// hasPolicy tells that the policy of the function is set, it overrides the model of the function
+func (e *Explorer) hasPolicy(callee *types.Func) bool {
+	_, ok := e.Policies[callee.Origin().FullName()]
+	return ok
+}
+
+// callUnknown handles the call of the function which can't be explored by its policy. The abstracted and
+// the concretized calls store their results into the temporaries of the call node, the state goes on
+func (e *Explorer) callUnknown(st *State, node *callNode, callee *types.Func, recv smt.SymValue, args []smt.SymValue, cause error) error {
+	name := callee.Origin().FullName()
+	policy, ok := e.Policies[name]
+	if !ok {
+		policy = e.DefaultPolicy
+	}
+	if policy == Stop {
+		return e.unsupported(node.call, "call of %s: %s", name, cause)
+	}
+	// the summary would keep the arguments fixed by the path it's computed on
+	if e.summary != nil {
+		return e.unsupported(node.call, "call of %s is %s, it isn't summarized", name, policy)
+	}
+
+	signature := callee.Type().(*types.Signature)
+	params, args := parameters(signature, recv, args)
+
+	var results []smt.SymValue
+	var err error
+	if policy == Abstract {
+		results, err = e.abstract(name, params, signature.Results(), args)
+	} else {
+		results, err = e.concretize(st, name, params, signature.Results(), args)
+	}
+	if errors.Is(err, errInfeasible) || gaveUp(err) {
+		return err
+	}
+	if err != nil {
+		return e.unsupported(node.call, "call of %s can't be %s: %s", name, policy, err)
+	}
+
+	// the abstracted results are uninterpreted as the approximated ones, the test of the path is confirmed on them
+	if policy == Abstract {
+		st.applications = append(st.applications, application{
+			function: name, how: Abstract.String(), params: params, results: signature.Results(), args: args, values: results})
+	}
+	if !slices.Contains(st.unknownCalls, UnknownCall{Function: name, Policy: policy}) {
+		st.unknownCalls = append(st.unknownCalls, UnknownCall{Function: name, Policy: policy})
+	}
+	for i, result := range node.results {
+		st.define(result, results[i])
+	}
+
+	return nil
+}
+
+// parameters are the parameters of the signature with the arguments of the call, the receiver comes first
+func parameters(signature *types.Signature, recv smt.SymValue, args []smt.SymValue) (*types.Tuple, []smt.SymValue) {
+	params := make([]*types.Var, 0, signature.Params().Len()+1)
+	if recv != nil {
+		params, args = append(params, signature.Recv()), append([]smt.SymValue{recv}, args...)
+	}
+	for i := 0; i < signature.Params().Len(); i++ {
+		params = append(params, signature.Params().At(i))
+	}
+
+	return types.NewTuple(params...), args
+}
+
+// application is the call of the approximated or the abstracted function. The test of the path is
+// confirmed when the implementation of the function returns the values on the arguments
+type application struct {
+	function string
+	// how is approximated or abstracted
+	how     string
+	params  *types.Tuple
+	results *types.Tuple
+	args    []smt.SymValue
+	values  []smt.SymValue
+}
+
+// approximate records the call of the model with the uninterpreted results on the path
+func (e *Explorer) approximate(st *State, node *callNode, callee *types.Func, recv smt.SymValue, args []smt.SymValue, results []smt.SymValue) error {
+	name := callee.Origin().FullName()
+	// the summary would need the applications of its paths at every call
+	if e.summary != nil {
+		return e.unsupported(node.call, "model of %s is approximated, it isn't summarized", name)
+	}
+
+	signature := callee.Type().(*types.Signature)
+	params, args := parameters(signature, recv, args)
+	st.applications = append(st.applications, application{
+		function: name, how: "approximated", params: params, results: signature.Results(), args: args, values: results})
+	if !slices.Contains(st.approximatedCalls, name) {
+		st.approximatedCalls = append(st.approximatedCalls, name)
+	}
+
+	return nil
+}
+
+// appliedFunctions are the functions of the applications with how they're applied, every function once
+func appliedFunctions(applications []application) []string {
+	var result []string
+	for _, app := range applications {
+		function := fmt.Sprintf("%s (%s)", app.function, app.how)
+		if !slices.Contains(result, function) {
+			result = append(result, function)
+		}
+	}
+
+	return result
+}
+
+// confirmAttempts is how many models of the path are tried to confirm its applications
+const confirmAttempts = 3
+
+// confirm looks for a model of the path, whose condition is asserted, in which the implementations return
+// the values of the applications. The arguments the implementations disagree on are excluded from the
+// following attempts, the simple inputs are tried then. The model is nil when the path isn't confirmed:
+// the implementations disagree, can't be run or the solver gives up
+func (e *Explorer) confirm(st *State) (*z3.Model, error) {
+	solver := e.sCtx.Solver
+	for attempt := 0; attempt < confirmAttempts; attempt++ {
+		model, disagreed, err := e.pinApplications(st)
+		if err != nil || model != nil {
+			return model, err
+		}
+		if disagreed == nil {
+			return nil, nil
+		}
+		solver.Assert(disagreed.Not())
+	}
+
+	// the solver tends to choose the extreme floats, the simple inputs are closer to what the axioms describe
+	for _, seed := range e.seeds() {
+		solver.Push()
+		solver.Assert(seed)
+		model, _, err := e.pinApplications(st)
+		solver.Pop()
+		if err != nil || model != nil {
+			return model, err
+		}
+	}
+
+	return nil, nil
+}
+
+// pinApplications fixes the arguments of the applications one by one to their values in the model and
+// their results to the results of the implementations. It returns the model of the pinned path or
+// the pins of the arguments the implementations disagree on
+func (e *Explorer) pinApplications(st *State) (*z3.Model, *z3.Bool, error) {
+	solver := e.sCtx.Solver
+	solver.Push()
+	defer solver.Pop()
+
+	pinned := e.sCtx.Ctx.FromBool(true)
+	for _, app := range st.applications {
+		model, err := e.pinnedModel()
+		if errors.Is(err, errInfeasible) {
+			return nil, &pinned, nil
+		}
+		if model == nil || err != nil {
+			return nil, nil, err
+		}
+
+		implementation, ok := e.Implementations[app.function]
+		if !ok {
+			return nil, nil, nil
+		}
+		results, pins, err := e.runImplementation(st, model, implementation, app.params, app.results, app.args)
+		if err != nil {
+			return nil, nil, nil
+		}
+		for _, pin := range pins {
+			pinned = pinned.And(pin)
+		}
+		for i, result := range results {
+			pin, err := identical(app.values[i], result)
+			if err != nil {
+				return nil, nil, nil
+			}
+			pins = append(pins, pin)
+		}
+		for _, pin := range pins {
+			solver.Assert(pin)
+		}
+	}
+
+	model, err := e.pinnedModel()
+	if errors.Is(err, errInfeasible) {
+		return nil, &pinned, nil
+	}
+	return model, nil, err
+}
+
+// pinnedModel is the model of the assertions. The model is nil without an error when the solver gives up
+func (e *Explorer) pinnedModel() (*z3.Model, error) {
+	sat, err := e.check()
+	if gaveUp(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !sat {
+		return nil, errInfeasible
+	}
+
+	return e.sCtx.Solver.Model(), nil
+}
+
+// abstract returns the applications of the uninterpreted functions of the callee to the arguments
+func (e *Explorer) abstract(name string, params *types.Tuple, results *types.Tuple, args []smt.SymValue) ([]smt.SymValue, error) {
+	paramTypes, err := basicTypes(params)
+	if err != nil {
+		return nil, err
+	}
+	resultTypes, err := basicTypes(results)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := e.sCtx.NewAbstractFunction(name, paramTypes, resultTypes)
+	if err != nil {
+		return nil, err
+	}
+	return f.Call(args)
+}
+
+// concretize runs the implementation of the callee on the values of the arguments in the model of the path.
+// The path assumes the arguments have those values
+func (e *Explorer) concretize(st *State, name string, params *types.Tuple, results *types.Tuple, args []smt.SymValue) ([]smt.SymValue, error) {
+	implementation, ok := e.Implementations[name]
+	if !ok {
+		return nil, fmt.Errorf("the explorer has no implementation to run")
+	}
+
+	model, err := e.pathModel(st)
+	if err != nil {
+		return nil, err
+	}
+
+	values, pins, err := e.runImplementation(st, model, implementation, params, results, args)
+	if err != nil {
+		return nil, err
+	}
+	for _, pin := range pins {
+		st.assume(pin)
+	}
+	return values, nil
+}
+
+// runImplementation runs the implementation on the values of the arguments in the model. It returns
+// the constants of the results and the pins of the arguments to their values. The error it returns becomes
+// the error made by the library with the same message, its type is lost
+func (e *Explorer) runImplementation(st *State, model *z3.Model, implementation any, params *types.Tuple, results *types.Tuple, args []smt.SymValue) ([]smt.SymValue, []z3.Bool, error) {
+	f := reflect.ValueOf(implementation)
+	if f.Kind() != reflect.Func || f.Type().NumIn() != params.Len() || f.Type().NumOut() != results.Len() {
+		return nil, nil, fmt.Errorf("implementation %s doesn't match the signature", f.Type())
+	}
+
+	in := make([]reflect.Value, 0, len(args))
+	pins := make([]z3.Bool, 0, len(args))
+	for i, arg := range args {
+		basic, ok := params.At(i).Type().Underlying().(*types.Basic)
+		if !ok {
+			return nil, nil, fmt.Errorf("arguments of type %s can't be concretized", params.At(i).Type())
+		}
+		value, err := decodeBasic(model, arg, basic)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !value.CanConvert(f.Type().In(i)) {
+			return nil, nil, fmt.Errorf("argument %d of type %s can't be passed to the implementation", i, params.At(i).Type())
+		}
+
+		concrete, err := e.concreteValue(value, basic)
+		if err != nil {
+			return nil, nil, err
+		}
+		pin, err := identical(arg, concrete)
+		if err != nil {
+			return nil, nil, err
+		}
+		in = append(in, value.Convert(f.Type().In(i)))
+		pins = append(pins, pin)
+	}
+
+	out, err := call(f, in)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values := make([]smt.SymValue, 0, len(out))
+	for i, result := range out {
+		t := results.At(i).Type()
+		if isError(t) {
+			var value smt.SymValue
+			var err error
+			if result.IsNil() {
+				value, err = e.zeroValue(t)
+			} else {
+				made := models.Error{Text: e.sCtx.NewStringConst(result.Interface().(error).Error())}
+				value, err = e.makeError(st, nil, made, nil)
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			values = append(values, value)
+			continue
+		}
+
+		basic, ok := t.Underlying().(*types.Basic)
+		if !ok {
+			return nil, nil, fmt.Errorf("results of type %s can't be concretized", t)
+		}
+		value, err := e.concreteValue(result, basic)
+		if err != nil {
+			return nil, nil, err
+		}
+		values = append(values, value)
+	}
+
+	return values, pins, nil
+}
+
+// pathModel is a model of the path condition of the state
+func (e *Explorer) pathModel(st *State) (*z3.Model, error) {
+	solver := e.sCtx.Solver
+	solver.Push()
+	defer solver.Pop()
+
+	solver.Assert(st.condition(e.sCtx.Ctx))
+	sat, err := e.check()
+	if err != nil {
+		return nil, err
+	}
+	if !sat {
+		return nil, errInfeasible
+	}
+
+	return solver.Model(), nil
+}
+
+// call runs the implementation, its panic is the error of the call
+func call(f reflect.Value, in []reflect.Value) (out []reflect.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("it panicked: %v", r)
+		}
+	}()
+
+	return f.Call(in), nil
+}
+
+// concreteValue is the constant of the value of the basic type
+func (e *Explorer) concreteValue(value reflect.Value, basic *types.Basic) (smt.SymValue, error) {
+	ctx := e.sCtx.Ctx
+	switch value.Kind() {
+	case reflect.Bool:
+		return smt.BoolValue(ctx.FromBool(value.Bool())), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return smt.IntValue(e.intConst(value.Int())), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if value.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("integer %d is too big", value.Uint())
+		}
+		return smt.IntValue(e.intConst(int64(value.Uint()))), nil
+	case reflect.Float32, reflect.Float64:
+		return smt.FloatValue(ctx.FromFloat64(value.Float(), e.floatSort(basic))), nil
+	case reflect.Complex64, reflect.Complex128:
+		sort := ctx.FloatSort(11, 53)
+		c := value.Complex()
+		return smt.ComplexValue(ctx.FromFloat64(real(c), sort), ctx.FromFloat64(imag(c), sort)), nil
+	case reflect.String:
+		return e.sCtx.NewStringConst(value.String()), nil
+	default:
+		return nil, fmt.Errorf("values of kind %s can't be concretized", value.Kind())
+	}
+}
+
+// identical holds when the value is the constant. Floats are compared by their bits, so NaN is fixed too
+func identical(value smt.SymValue, constant smt.SymValue) (z3.Bool, error) {
+	switch value := value.(type) {
+	case smt.SymBool:
+		return value.Bool().Eq(constant.(smt.SymBool).Bool()), nil
+	case smt.SymInt:
+		return value.Int().Eq(constant.(smt.SymInt).Int()), nil
+	case smt.SymFloat:
+		return value.Float().Eq(constant.(smt.SymFloat).Float()), nil
+	case smt.SymComplex:
+		c := constant.(smt.SymComplex)
+		return value.Real().Eq(c.Real()).And(value.Imag().Eq(c.Imag())), nil
+	case smt.SymString:
+		return value.Eq(constant.(smt.SymString)), nil
+	default:
+		return z3.Bool{}, fmt.Errorf("%T can't be concretized", value)
+	}
+}
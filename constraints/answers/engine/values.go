@@ -0,0 +1,593 @@
+package engine
+
+import (
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+	smttypes "github.com/vldF/symbolic_execution_course/constraints/smt/types"
+	"github.com/vldF/symbolic_execution_course/constraints/testgen"
+	"go/types"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// untypedNil is the value of the predeclared nil before it's converted to a type
+type untypedNil struct {
+	smt.SymValueBase
+}
+
+// inputRef is a pointer of an input: it's nil or it points to the fresh cell allocated for it
+type inputRef struct {
+	smt.SymValueBase
+	isNil z3.Bool
+	cell  int
+}
+
+func (ref inputRef) Address() z3.Int {
+	ctx := ref.isNil.Context()
+	return ref.isNil.IfThenElse(ctx.FromInt(0, ctx.IntSort()), ctx.FromInt(int64(ref.cell), ctx.IntSort())).(z3.Int)
+}
+
+func (ref inputRef) IsNil() z3.Bool {
+	return ref.isNil
+}
+
+// contentsRef is a slice or a map input: its elements are kept in the heap cell, so the copies of the value
+// and the callees it's passed to see the writes through each other. Arrays are values, they aren't referenced
+type contentsRef struct {
+	smt.SymValueBase
+	cell int
+}
+
+// newArgument creates a symbolic input of the type t. Pointers, the pointer fields of structs among them,
+// are nil or point to fresh cells, the pointers deeper than PointerDepth are nil.
+// Interfaces get dynamic values for every type implementing them
+func (e *Explorer) newArgument(st *State, name string, t types.Type) (smt.SymValue, error) {
+	return e.newInput(st, name, t, e.PointerDepth)
+}
+
+// newInput creates the input with the pointers followed from it to the given depth
+func (e *Explorer) newInput(st *State, name string, t types.Type, depth int) (smt.SymValue, error) {
+	switch underlying := t.Underlying().(type) {
+	case *types.Basic:
+		return e.sCtx.NewBasicArgument(name, underlying)
+	case *types.Struct:
+		desc, err := e.describe(t)
+		if err != nil {
+			return nil, err
+		}
+		return e.pointerFields(st, name, e.sCtx.NewStructArgument(name, desc), underlying, depth)
+	case *types.Pointer:
+		if depth == 0 {
+			return e.pointerTo(0), nil
+		}
+		elem, err := e.newInput(st, name, underlying.Elem(), depth-1)
+		if err != nil {
+			return nil, err
+		}
+		return inputRef{isNil: e.sCtx.Ctx.FreshConst(name+".nil", e.sCtx.Ctx.BoolSort()).(z3.Bool), cell: st.alloc(elem)}, nil
+	case *types.Slice:
+		var slice smt.SymSlice
+		switch elem := underlying.Elem().Underlying().(type) {
+		case *types.Basic:
+			var err error
+			if slice, err = e.sCtx.NewSliceArgument(name, elem); err != nil {
+				return nil, err
+			}
+		case *types.Struct:
+			desc, err := e.describe(underlying.Elem())
+			if err != nil {
+				return nil, err
+			}
+			slice = e.sCtx.NewStructSliceArgument(name, desc)
+		default:
+			return nil, fmt.Errorf("arguments of type %s aren't supported", t)
+		}
+		return contentsRef{cell: st.alloc(slice)}, nil
+	case *types.Array:
+		elem, ok := underlying.Elem().Underlying().(*types.Basic)
+		if !ok {
+			return nil, fmt.Errorf("arguments of type %s aren't supported", t)
+		}
+		return e.sCtx.NewArrayArgument(name, elem, int(underlying.Len()))
+	case *types.Map:
+		mapType, err := reflectMapType(underlying)
+		if err != nil {
+			return nil, err
+		}
+		m, err := e.sCtx.NewMapArgument(name, mapType)
+		if err != nil {
+			return nil, err
+		}
+		return contentsRef{cell: st.alloc(m)}, nil
+	case *types.Interface:
+		static, err := interfaceName(t)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := e.ts.NewInterfaceArgument(name, static)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, typeName := range e.ts.Hierarchy().Implementations(static) {
+			if typeName == smttypes.LibraryError {
+				value, err := e.newErrorInput(st, fmt.Sprintf("%s.(%s)", name, typeName))
+				if err != nil {
+					return nil, err
+				}
+				result = result.WithDynamicValue(typeName, value)
+				continue
+			}
+			dynamicType, ok := e.typesByName[typeName]
+			if !ok {
+				continue
+			}
+
+			value, err := e.newInput(st, fmt.Sprintf("%s.(%s)", name, typeName), dynamicType, depth)
+			if err != nil {
+				return nil, err
+			}
+			result = result.WithDynamicValue(typeName, value)
+		}
+
+		return result, nil
+	case *types.Signature:
+		return e.newFunctionArgument(st, name, underlying)
+	default:
+		return nil, fmt.Errorf("arguments of type %s aren't supported", t)
+	}
+}
+
+// holdsStrings tells that the inputs of the type may hold strings, they're bounded by MaxStringLength
+func (e *Explorer) holdsStrings(t types.Type, seen map[types.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	switch underlying := t.Underlying().(type) {
+	case *types.Basic:
+		return underlying.Info()&types.IsString != 0
+	case *types.Struct:
+		for i := 0; i < underlying.NumFields(); i++ {
+			if e.holdsStrings(underlying.Field(i).Type(), seen) {
+				return true
+			}
+		}
+		return false
+	case *types.Pointer:
+		return e.holdsStrings(underlying.Elem(), seen)
+	case *types.Slice:
+		return e.holdsStrings(underlying.Elem(), seen)
+	case *types.Array:
+		return e.holdsStrings(underlying.Elem(), seen)
+	case *types.Map:
+		return e.holdsStrings(underlying.Key(), seen) || e.holdsStrings(underlying.Elem(), seen)
+	case *types.Interface:
+		static, err := interfaceName(t)
+		if err != nil {
+			return false
+		}
+		for _, typeName := range e.ts.Hierarchy().Implementations(static) {
+			if typeName == smttypes.LibraryError {
+				return true
+			}
+			if dynamicType, ok := e.typesByName[typeName]; ok && e.holdsStrings(dynamicType, seen) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// pointerFields replaces the pointer fields of the struct input, nested structs included, by the input pointers
+func (e *Explorer) pointerFields(st *State, name string, structure smt.SymStruct, t *types.Struct, depth int) (smt.SymStruct, error) {
+	for i := 0; i < t.NumFields(); i++ {
+		field := t.Field(i)
+		var value smt.SymValue
+		var err error
+		switch fieldType := field.Type().Underlying().(type) {
+		case *types.Pointer:
+			value, err = e.newInput(st, name+"."+field.Name(), field.Type(), depth)
+		case *types.Struct:
+			var nested smt.SymValue
+			if nested, err = structure.Field(field.Name()); err == nil {
+				value, err = e.pointerFields(st, name+"."+field.Name(), nested.(smt.SymStruct), fieldType, depth)
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return structure, err
+		}
+
+		if structure, err = structure.WithField(field.Name(), value); err != nil {
+			return structure, err
+		}
+	}
+
+	return structure, nil
+}
+
+func (e *Explorer) zeroValue(t types.Type) (smt.SymValue, error) {
+	switch underlying := t.Underlying().(type) {
+	case *types.Basic:
+		return e.sCtx.ZeroValue(underlying)
+	case *types.Struct:
+		desc, err := e.describe(t)
+		if err != nil {
+			return nil, err
+		}
+		return e.sCtx.NewZeroStruct(desc), nil
+	case *types.Pointer:
+		return e.pointerTo(0), nil
+	case *types.Slice:
+		switch elem := underlying.Elem().Underlying().(type) {
+		case *types.Basic:
+			return e.sCtx.NewNilSlice(elem)
+		case *types.Struct:
+			desc, err := e.describe(underlying.Elem())
+			if err != nil {
+				return nil, err
+			}
+			return e.sCtx.NewNilStructSlice(desc), nil
+		default:
+			return nil, fmt.Errorf("values of type %s aren't supported", t)
+		}
+	case *types.Array:
+		elem, ok := underlying.Elem().Underlying().(*types.Basic)
+		if !ok {
+			return nil, fmt.Errorf("values of type %s aren't supported", t)
+		}
+		return e.sCtx.NewZeroArray(elem, int(underlying.Len()))
+	case *types.Map:
+		mapType, err := reflectMapType(underlying)
+		if err != nil {
+			return nil, err
+		}
+		return e.sCtx.NewNilMap(mapType)
+	case *types.Interface:
+		static, err := interfaceName(t)
+		if err != nil {
+			return nil, err
+		}
+		return e.ts.NewNilInterface(static), nil
+	case *types.Signature:
+		return closure{}, nil
+	default:
+		return nil, fmt.Errorf("values of type %s aren't supported", t)
+	}
+}
+
+// reflectMapType is the Go type of the maps the model decodes, the keys and the values are of basic types
+func reflectMapType(t *types.Map) (reflect.Type, error) {
+	key, ok := t.Key().Underlying().(*types.Basic)
+	if !ok {
+		return nil, fmt.Errorf("maps with keys of type %s aren't supported", t.Key())
+	}
+	elem, ok := t.Elem().Underlying().(*types.Basic)
+	if !ok {
+		return nil, fmt.Errorf("maps with values of type %s aren't supported", t.Elem())
+	}
+
+	keyType, err := smt.BasicType(key)
+	if err != nil {
+		return nil, err
+	}
+	elemType, err := smt.BasicType(elem)
+	if err != nil {
+		return nil, err
+	}
+
+	return reflect.MapOf(keyType, elemType), nil
+}
+
+func (e *Explorer) describe(t types.Type) (*smt.StructDescriptor, error) {
+	if desc, ok := e.structs[t]; ok {
+		return desc, nil
+	}
+
+	desc, err := e.sCtx.DescribeStructType(smttypes.GoTypeName(t), t.Underlying().(*types.Struct))
+	if err != nil {
+		return nil, err
+	}
+	e.structs[t] = desc
+
+	return desc, nil
+}
+
+func (e *Explorer) pointerTo(address int) smt.SymRef {
+	return smt.RefValue(e.intConst(int64(address)))
+}
+
+func (e *Explorer) intConst(value int64) z3.Int {
+	return e.sCtx.Ctx.FromInt(value, e.sCtx.Ctx.IntSort()).(z3.Int)
+}
+
+// interfaceName is the name of the interface in the type hierarchy
+func interfaceName(t types.Type) (string, error) {
+	if _, ok := t.(*types.Named); ok {
+		return smttypes.GoTypeName(t), nil
+	}
+
+	if iface, ok := t.Underlying().(*types.Interface); ok && iface.Empty() {
+		return "any", nil
+	}
+
+	return "", fmt.Errorf("interface %s has no name", t)
+}
+
+// convert applies the implicit conversion of the value to the type of the variable it's assigned to
+func (e *Explorer) convert(value smt.SymValue, from types.Type, to types.Type) (smt.SymValue, error) {
+	if _, ok := value.(untypedNil); ok {
+		return e.zeroValue(to)
+	}
+
+	if !types.IsInterface(to) {
+		return value, nil
+	}
+
+	static, err := interfaceName(to)
+	if err != nil {
+		return nil, err
+	}
+
+	if iface, ok := value.(smttypes.SymInterface); ok {
+		return iface.WithStatic(static), nil
+	}
+
+	return e.ts.NewInterfaceValue(static, smttypes.GoTypeName(types.Default(from)), value)
+}
+
+// dynamicValue returns the dynamic value of the interface for the concrete type t
+func (e *Explorer) dynamicValue(iface smttypes.SymInterface, t types.Type) (smt.SymValue, error) {
+	if value, ok := iface.DynamicValue(smttypes.GoTypeName(t)); ok {
+		return value, nil
+	}
+
+	// the interface can't have such a dynamic type, any value fits
+	return e.zeroValue(t)
+}
+
+// decode builds the Go value of the symbolic value in the model. Values of types declared
+// in the package under test are rendered as testgen.Source
+func (e *Explorer) decode(model *z3.Model, st *State, value smt.SymValue, t types.Type) (reflect.Value, error) {
+	switch underlying := t.Underlying().(type) {
+	case *types.Basic:
+		result, err := decodeBasic(model, value, underlying)
+		if err != nil || t == underlying {
+			return result, err
+		}
+
+		return reflect.ValueOf(testgen.Source(fmt.Sprintf("%s(%s)", smttypes.GoTypeName(t), testgen.Literal(result)))), nil
+	case *types.Struct:
+		structure, ok := value.(smt.SymStruct)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("%T isn't a struct", value)
+		}
+
+		fields := make([]string, 0, underlying.NumFields())
+		for i := 0; i < underlying.NumFields(); i++ {
+			field := underlying.Field(i)
+			fieldValue, err := structure.Field(field.Name())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+
+			decoded, err := e.decode(model, st, fieldValue, field.Type())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			fields = append(fields, fmt.Sprintf("%s: %s", field.Name(), testgen.Literal(decoded)))
+		}
+
+		return reflect.ValueOf(testgen.Source(fmt.Sprintf("%s{%s}", smttypes.GoTypeName(t), strings.Join(fields, ", ")))), nil
+	case *types.Pointer:
+		address, err := e.address(model, value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if address == 0 {
+			return reflect.ValueOf(testgen.Source(fmt.Sprintf("(%s)(nil)", smttypes.GoTypeName(t)))), nil
+		}
+		if address < 0 || address >= len(st.heap) {
+			return reflect.Value{}, fmt.Errorf("pointer to %d is out of the heap", address)
+		}
+		if slices.Contains(e.decoding, address) {
+			return reflect.Value{}, fmt.Errorf("cyclic values of type %s can't be rendered", t)
+		}
+
+		e.decoding = append(e.decoding, address)
+		elem, err := e.decode(model, st, st.heap[address], underlying.Elem())
+		e.decoding = e.decoding[:len(e.decoding)-1]
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if _, ok := underlying.Elem().Underlying().(*types.Struct); ok {
+			return reflect.ValueOf(testgen.PointerSource("&" + testgen.Literal(elem))), nil
+		}
+
+		return reflect.ValueOf(testgen.PointerSource(fmt.Sprintf("func() %s { v := %s; return &v }()", smttypes.GoTypeName(t), testgen.Literal(elem)))), nil
+	case *types.Slice:
+		slice, ok := st.contents(value).(smt.SymSlice)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("%T isn't a slice", value)
+		}
+		if slice.IsNil() {
+			return reflect.ValueOf(testgen.PointerSource(fmt.Sprintf("%s(nil)", smttypes.GoTypeName(t)))), nil
+		}
+
+		length, err := slice.DecodeLen(model)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		elements := make([]string, 0, length)
+		for i := 0; i < length; i++ {
+			element, _ := slice.At(e.intConst(int64(i)))
+			decoded, err := e.decode(model, st, element, underlying.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			elements = append(elements, testgen.Literal(decoded))
+		}
+
+		// slices aren't comparable, the tests compare them deeply
+		return reflect.ValueOf(testgen.PointerSource(fmt.Sprintf("%s{%s}", smttypes.GoTypeName(t), strings.Join(elements, ", ")))), nil
+	case *types.Array:
+		array, ok := value.(smt.SymSlice)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("%T isn't an array", value)
+		}
+
+		elements := make([]string, 0, underlying.Len())
+		for i := 0; i < int(underlying.Len()); i++ {
+			element, _ := array.At(e.intConst(int64(i)))
+			decoded, err := e.decode(model, st, element, underlying.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			elements = append(elements, testgen.Literal(decoded))
+		}
+
+		return reflect.ValueOf(testgen.Source(fmt.Sprintf("%s{%s}", smttypes.GoTypeName(t), strings.Join(elements, ", ")))), nil
+	case *types.Map:
+		m, ok := st.contents(value).(smt.SymMap)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("%T isn't a map", value)
+		}
+
+		decoded, err := m.Decode(model)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if decoded.IsNil() {
+			return reflect.ValueOf(testgen.PointerSource(fmt.Sprintf("%s(nil)", smttypes.GoTypeName(t)))), nil
+		}
+
+		// the untyped constants of the entries take the types of the keys and the values
+		entries := make([]string, 0, decoded.Len())
+		for _, key := range decoded.MapKeys() {
+			entries = append(entries, fmt.Sprintf("%s: %s", testgen.Literal(key), testgen.Literal(decoded.MapIndex(key))))
+		}
+		slices.Sort(entries)
+
+		return reflect.ValueOf(testgen.PointerSource(fmt.Sprintf("%s{%s}", smttypes.GoTypeName(t), strings.Join(entries, ", ")))), nil
+	case *types.Interface:
+		iface, ok := value.(smttypes.SymInterface)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("%T isn't an interface", value)
+		}
+
+		typeName, err := e.ts.DecodeDynamicType(model, iface)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if typeName == "nil" {
+			return reflect.ValueOf(testgen.Source("nil")), nil
+		}
+
+		dynamicValue, ok := iface.DynamicValue(typeName)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("interface has no value of type %s", typeName)
+		}
+		if typeName == smttypes.LibraryError {
+			message, err := e.decodeErrorMessage(model, st, dynamicValue)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			return reflect.ValueOf(testgen.Source(fmt.Sprintf("errors.New(%q)", message))), nil
+		}
+
+		decoded, err := e.decode(model, st, dynamicValue, e.typesByName[typeName])
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		// the literal of the value in the interface keeps the dynamic type, see testgen.Literal
+		boxed := reflect.New(reflect.TypeOf((*any)(nil)).Elem()).Elem()
+		boxed.Set(decoded)
+		return boxed, nil
+	case *types.Signature:
+		return e.decodeFunction(model, value, t)
+	default:
+		return reflect.Value{}, fmt.Errorf("values of type %s can't be decoded", t)
+	}
+}
+
+var errorType = types.Universe.Lookup("error").Type()
+
+func isError(t types.Type) bool {
+	return types.Identical(t, errorType)
+}
+
+// decodeError builds the expected error: it's nil, has the dynamic type in the model or is made by the library
+// with the message in the model
+func (e *Explorer) decodeError(model *z3.Model, st *State, value smt.SymValue) (reflect.Value, error) {
+	iface, ok := value.(smttypes.SymInterface)
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("%T isn't an interface", value)
+	}
+
+	typeName, err := e.ts.DecodeDynamicType(model, iface)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if typeName == "nil" {
+		return reflect.ValueOf(testgen.Error{}), nil
+	}
+	if typeName == smttypes.LibraryError {
+		dynamicValue, _ := iface.DynamicValue(typeName)
+		message, err := e.decodeErrorMessage(model, st, dynamicValue)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(testgen.Error{Type: typeName, Made: true, Message: message}), nil
+	}
+
+	return reflect.ValueOf(testgen.Error{Type: typeName}), nil
+}
+
+func decodeBasic(model *z3.Model, value smt.SymValue, basic *types.Basic) (reflect.Value, error) {
+	t, err := smt.BasicType(basic)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	switch value := value.(type) {
+	case smt.SymBool:
+		return smt.DecodeValue(model, value.Bool(), t)
+	case smt.SymInt:
+		return smt.DecodeValue(model, value.Int(), t)
+	case smt.SymFloat:
+		return smt.DecodeValue(model, value.Float(), t)
+	case smt.SymString:
+		decoded, err := value.Decode(model)
+		return reflect.ValueOf(decoded), err
+	case smt.SymComplex:
+		decoded, err := value.Decode(model)
+		return reflect.ValueOf(decoded), err
+	default:
+		return reflect.Value{}, fmt.Errorf("%T isn't a value of type %s", value, basic)
+	}
+}
+
+// address evaluates the address of the pointer in the model
+func (e *Explorer) address(model *z3.Model, value smt.SymValue) (int, error) {
+	ref, ok := value.(smt.SymRef)
+	if !ok {
+		return 0, fmt.Errorf("%T isn't a pointer", value)
+	}
+
+	address, isLiteral, ok := model.Eval(ref.Address(), true).(z3.Int).AsInt64()
+	if !isLiteral || !ok {
+		return 0, fmt.Errorf("can't evaluate %s", ref.Address())
+	}
+
+	return int(address), nil
+}
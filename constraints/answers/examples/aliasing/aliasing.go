@@ -0,0 +1,41 @@
+package aliasing
+
+// Copied writes through the copy of the slice, the slice sees the write
+func Copied(s []int) int {
+	t := s
+	t[0] = 5
+	if s[0] == 5 {
+		return 1
+	}
+	return 0
+}
+
+func reset(s []int) {
+	s[0] = 0
+}
+
+// Reset passes the slice to the callee writing its first element
+func Reset(s []int) int {
+	reset(s)
+	if s[0] != 0 {
+		return -1
+	}
+	return len(s)
+}
+
+// Counted increments the entry through the copy of the map
+func Counted(m map[string]int) int {
+	n := m
+	n["a"] = 1
+	return m["a"]
+}
+
+// Detached writes to the copy of the array, arrays are values and the original stays the same
+func Detached(a [2]int) int {
+	b := a
+	b[0] = 5
+	if a[0] == 5 {
+		return 1
+	}
+	return 0
+}
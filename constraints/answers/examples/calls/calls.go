@@ -0,0 +1,24 @@
+package calls
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// Distance calls abs on both paths of its branch
+func Distance(a int, b int) int {
+	if a > b {
+		return abs(a - b)
+	}
+	return abs(b - a)
+}
+
+// Factorial recurses as deep as n is, deep recursion is cut by the bound
+func Factorial(n int) int {
+	if n <= 1 {
+		return 1
+	}
+	return n * Factorial(n-1)
+}
@@ -0,0 +1,101 @@
+package closures
+
+// Counter increments the captured variable by the closure, the variable is shared by reference
+func Counter(n int) int {
+	count := 0
+	increment := func() {
+		count++
+	}
+	for i := 0; i < n && i < 3; i++ {
+		increment()
+	}
+	return count
+}
+
+func adder(n int) func(int) int {
+	return func(x int) int {
+		return x + n
+	}
+}
+
+// AddTwice adds n twice by the closure returned by adder
+func AddTwice(n int, x int) int {
+	add := adder(n)
+	return add(add(x))
+}
+
+func double(x int) int {
+	return 2 * x
+}
+
+func square(x int) int {
+	return x * x
+}
+
+// Pick chooses a declared function as a value
+func Pick(squared bool, x int) int {
+	f := double
+	if squared {
+		f = square
+	}
+	return f(x)
+}
+
+// Apply calls the function argument, it's an uninterpreted function unless candidates are given
+func Apply(f func(int) int, x int) string {
+	y := f(x)
+	if y > x {
+		return "grows"
+	}
+	if f(y) == x {
+		return "involution"
+	}
+	return "other"
+}
+
+type Predicate func(int) bool
+
+// Count counts the elements the predicate holds for
+func Count(values []int, p Predicate) int {
+	count := 0
+	for _, value := range values {
+		if p(value) {
+			count++
+		}
+	}
+	return count
+}
+
+// Optional calls the function unless it's nil
+func Optional(f func() int, x int) int {
+	g := func() int { return x }
+	if x > 0 {
+		g = nil
+	}
+	if g == nil {
+		return -1
+	}
+	return g()
+}
+
+// Deferred defers the closure stored in a variable, it sees the value of the result at the return
+func Deferred(x int) (result int) {
+	restore := func() {
+		if result < 0 {
+			result = 0
+		}
+	}
+	defer restore()
+	return x - 10
+}
+
+// Operation returns the operation by its name, unknown names have the nil function
+func Operation(name string) func(int) int {
+	switch name {
+	case "double":
+		return double
+	case "square":
+		return square
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package conditions
+
+// BitwiseOperations is bitwiseOperations from numbers.go, its decisions are explored operand by operand
+func BitwiseOperations(a int, b int) int {
+	if a&1 == 0 && b&1 == 0 {
+		return a | b
+	} else if a&1 == 1 && b&1 == 1 {
+		return a & b
+	}
+	return a ^ b
+}
+
+// SafeRatio divides only when the divisor isn't zero, so it never panics
+func SafeRatio(a int, b int) bool {
+	if b != 0 && a/b > 2 {
+		return true
+	}
+	return false
+}
+
+func Check(a int, b int, c int) int {
+	positive := a > 0 && (b > 0 || c > 0)
+	if positive {
+		return 1
+	}
+	if !(a < 0 || b < 0) {
+		return 0
+	}
+	return -1
+}
+
+// Overflow finds the int8 which overflows when it's incremented
+func Overflow(x int8) int {
+	if x+1 < x {
+		return 1
+	}
+	return 0
+}
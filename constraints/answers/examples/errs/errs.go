@@ -0,0 +1,122 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// RangeError is the error returned by value
+type RangeError struct {
+	Value int
+}
+
+func (e RangeError) Error() string {
+	return "value out of range"
+}
+
+// LimitError is the error returned by pointer
+type LimitError struct {
+	Limit int
+}
+
+func (e *LimitError) Error() string {
+	return "value over the limit"
+}
+
+// Parse returns a value and an error, one of them is meaningful on every path
+func Parse(x int) (int, error) {
+	if x < 0 {
+		return 0, RangeError{Value: x}
+	}
+	if x > 100 {
+		return 0, &LimitError{Limit: 100}
+	}
+	return x * 2, nil
+}
+
+// Double forwards the error of Parse
+func Double(x int) (int, error) {
+	v, err := Parse(x)
+	if err != nil {
+		return 0, err
+	}
+	return v + v, nil
+}
+
+// divmod sets its named results and returns them with the naked return
+func divmod(a int, b int) (q int, r int, err error) {
+	if b == 0 {
+		err = RangeError{Value: b}
+		return
+	}
+	q, r = a/b, a%b
+	return
+}
+
+// Split assigns the results of divmod to declared variables and ignores some of them
+func Split(a int, b int) (int, error) {
+	var q, r int
+	var err error
+	q, r, err = divmod(a, b)
+	if err != nil {
+		return 0, err
+	}
+	_, half, _ := divmod(r, 2)
+	return q + half, nil
+}
+
+// Forward returns all the results of divmod at once
+func Forward(a int, b int) (int, int, error) {
+	return divmod(a, b)
+}
+
+// Kind switches on the dynamic type of the error
+func Kind(x int) string {
+	_, err := Parse(x)
+	switch err.(type) {
+	case nil:
+		return "ok"
+	case RangeError:
+		return "range"
+	case *LimitError:
+		return "limit"
+	}
+	return "other"
+}
+
+// Describe gets the error as an argument, it may be nil, any error type of the package or an error of errors.New
+func Describe(err error) (bool, string) {
+	if err == nil {
+		return true, ""
+	}
+	return false, err.Error()
+}
+
+// errNegative is the sentinel error of Half, callers compare errors with it
+var errNegative = errors.New("negative")
+
+// Half returns the sentinel error or the error made on the call
+func Half(x int) (int, error) {
+	if x < 0 {
+		return 0, errNegative
+	}
+	if x > 10000 {
+		return 0, fmt.Errorf("%d is too large", x)
+	}
+	if x%2 == 1 {
+		return 0, errors.New("odd")
+	}
+	return x / 2, nil
+}
+
+// Sentinel tells the sentinel error of Half from the others
+func Sentinel(x int) string {
+	_, err := Half(x)
+	if err == errNegative {
+		return "negative"
+	}
+	if err != nil {
+		return err.Error()
+	}
+	return "ok"
+}
@@ -0,0 +1,51 @@
+package generics
+
+type Number interface {
+	~int | ~float64
+}
+
+type Celsius float64
+
+type Sized interface {
+	Size() int
+}
+
+type Box struct {
+	Width  int
+	Height int
+}
+
+func (b Box) Size() int {
+	return b.Width * b.Height
+}
+
+type Line struct {
+	Length int
+}
+
+func (l *Line) Size() int {
+	return l.Length
+}
+
+func Max[T Number](a, b T) T {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Clamp keeps x within [low, high], the bounds are expected to be ordered
+func Clamp[T Number](x, low, high T) T {
+	if high < low {
+		panic("empty range")
+	}
+
+	return Max(low, -Max(-x, -high))
+}
+
+func Larger[T Sized](a, b T) T {
+	if a.Size() > b.Size() {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,34 @@
+package invariants
+
+// Sum adds the numbers below n, the sum never gets negative
+func Sum(n int) int {
+	sum := 0
+	//invariant: sum >= 0 && i >= 0
+	for i := 0; i < n; i++ {
+		sum += i
+	}
+	return sum
+}
+
+// Alternate flips the sign back and forth, the invariant needs two iterations to be proved
+func Alternate(n int) int {
+	x := 1
+	y := 1
+	//invariant: x == y || x == -y
+	//invariant: x >= -1 && x <= 1
+	for i := 0; i < n; i++ {
+		x, y = y, -x
+	}
+	return x
+}
+
+// Countdown is wrong about its invariant, it fails for large n
+func Countdown(n int) int {
+	steps := 0
+	//invariant: steps < 3
+	for n > 0 {
+		n--
+		steps++
+	}
+	return steps
+}
@@ -0,0 +1,86 @@
+package libraries
+
+import (
+	"math"
+	"math/bits"
+	"math/cmplx"
+)
+
+// Sign reads the sign and the magnitude by math.Signbit, math.Abs and math.Copysign
+func Sign(x float64) string {
+	if math.Signbit(x) && math.Abs(x) == 0 {
+		return "negative zero"
+	}
+	if math.Copysign(x, 1) > 100 {
+		return "large"
+	}
+	return "small"
+}
+
+// Bucket rounds by math.Floor and math.Ceil and clamps by math.Max and math.Min
+func Bucket(x float64) int {
+	if math.Floor(x) != math.Ceil(x) {
+		return -1
+	}
+	clamped := math.Max(0, math.Min(x, 10))
+	if clamped == 10 {
+		return 10
+	}
+	if clamped == 0 {
+		return 0
+	}
+	return 5
+}
+
+// Parity counts the set bits by math/bits
+func Parity(x uint32) string {
+	if bits.OnesCount32(x) == 0 {
+		return "zero"
+	}
+	if bits.LeadingZeros32(x) < 8 {
+		return "high"
+	}
+	if bits.TrailingZeros32(x) > 4 && bits.OnesCount32(x)%2 == 0 {
+		return "even aligned"
+	}
+	if bits.Len32(x) < 4 {
+		return "small"
+	}
+	return "other"
+}
+
+// Classify checks the complex number by math/cmplx
+func Classify(z complex128) string {
+	if cmplx.IsInf(z) {
+		return "infinite"
+	}
+	if cmplx.IsNaN(z) {
+		return "NaN"
+	}
+	if imag(cmplx.Conj(z)) > 0 {
+		return "lower half"
+	}
+	return "upper half"
+}
+
+// Growth takes the uninterpreted models of math.Exp and math.Log, the paths only know their axioms
+func Growth(x float64) string {
+	if math.Exp(x) < 1 {
+		return "decay"
+	}
+	if math.Log(x) > 0 {
+		return "growth"
+	}
+	return "flat"
+}
+
+// Root takes the exact model of math.Sqrt, the solver gives up on the paths through the comparison
+func Root(x float64) string {
+	if x < 0 {
+		return "negative"
+	}
+	if math.Sqrt(x) == 3 {
+		return "nine"
+	}
+	return "other"
+}
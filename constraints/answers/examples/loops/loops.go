@@ -0,0 +1,79 @@
+package loops
+
+// PushPopIncrementality is pushPopIncrementality from push_pop.go, its loop is unrolled by the engine
+func PushPopIncrementality(j int) int {
+	result := j
+
+	for i := 1; i <= 10; i++ {
+		result += i
+	}
+
+	if result%2 == 0 {
+		result++
+	}
+	return result
+}
+
+// CountPositive loops as many times as the slice has elements
+func CountPositive(values []int) int {
+	count := 0
+	for i := 0; i < len(values); i++ {
+		if values[i] <= 0 {
+			continue
+		}
+		count++
+	}
+	return count
+}
+
+// FirstNegative returns the index of the first negative value or -1
+func FirstNegative(values []int) int {
+	index := -1
+	for i := 0; i < len(values); i++ {
+		if values[i] < 0 {
+			index = i
+			break
+		}
+	}
+	return index
+}
+
+// Steps loops until n reaches zero, large n need more iterations than the bound allows
+func Steps(n int) int {
+	steps := 0
+	for n > 0 {
+		n -= 3
+		steps++
+	}
+	return steps
+}
+
+// Triangle sums the numbers up to n, the sum gets large only after many iterations
+func Triangle(n int) int {
+	sum := 0
+	for i := 1; i <= n; i++ {
+		sum += i
+	}
+
+	if sum > 5000 {
+		return 2
+	}
+	if sum > 0 {
+		return 1
+	}
+	return 0
+}
+
+// EvenPositions counts the positions from the end of the slice going back by two
+func EvenPositions(values []int, weight int) int {
+	count, total := 0, 0
+	for i := len(values) - 1; i >= 0; i -= 2 {
+		count++
+		total = total + weight*2 - i
+	}
+
+	if total > 10 {
+		return count
+	}
+	return -count
+}
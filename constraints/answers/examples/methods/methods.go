@@ -0,0 +1,67 @@
+package methods
+
+type Account struct {
+	Balance int
+	Limit   int
+}
+
+// Withdraw changes the account through the pointer receiver
+func (a *Account) Withdraw(amount int) bool {
+	if amount <= 0 || a.Balance+a.Limit < amount {
+		return false
+	}
+	a.Balance -= amount
+	return true
+}
+
+// Overdrawn reads the account by the value receiver
+func (a Account) Overdrawn() bool {
+	return a.Balance < 0
+}
+
+// Transfer calls the methods on the receiver and on the pointer argument
+func (a *Account) Transfer(to *Account, amount int) int {
+	if !a.Withdraw(amount) {
+		return 0
+	}
+	to.Balance += amount
+	if a.Overdrawn() {
+		return -1
+	}
+	return 1
+}
+
+type Celsius int
+
+// Freezing is a method of a basic type
+func (c Celsius) Freezing() bool {
+	return c <= 0
+}
+
+var (
+	threshold = 100
+	scale     = 2 * threshold
+	calls     int
+	mode      string
+)
+
+// Classify reads package variables, they start with the initializers or with any values
+func Classify(x int) string {
+	calls++
+	if x > scale {
+		return "large"
+	}
+	if x > threshold {
+		return "medium"
+	}
+	if mode == "strict" {
+		return "rejected"
+	}
+	return "small"
+}
+
+// Count reports the calls of Classify counted by the package variable
+func Count() int {
+	Classify(0)
+	return calls
+}
@@ -0,0 +1,132 @@
+package panics
+
+// SafeDivide recovers from the division by zero and reports it by the named result
+func SafeDivide(a int, b int) (q int, ok bool) {
+	defer func() {
+		if recover() != nil {
+			q, ok = 0, false
+		}
+	}()
+	return a / b, true
+}
+
+// Checked panics on its own, the deferred call recovers the value passed to panic
+func Checked(x int) (result string) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = r.(string)
+		}
+	}()
+	if x < 0 {
+		panic("negative")
+	}
+	return "fine"
+}
+
+type Counter struct {
+	Value int
+}
+
+func (c *Counter) Increment() {
+	c.Value++
+}
+
+// Count increments the counter by the deferred calls after the return statement has evaluated its result
+func Count(n int) int {
+	c := &Counter{}
+	defer c.Increment()
+	defer c.Increment()
+	if n > 0 {
+		return c.Value + n
+	}
+	return c.Value
+}
+
+func mustPositive(x int) int {
+	if x <= 0 {
+		panic("not positive")
+	}
+	return x
+}
+
+func release(released *bool) {
+	*released = true
+}
+
+// Escaping runs the deferred call on the panic too, nothing recovers it
+func Escaping(x int) int {
+	released := false
+	defer release(&released)
+	return mustPositive(x)
+}
+
+// Nested recovers in the callee, the caller sees the normal return
+func Nested(xs []int, i int) int {
+	return element(xs, i) + 1
+}
+
+func element(xs []int, i int) (value int) {
+	defer func() {
+		if recover() != nil {
+			value = -1
+		}
+	}()
+	return xs[i]
+}
+
+// Late calls recover outside of the deferred call, so it returns nil and the panic escapes
+func Late(x int) bool {
+	defer func() {}()
+	if recover() != nil {
+		return true
+	}
+	return 10/x > 1
+}
+
+func ratio(a int, b int) (r int) {
+	defer func() {
+		if recover() != nil {
+			r = 0
+		}
+	}()
+	return a / b
+}
+
+// Ratios recovers in both calls of ratio, the summary of ratio has the recovered path
+func Ratios(a int, b int, c int) int {
+	return ratio(a, b) + ratio(b, c)
+}
+
+func guarded(a int, b int) int {
+	released := false
+	defer release(&released)
+	return a / b
+}
+
+// Guarded lets the panic of the callee escape through its deferred call
+func Guarded(a int, b int) int {
+	if a > b {
+		return guarded(a, b)
+	}
+	return guarded(b, a)
+}
+
+type Node struct {
+	Val  int
+	Next *Node
+}
+
+// Second reads the value of the next node, the nil node and the nil next node panic
+func Second(n *Node) int {
+	if n.Next.Val > 3 {
+		return n.Val
+	}
+	return -1
+}
+
+// Tally counts the word in the map and marks the slot, the nil map and the slot out of range panic
+func Tally(counts map[string]int, word string, slots []bool, slot int) int {
+	slots[slot] = true
+	counts[word]++
+	return counts[word]
+}
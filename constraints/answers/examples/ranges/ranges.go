@@ -0,0 +1,130 @@
+package ranges
+
+type Person struct {
+	Name string
+	Age  int
+}
+
+// SumAges sums all ages in people, the people with invalid ages are an error
+func SumAges(people []Person) int {
+	sum := 0
+	for _, person := range people {
+		if person.Age < 0 {
+			return -1
+		}
+		sum += person.Age
+	}
+	return sum
+}
+
+// Total sums the positive values of the slice
+func Total(values []int) int {
+	total := 0
+	for _, v := range values {
+		if v > 0 {
+			total += v
+		}
+	}
+	return total
+}
+
+// Ascending counts the elements of the array greater than their indices
+func Ascending(values [3]int) int {
+	count := 0
+	for i, v := range values {
+		if v > i {
+			count++
+		}
+	}
+	return count
+}
+
+// Last assigns the elements to the variable declared before the loop
+func Last(values []int) int {
+	last := -1
+	for _, last = range values {
+	}
+	return last
+}
+
+// Wide returns the byte index of the first rune which takes several bytes
+func Wide(s string) int {
+	for i, r := range s {
+		if r >= 0x80 {
+			return i
+		}
+	}
+	return -1
+}
+
+// Runes counts the runes of the string
+func Runes(s string) int {
+	count := 0
+	for range s {
+		count++
+	}
+	return count
+}
+
+// Sum sums the values of the map, the order of the keys doesn't matter
+func Sum(m map[int]int) int {
+	sum := 0
+	for _, v := range m {
+		sum += v
+	}
+	return sum
+}
+
+// First returns the first key the iteration visits with the true value. Any key of the map may be visited
+// first, so the maps with several true values give different results on different paths
+func First(m map[int]bool) int {
+	for k, v := range m {
+		if v {
+			return k
+		}
+	}
+	return -1
+}
+
+// Lookup reads the map by the key
+func Lookup(m map[int]int, key int) int {
+	if v, ok := m[key]; ok {
+		return v
+	}
+	return len(m)
+}
+
+// Stock reads the map by the string key
+func Stock(m map[string]int, item string) int {
+	if n, ok := m[item]; ok {
+		return n
+	}
+	if _, ok := m["spare"]; ok {
+		return -1
+	}
+	return len(m)
+}
+
+// Squares ranges over the integer
+func Squares(n int) int {
+	sum := 0
+	for i := range n {
+		sum += i * i
+	}
+	return sum
+}
+
+// Pairs counts the pairs of equal elements, the inner loop continues the outer one by its label
+func Pairs(values []int) int {
+	count := 0
+outer:
+	for i, a := range values {
+		for j := i + 1; j < len(values); j++ {
+			if a == values[j] {
+				count++
+				continue outer
+			}
+		}
+	}
+	return count
+}
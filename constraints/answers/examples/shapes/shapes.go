@@ -0,0 +1,104 @@
+package shapes
+
+import "math"
+
+type Shape interface {
+	Area() float64
+}
+
+type Scalable interface {
+	Shape
+	Scale(factor float64)
+}
+
+type Named interface {
+	Name() string
+}
+
+type Circle struct {
+	Radius float64
+}
+
+func (c Circle) Area() float64 {
+	return math.Pi * c.Radius * c.Radius
+}
+
+func (c Circle) Name() string {
+	return "circle"
+}
+
+// Square can be scaled only through a pointer
+type Square struct {
+	Side float64
+}
+
+func (s Square) Area() float64 {
+	return s.Side * s.Side
+}
+
+func (s *Square) Scale(factor float64) {
+	s.Side *= factor
+}
+
+type InvalidShape struct {
+	Reason string
+}
+
+func (e InvalidShape) Error() string {
+	return e.Reason
+}
+
+func Describe(s Shape) int {
+	if s == nil {
+		return 0
+	}
+
+	if _, ok := s.(Scalable); ok {
+		return 1
+	}
+
+	if _, ok := s.(Named); ok {
+		return 2
+	}
+
+	return 3
+}
+
+// Resize doubles the square through the method with the pointer receiver
+func Resize(sq Square) string {
+	sq.Scale(2)
+	if sq.Side > 10 {
+		return "large"
+	}
+
+	return "small"
+}
+
+func Kind(s Shape) string {
+	switch v := s.(type) {
+	case nil:
+		return "none"
+	case Circle:
+		if v.Radius > 10 {
+			return "big circle"
+		}
+		return "circle"
+	case *Square:
+		return "square"
+	default:
+		return "shape"
+	}
+}
+
+// Grow doubles the scalable shapes and returns the area the shape has afterwards
+func Grow(s Shape) float64 {
+	if scalable, ok := s.(Scalable); ok {
+		scalable.Scale(2)
+	}
+
+	return s.Area()
+}
+
+func MustSquare(s Shape) float64 {
+	return s.(*Square).Side
+}
@@ -0,0 +1,28 @@
+package summaries
+
+func clamp(x int, low int, high int) int {
+	if x < low {
+		return low
+	}
+	if x > high {
+		return high
+	}
+	return x
+}
+
+// Brightness calls clamp three times, the summary of clamp is computed once and instantiated at every call
+func Brightness(r int, g int, b int) int {
+	return (clamp(r, 0, 255) + clamp(g, 0, 255) + clamp(b, 0, 255)) / 3
+}
+
+func ratio(a int, b int) int {
+	return a / b
+}
+
+// Share panics when ratio divides by zero, the panicking path of the summary is instantiated too
+func Share(total int, parts int) int {
+	if total < 0 {
+		return 0
+	}
+	return ratio(total, parts)
+}
@@ -0,0 +1,147 @@
+package switches
+
+// Grade is a tagless switch, its cases are conditions tested in order
+func Grade(score int) string {
+	switch {
+	case score < 0 || score > 100:
+		return "invalid"
+	case score >= 90:
+		return "A"
+	case score >= 75:
+		return "B"
+	default:
+		return "C"
+	}
+}
+
+// Weekday lists several values in a case, the default clause comes first but is taken last
+func Weekday(day int) string {
+	switch day % 7 {
+	default:
+		return "negative"
+	case 0, 6:
+		return "weekend"
+	case 1, 2, 3, 4, 5:
+		return "workday"
+	}
+}
+
+// Level falls through from every clause to the next one
+func Level(n int) int {
+	level := 0
+	switch {
+	case n > 100:
+		level++
+		fallthrough
+	case n > 10:
+		level++
+		fallthrough
+	case n > 0:
+		level++
+	}
+	return level
+}
+
+// Unit compares the string tag with the constants of the cases
+func Unit(name string) int {
+	switch name {
+	case "km":
+		return 1000
+	case "m":
+		return 1
+	}
+	return 0
+}
+
+// Same compares the interface with values of different types, the case matches the dynamic type and value
+func Same(value any) string {
+	switch value {
+	case nil:
+		return "nil"
+	case 1, "one":
+		return "one"
+	case true:
+		return "yes"
+	}
+	return "other"
+}
+
+func half(x int) int {
+	return x / 2
+}
+
+// Parity switches on the result of a call after the init statement
+func Parity(x int) string {
+	switch h := half(x); h % 2 {
+	case 0:
+		if h == 0 {
+			break
+		}
+		return "even half"
+	case 1, -1:
+		return "odd half"
+	}
+	return "zero"
+}
+
+// Pair finds the first pair of counters with the given sum by breaking out of the outer loop
+func Pair(sum int) int {
+	found := -1
+outer:
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if i == j {
+				continue outer
+			}
+			if i+j == sum {
+				found = i*10 + j
+				break outer
+			}
+		}
+	}
+	return found
+}
+
+// Skip counts the steps of the loop, the switch inside it continues the loop by its label
+func Skip(n int) int {
+	count := 0
+loop:
+	for i := 0; i < n; i++ {
+		switch i % 3 {
+		case 0:
+			continue loop
+		case 1:
+			if i > 4 {
+				break loop
+			}
+		}
+		count++
+	}
+	return count
+}
+
+// Steps is the loop made of a goto jumping back
+func Steps(n int) int {
+	steps := 0
+again:
+	if n > 1 {
+		n /= 2
+		steps++
+		goto again
+	}
+	return steps
+}
+
+// Clamp jumps forward over the code for the values in range
+func Clamp(x int) int {
+	if x >= 0 && x <= 10 {
+		goto done
+	}
+	if x < 0 {
+		x = 0
+	} else {
+		x = 10
+	}
+done:
+	return x
+}
@@ -0,0 +1,75 @@
+package unknown
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Label formats the number by strconv.Itoa, which has no body to explore
+func Label(n int) string {
+	if n < 0 {
+		return "negative"
+	}
+	s := strconv.Itoa(n)
+	if len(s) > 2 {
+		return "long " + s
+	}
+	return s
+}
+
+// Power takes the result of math.Pow, the calls with equal arguments return equal results when it's abstracted
+func Power(x float64, y float64) int {
+	if math.Pow(x, y) > 100 {
+		return 1
+	}
+	if math.Pow(x, y) == math.Pow(x, y) {
+		return 0
+	}
+	return -1
+}
+
+// Shout compares the string with its upper case
+func Shout(s string) bool {
+	return strings.ToUpper(s) == s
+}
+
+// Digit checks the rune by unicode.IsDigit
+func Digit(r rune) int {
+	if unicode.IsDigit(r) {
+		return int(r - '0')
+	}
+	return -1
+}
+
+func square(n int) string {
+	return strconv.Itoa(n * n)
+}
+
+// Squares calls the helper calling strconv.Itoa, the helper isn't summarized and is explored at every call
+func Squares(a int, b int) bool {
+	return square(a) == square(b)
+}
+
+// Wave prefers the policy of math.Sin to its model when the policy is set
+func Wave(x float64) string {
+	if math.Sin(x) > 0.5 {
+		return "crest"
+	}
+	return "trough"
+}
+
+// Number parses the string by strconv.Atoi, the concretized call returns its error for the strings of no number
+func Number(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// Unsigned trims the sign by strings.TrimPrefix, which isn't one of the standard implementations
+func Unsigned(s string) string {
+	return strings.TrimPrefix(s, "-")
+}
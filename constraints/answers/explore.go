@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"github.com/vldF/symbolic_execution_course/constraints/engine"
+	"github.com/vldF/symbolic_execution_course/constraints/smt/types"
+	"github.com/vldF/symbolic_execution_course/constraints/testgen"
+	"strings"
+)
+
+func solveDispatch() {
+	exploreFunction("examples/shapes", "Kind")
+	exploreFunction("examples/shapes", "Grow")
+	exploreFunction("examples/shapes", "MustSquare")
+}
+
+func solveShortCircuit() {
+	exploreFunction("examples/conditions", "BitwiseOperations")
+	exploreFunction("examples/conditions", "SafeRatio")
+	exploreFunction("examples/conditions", "Check")
+}
+
+func solveLoops() {
+	exploreFunction("examples/loops", "PushPopIncrementality")
+	exploreFunction("examples/loops", "FirstNegative")
+	exploreFunction("examples/loops", "Steps")
+	exploreFunction("examples/loops", "Triangle")
+}
+
+func solveInvariants() {
+	proveInvariants("examples/invariants", "Sum", 1)
+	proveInvariants("examples/invariants", "Alternate", 1)
+	proveInvariants("examples/invariants", "Alternate", 2)
+	proveInvariants("examples/invariants", "Countdown", 2)
+	proveInvariants("examples/invariants", "Countdown", 4)
+}
+
+func solveCalls() {
+	exploreFunction("examples/calls", "Distance")
+	exploreFunction("examples/calls", "Factorial")
+}
+
+func solveSummaries() {
+	printSummary("examples/summaries", "Share")
+	exploreFunction("examples/summaries", "Brightness")
+	exploreFunction("examples/summaries", "Share")
+}
+
+func solveErrors() {
+	exploreFunction("examples/errs", "Parse")
+	exploreFunction("examples/errs", "Split")
+	exploreFunction("examples/errs", "Kind")
+	exploreFunction("examples/errs", "Describe")
+	exploreFunction("examples/errs", "Half")
+	exploreFunction("examples/errs", "Sentinel")
+}
+
+func solvePanics() {
+	exploreFunction("examples/panics", "SafeDivide")
+	exploreFunction("examples/panics", "Checked")
+	exploreFunction("examples/panics", "Count")
+	exploreFunction("examples/panics", "Escaping")
+	exploreFunction("examples/panics", "Nested")
+	exploreFunction("examples/panics", "Late")
+}
+
+func solveSwitches() {
+	exploreFunction("examples/switches", "Weekday")
+	exploreFunction("examples/switches", "Level")
+	exploreFunction("examples/switches", "Same")
+	exploreFunction("examples/switches", "Pair")
+	exploreFunction("examples/switches", "Steps")
+}
+
+func solveRanges() {
+	exploreFunction("examples/ranges", "SumAges")
+	exploreFunction("examples/ranges", "Ascending")
+	exploreFunction("examples/ranges", "Wide")
+	exploreFunction("examples/ranges", "First")
+	exploreFunction("examples/ranges", "Lookup")
+}
+
+func solveAliasing() {
+	exploreFunction("examples/aliasing", "Copied")
+	exploreFunction("examples/aliasing", "Reset")
+	exploreFunction("examples/aliasing", "Counted")
+	exploreFunction("examples/aliasing", "Detached")
+}
+
+func solveGenerics() {
+	exploreFunction("examples/generics", "Clamp")
+	exploreFunction("examples/generics", "Larger")
+}
+
+func solveClosures() {
+	exploreFunction("examples/closures", "Counter")
+	exploreFunction("examples/closures", "AddTwice")
+	exploreFunction("examples/closures", "Apply")
+	exploreWith("examples/closures", "Apply", func(explorer *engine.Explorer) {
+		explorer.Candidates = map[string][]string{"f": {"double", "square"}}
+	})
+	exploreFunction("examples/closures", "Operation")
+}
+
+func solveMethods() {
+	exploreFunction("examples/methods", "(*Account).Withdraw")
+	exploreFunction("examples/methods", "Account.Transfer")
+	exploreFunction("examples/methods", "Classify")
+	exploreWith("examples/methods", "Classify", func(explorer *engine.Explorer) {
+		explorer.SymbolicGlobals = true
+	})
+}
+
+func solveLibraries() {
+	exploreFunction("examples/libraries", "Bucket")
+	exploreFunction("examples/libraries", "Parity")
+	exploreFunction("examples/libraries", "Classify")
+	exploreFunction("examples/libraries", "Growth")
+}
+
+func solveUnknownCalls() {
+	exploreFunction("examples/unknown", "Label")
+	exploreWith("examples/unknown", "Label", func(explorer *engine.Explorer) {
+		explorer.DefaultPolicy = engine.Concretize
+	})
+	exploreWith("examples/unknown", "Power", func(explorer *engine.Explorer) {
+		explorer.Policies = map[string]engine.Policy{"math.Pow": engine.Abstract}
+	})
+	exploreWith("examples/unknown", "Squares", func(explorer *engine.Explorer) {
+		explorer.DefaultPolicy = engine.Concretize
+	})
+	exploreWith("examples/unknown", "Number", func(explorer *engine.Explorer) {
+		explorer.DefaultPolicy = engine.Concretize
+	})
+	exploreWith("examples/unknown", "Unsigned", func(explorer *engine.Explorer) {
+		explorer.DefaultPolicy = engine.Concretize
+		explorer.Implementations["strings.TrimPrefix"] = strings.TrimPrefix
+	})
+}
+
+// exploreFunction prints the paths of the function from the package in dir and the tests covering them
+func exploreFunction(dir string, name string) {
+	exploreWith(dir, name, func(*engine.Explorer) {})
+}
+
+// exploreWith explores the function by the explorer with the settings made by configure
+func exploreWith(dir string, name string, configure func(explorer *engine.Explorer)) {
+	fmt.Println("===================")
+	fmt.Printf("paths of %s\n", name)
+
+	pkg, err := types.Load(dir)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	sCtx := CreateSymContext()
+	explorer, err := engine.NewExplorer(&sCtx, pkg)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	configure(explorer)
+
+	paths, err := explorer.Explore(name)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	cases := make([]testgen.Case, 0, len(paths))
+	for _, path := range paths {
+		fmt.Println(path)
+		// the incomplete paths have no outcome to check
+		if path.Status != engine.Incomplete && path.Status != engine.Unsupported {
+			cases = append(cases, path.Test)
+		}
+	}
+
+	coverage := explorer.MCDC(paths)
+	if len(coverage) > 0 {
+		fmt.Println("MC/DC coverage:")
+		for _, decision := range coverage {
+			fmt.Println(decision)
+		}
+	}
+
+	file := testgen.RenderFile(pkg.Package.Name(), cases, explorer.Notes()...)
+	if file == "" {
+		fmt.Println("no tests generated")
+		return
+	}
+	fmt.Println("generated tests:")
+	fmt.Println(file)
+}
+
+func proveInvariants(dir string, name string, k int) {
+	fmt.Println("===================")
+	fmt.Printf("invariants of %s, k = %d\n", name, k)
+
+	pkg, err := types.Load(dir)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	sCtx := CreateSymContext()
+	explorer, err := engine.NewExplorer(&sCtx, pkg)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	proofs, err := explorer.ProveInvariants(name, k)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	for _, proof := range proofs {
+		fmt.Println(proof)
+	}
+}
+
+// printSummary prints the paths of the summary of the function from the package in dir
+func printSummary(dir string, name string) {
+	fmt.Println("===================")
+	fmt.Printf("summary of %s\n", name)
+
+	pkg, err := types.Load(dir)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	sCtx := CreateSymContext()
+	explorer, err := engine.NewExplorer(&sCtx, pkg)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	summary, err := explorer.Summarize(name)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(summary)
+}
@@ -5,5 +5,26 @@ func main() {
 	solveComplex()
 	solvePushPop()
 	solveArrays()
+	solveMaps()
+	solveStructs()
+	solveStrings()
+	solveTypes()
+	solveGoTypes()
+	solveDispatch()
+	solveGenerics()
+	solveShortCircuit()
+	solveLoops()
+	solveInvariants()
+	solveCalls()
+	solveSummaries()
+	solveErrors()
+	solvePanics()
+	solveSwitches()
+	solveRanges()
+	solveAliasing()
+	solveClosures()
+	solveMethods()
+	solveLibraries()
+	solveUnknownCalls()
 	solveSelfconstraints()
 }
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+	"reflect"
+)
+
+func solveMaps() {
+	solveUpdateStock()
+}
+
+//	func updateStock(stock map[int]int, item int, amount int) int {
+//		current, ok := stock[item]
+//		if !ok {
+//			stock[item] = amount
+//			return len(stock)						(1), panics on nil map (2)
+//		}
+//
+//		if current+amount <= 0 {
+//			delete(stock, item)
+//			return -1								(3)
+//		}
+//
+//		stock[item] = current + amount
+//		return len(stock)							(4)
+//	}
+func solveUpdateStock() {
+	fmt.Println("func updateStock(stock map[int]int, item int, amount int) int")
+	runForCase(updateStock1)
+	runForCase(updateStock2)
+	runForCase(updateStock3)
+	runForCase(updateStock4)
+}
+
+func newStockArgument(sCtx *smt.SymContext) smt.SymMap {
+	argStock, err := sCtx.NewMapArgument("stock", reflect.TypeOf(map[int]int{}))
+	if err != nil {
+		panic(err)
+	}
+
+	return argStock
+}
+
+func updateStock1(sCtx *smt.SymContext) string {
+	argStock := newStockArgument(sCtx)
+	argItem := sCtx.NewIntArgument("item")
+	argAmount := sCtx.NewIntArgument("amount")
+
+	sCtx.Solver.Assert(argStock.Touch(argItem))
+	_, ok := argStock.Lookup(argItem)
+	sCtx.Solver.Assert(ok.Not())
+
+	_, panics := argStock.Store(argItem, argAmount)
+	sCtx.Solver.Assert(panics.Not())
+
+	return "!ok && stock != nil"
+}
+
+func updateStock2(sCtx *smt.SymContext) string {
+	argStock := newStockArgument(sCtx)
+	argItem := sCtx.NewIntArgument("item")
+	argAmount := sCtx.NewIntArgument("amount")
+
+	sCtx.Solver.Assert(argStock.Touch(argItem))
+	_, ok := argStock.Lookup(argItem)
+	sCtx.Solver.Assert(ok.Not())
+
+	_, panics := argStock.Store(argItem, argAmount)
+	sCtx.Solver.Assert(panics)
+
+	return "!ok && stock == nil (panic)"
+}
+
+func updateStock3(sCtx *smt.SymContext) string {
+	argStock := newStockArgument(sCtx)
+	argItem := sCtx.NewIntArgument("item")
+	argAmount := sCtx.NewIntArgument("amount")
+
+	sCtx.Solver.Assert(argStock.Touch(argItem))
+	current, ok := argStock.Lookup(argItem)
+	sCtx.Solver.Assert(ok)
+
+	zeroIntConst := sCtx.Ctx.FromInt(0, sCtx.Ctx.IntSort()).(z3.Int)
+	cond := current.(z3.Int).Add(argAmount).LE(zeroIntConst)
+	sCtx.Solver.Assert(cond)
+
+	_ = argStock.Delete(argItem)
+
+	return "ok && (current+amount <= 0)"
+}
+
+func updateStock4(sCtx *smt.SymContext) string {
+	argStock := newStockArgument(sCtx)
+	argItem := sCtx.NewIntArgument("item")
+	argAmount := sCtx.NewIntArgument("amount")
+
+	sCtx.Solver.Assert(argStock.Touch(argItem))
+	current, ok := argStock.Lookup(argItem)
+	sCtx.Solver.Assert(ok)
+
+	zeroIntConst := sCtx.Ctx.FromInt(0, sCtx.Ctx.IntSort()).(z3.Int)
+	prevCond := current.(z3.Int).Add(argAmount).LE(zeroIntConst)
+	sCtx.Solver.Assert(prevCond.Not())
+
+	_, panics := argStock.Store(argItem, current.(z3.Int).Add(argAmount))
+	sCtx.Solver.Assert(panics.Not())
+
+	return "ok && !(current+amount <= 0)"
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"math/big"
+
+	"github.com/aclements/go-z3/z3"
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+)
+
+func registerBits(registry Registry) {
+	for _, suffix := range []string{"", "8", "16", "32", "64"} {
+		registry.Register("math/bits.OnesCount"+suffix, bitCount(suffix, onesCount))
+		registry.Register("math/bits.LeadingZeros"+suffix, bitCount(suffix, func(bits []z3.Int) z3.Int {
+			return intConst(bits[0], len(bits)).Sub(length(bits))
+		}))
+		registry.Register("math/bits.TrailingZeros"+suffix, bitCount(suffix, trailingZeros))
+		registry.Register("math/bits.Len"+suffix, bitCount(suffix, length))
+	}
+}
+
+// bitCount is the model of the function counting bits of the unsigned integer, the suffix of its name is the width
+func bitCount(suffix string, count func(bits []z3.Int) z3.Int) Model {
+	return func(sCtx *smt.SymContext, args []smt.SymValue) (Call, error) {
+		x, err := intArg(args, 0)
+		if err != nil {
+			return Call{}, err
+		}
+
+		width := sCtx.TypesCtx.IntSize
+		switch suffix {
+		case "8":
+			width = 8
+		case "16":
+			width = 16
+		case "32":
+			width = 32
+		case "64":
+			width = 64
+		}
+		bits, sum := bitsOf(sCtx, x, width)
+		return Call{Results: []smt.SymValue{smt.IntValue(count(bits))}, Facts: []z3.Bool{sum}}, nil
+	}
+}
+
+// bitsOf splits the integer into the bits, the fact is x = Σ bitᵢ·2ⁱ. The bits keep the counts in the linear
+// arithmetic, the solver is slow on the conversions of integers to bit-vectors and back. The bits are
+// the applications of the uninterpreted function, so the calls with the same argument share them
+func bitsOf(sCtx *smt.SymContext, x z3.Int, width int) ([]z3.Int, z3.Bool) {
+	zero, one := intConst(x, 0), intConst(x, 1)
+	bit := sCtx.Ctx.FuncDecl("math/bits.bit", []z3.Sort{x.Sort(), x.Sort()}, x.Sort())
+	bits := make([]z3.Int, width)
+	facts := make([]z3.Bool, 0, width)
+	sum := zero
+	for i := range bits {
+		bits[i] = bit.Apply(x, intConst(x, i)).(z3.Int)
+		facts = append(facts, bits[i].GE(zero).And(bits[i].LE(one)))
+		// 2ⁱ doesn't fit into int64 for the highest bit of uint64
+		power := sCtx.Ctx.FromBigInt(new(big.Int).Lsh(big.NewInt(1), uint(i)), sCtx.Ctx.IntSort()).(z3.Int)
+		sum = sum.Add(bits[i].Mul(power))
+	}
+
+	return bits, x.Eq(sum).And(facts...)
+}
+
+func intConst(x z3.Int, value int) z3.Int {
+	return x.Context().FromInt(int64(value), x.Sort()).(z3.Int)
+}
+
+func onesCount(bits []z3.Int) z3.Int {
+	return intConst(bits[0], 0).Add(bits...)
+}
+
+// length is the number of bits without the leading zeros, the highest set bit overrides the lower ones
+func length(bits []z3.Int) z3.Int {
+	one := intConst(bits[0], 1)
+	count := intConst(bits[0], 0)
+	for i, bit := range bits {
+		count = bit.Eq(one).IfThenElse(intConst(bit, i+1), count).(z3.Int)
+	}
+	return count
+}
+
+// trailingZeros is the index of the lowest set bit, zero has the width
+func trailingZeros(bits []z3.Int) z3.Int {
+	one := intConst(bits[0], 1)
+	count := intConst(bits[0], len(bits))
+	for i := len(bits) - 1; i >= 0; i-- {
+		count = bits[i].Eq(one).IfThenElse(intConst(bits[i], i), count).(z3.Int)
+	}
+	return count
+}
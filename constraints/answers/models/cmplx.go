@@ -0,0 +1,48 @@
+package models
+
+import (
+	"github.com/aclements/go-z3/z3"
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+)
+
+func registerCmplx(registry Registry) {
+	registry.Register("math/cmplx.Abs", func(sCtx *smt.SymContext, args []smt.SymValue) (Call, error) {
+		x, err := complexArg(args, 0)
+		if err != nil {
+			return Call{}, err
+		}
+		return returns(smt.FloatValue(hypot(sCtx, x.Real(), x.Imag()))), nil
+	})
+	registry.Register("math/cmplx.Conj", func(sCtx *smt.SymContext, args []smt.SymValue) (Call, error) {
+		x, err := complexArg(args, 0)
+		if err != nil {
+			return Call{}, err
+		}
+		return returns(smt.ComplexValue(x.Real(), x.Imag().Neg())), nil
+	})
+	registry.Register("math/cmplx.IsInf", complexPredicate(func(re z3.Float, im z3.Float) z3.Bool {
+		return re.IsInfinite().Or(im.IsInfinite())
+	}))
+	// an infinite part makes the number infinite even when the other part is NaN
+	registry.Register("math/cmplx.IsNaN", complexPredicate(func(re z3.Float, im z3.Float) z3.Bool {
+		return re.IsInfinite().Or(im.IsInfinite()).Not().And(re.IsNaN().Or(im.IsNaN()))
+	}))
+	registry.Register("math/cmplx.Inf", func(sCtx *smt.SymContext, _ []smt.SymValue) (Call, error) {
+		inf := sCtx.Ctx.FloatInf(float64Sort(sCtx), false)
+		return returns(smt.ComplexValue(inf, inf)), nil
+	})
+	registry.Register("math/cmplx.NaN", func(sCtx *smt.SymContext, _ []smt.SymValue) (Call, error) {
+		nan := sCtx.Ctx.FloatNaN(float64Sort(sCtx))
+		return returns(smt.ComplexValue(nan, nan)), nil
+	})
+}
+
+func complexPredicate(predicate func(re z3.Float, im z3.Float) z3.Bool) Model {
+	return func(sCtx *smt.SymContext, args []smt.SymValue) (Call, error) {
+		x, err := complexArg(args, 0)
+		if err != nil {
+			return Call{}, err
+		}
+		return returns(smt.BoolValue(predicate(x.Real(), x.Imag()))), nil
+	}
+}
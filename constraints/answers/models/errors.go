@@ -0,0 +1,46 @@
+package models
+
+import (
+	"fmt"
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+)
+
+// Error is the result of errors.New and fmt.Errorf. The explorer makes it into an error value of its own at
+// every call, as the library functions allocate one, so only the same error is equal to it. Text is the message
+// of errors.New or the format of fmt.Errorf, which formats Args by it. The arguments passed to the variadic
+// parameter of fmt.Errorf follow the format one by one
+type Error struct {
+	smt.SymValueBase
+	Text      smt.SymString
+	Formatted bool
+	Args      []smt.SymValue
+}
+
+func registerErrors(registry Registry) {
+	registry.Register("errors.New", func(sCtx *smt.SymContext, args []smt.SymValue) (Call, error) {
+		text, err := stringArg(args, 0)
+		if err != nil {
+			return Call{}, err
+		}
+		return returns(Error{Text: text}), nil
+	})
+	registry.Register("fmt.Errorf", func(sCtx *smt.SymContext, args []smt.SymValue) (Call, error) {
+		format, err := stringArg(args, 0)
+		if err != nil {
+			return Call{}, err
+		}
+		return returns(Error{Text: format, Formatted: true, Args: args[1:]}), nil
+	})
+}
+
+func stringArg(args []smt.SymValue, i int) (smt.SymString, error) {
+	if i >= len(args) {
+		return smt.SymString{}, fmt.Errorf("argument %d is missing", i)
+	}
+	value, ok := args[i].(smt.SymString)
+	if !ok {
+		return smt.SymString{}, fmt.Errorf("argument %d is %T, not a string", i, args[i])
+	}
+
+	return value, nil
+}
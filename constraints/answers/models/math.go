@@ -0,0 +1,165 @@
+package models
+
+import (
+	"github.com/aclements/go-z3/z3"
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+)
+
+func registerMath(registry Registry) {
+	registry.Register("math.Abs", unaryFloat(func(_ *smt.SymContext, x z3.Float) z3.Float { return x.Abs() }))
+	registry.Register("math.Sqrt", unaryFloat(func(_ *smt.SymContext, x z3.Float) z3.Float { return x.Sqrt() }))
+	registry.Register("math.Floor", rounding(z3.RoundToNegative))
+	registry.Register("math.Ceil", rounding(z3.RoundToPositive))
+	registry.Register("math.Trunc", rounding(z3.RoundToZero))
+	registry.Register("math.Round", rounding(z3.RoundToNearestAway))
+	registry.Register("math.RoundToEven", rounding(z3.RoundToNearestEven))
+	registry.Register("math.Max", binaryFloat(maxFloat))
+	registry.Register("math.Min", binaryFloat(minFloat))
+	registry.Register("math.Dim", binaryFloat(dim))
+	registry.Register("math.Hypot", binaryFloat(hypot))
+	registry.Register("math.Copysign", binaryFloat(func(_ *smt.SymContext, f z3.Float, sign z3.Float) z3.Float {
+		return sign.IsNegative().IfThenElse(f.Abs().Neg(), f.Abs()).(z3.Float)
+	}))
+	registry.Register("math.Inf", inf)
+	registry.Register("math.NaN", func(sCtx *smt.SymContext, _ []smt.SymValue) (Call, error) {
+		return returns(smt.FloatValue(sCtx.Ctx.FloatNaN(float64Sort(sCtx)))), nil
+	})
+	registry.Register("math.IsNaN", floatPredicate(func(x z3.Float) z3.Bool { return x.IsNaN() }))
+	registry.Register("math.Signbit", floatPredicate(func(x z3.Float) z3.Bool { return x.IsNegative() }))
+	registry.Register("math.IsInf", isInf)
+
+	registry.Register("math.Exp", uninterpreted("math.Exp", expAxioms))
+	registry.Register("math.Log", uninterpreted("math.Log", logAxioms))
+	registry.Register("math.Sin", uninterpreted("math.Sin", func(sCtx *smt.SymContext, x z3.Float, result z3.Float) z3.Bool {
+		// sin(±0) = ±0
+		return periodicAxioms(sCtx, x, result).And(x.IsZero().Implies(result.Eq(x)))
+	}))
+	registry.Register("math.Cos", uninterpreted("math.Cos", func(sCtx *smt.SymContext, x z3.Float, result z3.Float) z3.Bool {
+		one := sCtx.Ctx.FromFloat64(1, x.Sort())
+		return periodicAxioms(sCtx, x, result).And(x.IsZero().Implies(result.Eq(one)))
+	}))
+}
+
+func float64Sort(sCtx *smt.SymContext) z3.Sort {
+	return sCtx.Ctx.FloatSort(11, 53)
+}
+
+// rounding rounds to an integer in the rounding mode, as fp.roundToIntegral does. Zeros, infinities and NaN stay
+func rounding(mode z3.RoundingMode) Model {
+	return unaryFloat(func(_ *smt.SymContext, x z3.Float) z3.Float { return x.Round(mode) })
+}
+
+func floatPredicate(predicate func(x z3.Float) z3.Bool) Model {
+	return func(sCtx *smt.SymContext, args []smt.SymValue) (Call, error) {
+		x, err := floatArg(args, 0)
+		if err != nil {
+			return Call{}, err
+		}
+		return returns(smt.BoolValue(predicate(x))), nil
+	}
+}
+
+// maxFloat is math.Max: +Inf wins over NaN, NaN wins over the numbers and +0 is greater than -0
+func maxFloat(sCtx *smt.SymContext, x z3.Float, y z3.Float) z3.Float {
+	posInf := sCtx.Ctx.FloatInf(x.Sort(), false)
+	zeros := x.IsZero().And(y.IsZero()).IfThenElse(x.IsNegative().IfThenElse(y, x), x.GT(y).IfThenElse(x, y))
+	numbers := x.IsNaN().Or(y.IsNaN()).IfThenElse(sCtx.Ctx.FloatNaN(x.Sort()), zeros)
+	return x.Eq(posInf).Or(y.Eq(posInf)).IfThenElse(posInf, numbers).(z3.Float)
+}
+
+// minFloat is math.Min: -Inf wins over NaN, NaN wins over the numbers and -0 is less than +0
+func minFloat(sCtx *smt.SymContext, x z3.Float, y z3.Float) z3.Float {
+	negInf := sCtx.Ctx.FloatInf(x.Sort(), true)
+	zeros := x.IsZero().And(y.IsZero()).IfThenElse(x.IsNegative().IfThenElse(x, y), x.LT(y).IfThenElse(x, y))
+	numbers := x.IsNaN().Or(y.IsNaN()).IfThenElse(sCtx.Ctx.FloatNaN(x.Sort()), zeros)
+	return x.Eq(negInf).Or(y.Eq(negInf)).IfThenElse(negInf, numbers).(z3.Float)
+}
+
+// dim is math.Dim, the positive difference. NaN and the difference of equal infinities stay NaN
+func dim(sCtx *smt.SymContext, x z3.Float, y z3.Float) z3.Float {
+	v := x.Sub(y)
+	return v.LE(sCtx.Ctx.FloatZero(x.Sort(), false)).IfThenElse(sCtx.Ctx.FloatZero(x.Sort(), false), v).(z3.Float)
+}
+
+// hypot follows the algorithm of math.Hypot step by step, so the rounding is the same:
+// max * sqrt(1 + (min/max)²), +Inf wins over NaN
+func hypot(sCtx *smt.SymContext, x z3.Float, y z3.Float) z3.Float {
+	sort := x.Sort()
+	p, q := x.Abs(), y.Abs()
+	p, q = p.LT(q).IfThenElse(q, p).(z3.Float), p.LT(q).IfThenElse(p, q).(z3.Float)
+
+	ratio := q.Div(p)
+	finite := p.IsZero().IfThenElse(sCtx.Ctx.FloatZero(sort, false), p.Mul(sCtx.Ctx.FromFloat64(1, sort).Add(ratio.Mul(ratio)).Sqrt()))
+	numbers := p.IsNaN().Or(q.IsNaN()).IfThenElse(sCtx.Ctx.FloatNaN(sort), finite)
+	return p.IsInfinite().Or(q.IsInfinite()).IfThenElse(sCtx.Ctx.FloatInf(sort, false), numbers).(z3.Float)
+}
+
+// inf is math.Inf: +Inf for the non-negative sign, -Inf otherwise
+func inf(sCtx *smt.SymContext, args []smt.SymValue) (Call, error) {
+	sign, err := intArg(args, 0)
+	if err != nil {
+		return Call{}, err
+	}
+
+	sort := float64Sort(sCtx)
+	zero := sCtx.Ctx.FromInt(0, sCtx.Ctx.IntSort()).(z3.Int)
+	result := sign.GE(zero).IfThenElse(sCtx.Ctx.FloatInf(sort, false), sCtx.Ctx.FloatInf(sort, true)).(z3.Float)
+	return returns(smt.FloatValue(result)), nil
+}
+
+// isInf is math.IsInf: the positive sign checks +Inf, the negative one checks -Inf and zero checks both
+func isInf(sCtx *smt.SymContext, args []smt.SymValue) (Call, error) {
+	f, err := floatArg(args, 0)
+	if err != nil {
+		return Call{}, err
+	}
+	sign, err := intArg(args, 1)
+	if err != nil {
+		return Call{}, err
+	}
+
+	zero := sCtx.Ctx.FromInt(0, sCtx.Ctx.IntSort()).(z3.Int)
+	positive := sign.GE(zero).And(f.Eq(sCtx.Ctx.FloatInf(f.Sort(), false)))
+	negative := sign.LE(zero).And(f.Eq(sCtx.Ctx.FloatInf(f.Sort(), true)))
+	return returns(smt.BoolValue(positive.Or(negative))), nil
+}
+
+// expAxioms: exp(NaN) = NaN, exp(+Inf) = +Inf, exp(-Inf) = 0, exp(±0) = 1, exp is non-negative elsewhere and
+// lies on the side of 1 of the sign of x. The results near zero round to 1, so the bounds aren't strict
+func expAxioms(sCtx *smt.SymContext, x z3.Float, result z3.Float) z3.Bool {
+	sort := x.Sort()
+	zero := sCtx.Ctx.FloatZero(sort, false)
+	return x.IsNaN().Implies(result.IsNaN()).And(
+		x.Eq(sCtx.Ctx.FloatInf(sort, false)).Implies(result.Eq(sCtx.Ctx.FloatInf(sort, false))),
+		x.Eq(sCtx.Ctx.FloatInf(sort, true)).Implies(result.Eq(zero)),
+		x.IsZero().Implies(result.Eq(sCtx.Ctx.FromFloat64(1, sort))),
+		x.IsNaN().Not().Implies(result.GE(zero)),
+		x.GT(zero).Implies(result.GE(sCtx.Ctx.FromFloat64(1, sort))),
+		x.LT(zero).Implies(result.LE(sCtx.Ctx.FromFloat64(1, sort))),
+	)
+}
+
+// logAxioms: log of NaN and of negative numbers is NaN, log(±0) = -Inf, log(+Inf) = +Inf, log(1) = 0,
+// the logarithm of a finite positive number is finite and has the sign of its distance from 1
+func logAxioms(sCtx *smt.SymContext, x z3.Float, result z3.Float) z3.Bool {
+	sort := x.Sort()
+	zero := sCtx.Ctx.FloatZero(sort, false)
+	one := sCtx.Ctx.FromFloat64(1, sort)
+	finite := result.IsNaN().Not().And(result.IsInfinite().Not())
+	return x.IsNaN().Or(x.LT(zero)).Implies(result.IsNaN()).And(
+		x.IsZero().Implies(result.Eq(sCtx.Ctx.FloatInf(sort, true))),
+		x.Eq(sCtx.Ctx.FloatInf(sort, false)).Implies(result.Eq(sCtx.Ctx.FloatInf(sort, false))),
+		x.Eq(one).Implies(result.Eq(zero)),
+		x.GT(zero).And(x.IsInfinite().Not()).Implies(finite),
+		x.GT(one).And(x.IsInfinite().Not()).Implies(result.GT(zero)),
+		x.GT(zero).And(x.LT(one)).Implies(result.LT(zero)),
+	)
+}
+
+// periodicAxioms bound sine and cosine: they're NaN for NaN and infinities and lie in [-1, 1] elsewhere
+func periodicAxioms(sCtx *smt.SymContext, x z3.Float, result z3.Float) z3.Bool {
+	sort := x.Sort()
+	undefined := x.IsNaN().Or(x.IsInfinite())
+	bounded := result.GE(sCtx.Ctx.FromFloat64(-1, sort)).And(result.LE(sCtx.Ctx.FromFloat64(1, sort)))
+	return undefined.Implies(result.IsNaN()).And(undefined.Not().Implies(bounded))
+}
@@ -0,0 +1,133 @@
+// Package models keeps the symbolic models of library functions. The explorer calls the model of a function
+// instead of exploring it, since library functions have no bodies in the analyzed package. Models are exact
+// where the solver has the operation, as fp.sqrt and fp.roundToIntegral are, or follow the algorithm of the
+// library. The others are uninterpreted functions constrained by axioms, the explorer confirms the paths taking
+// them by running the real functions and doesn't check the outcomes of the tests it can't confirm.
+// The solver is slow on fp.sqrt and on the division, the checks of the paths through math.Sqrt, math.Hypot
+// and math/cmplx.Abs often run out of the solver timeout of the explorer and the paths are incomplete.
+// errors.New and fmt.Errorf return Error, the explorer makes it into the error value
+package models
+
+import (
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+)
+
+// Model computes the results of a call of the library function from its arguments, converted to the types
+// of the parameters
+type Model func(sCtx *smt.SymContext, args []smt.SymValue) (Call, error)
+
+// Call is the modeled call. The path taking it assumes the facts, as the axioms of the uninterpreted functions.
+// Approximate means the results are uninterpreted, the real function may return other values on the path
+type Call struct {
+	Results     []smt.SymValue
+	Facts       []z3.Bool
+	Approximate bool
+}
+
+// returns is the call without facts
+func returns(results ...smt.SymValue) Call {
+	return Call{Results: results}
+}
+
+// Registry maps the full names of functions, as math.Sqrt or math/bits.OnesCount, to their models.
+// Teams add the models of other library functions with Register
+type Registry map[string]Model
+
+// Default returns the models of math, math/bits, math/cmplx and of the functions making errors
+func Default() Registry {
+	registry := make(Registry)
+	registerMath(registry)
+	registerBits(registry)
+	registerCmplx(registry)
+	registerErrors(registry)
+
+	return registry
+}
+
+// Register adds the model of the function, it replaces the model the registry already has
+func (registry Registry) Register(name string, model Model) {
+	registry[name] = model
+}
+
+func floatArg(args []smt.SymValue, i int) (z3.Float, error) {
+	if i >= len(args) {
+		return z3.Float{}, fmt.Errorf("argument %d is missing", i)
+	}
+	value, ok := args[i].(smt.SymFloat)
+	if !ok {
+		return z3.Float{}, fmt.Errorf("argument %d is %T, not a float", i, args[i])
+	}
+
+	return value.Float(), nil
+}
+
+func intArg(args []smt.SymValue, i int) (z3.Int, error) {
+	if i >= len(args) {
+		return z3.Int{}, fmt.Errorf("argument %d is missing", i)
+	}
+	value, ok := args[i].(smt.SymInt)
+	if !ok {
+		return z3.Int{}, fmt.Errorf("argument %d is %T, not an integer", i, args[i])
+	}
+
+	return value.Int(), nil
+}
+
+func complexArg(args []smt.SymValue, i int) (smt.SymComplex, error) {
+	if i >= len(args) {
+		return smt.SymComplex{}, fmt.Errorf("argument %d is missing", i)
+	}
+	value, ok := args[i].(smt.SymComplex)
+	if !ok {
+		return smt.SymComplex{}, fmt.Errorf("argument %d is %T, not a complex number", i, args[i])
+	}
+
+	return value, nil
+}
+
+// unaryFloat is the model of the function of a float returning a float
+func unaryFloat(f func(sCtx *smt.SymContext, x z3.Float) z3.Float) Model {
+	return func(sCtx *smt.SymContext, args []smt.SymValue) (Call, error) {
+		x, err := floatArg(args, 0)
+		if err != nil {
+			return Call{}, err
+		}
+		return returns(smt.FloatValue(f(sCtx, x))), nil
+	}
+}
+
+// binaryFloat is the model of the function of two floats returning a float
+func binaryFloat(f func(sCtx *smt.SymContext, x z3.Float, y z3.Float) z3.Float) Model {
+	return func(sCtx *smt.SymContext, args []smt.SymValue) (Call, error) {
+		x, err := floatArg(args, 0)
+		if err != nil {
+			return Call{}, err
+		}
+		y, err := floatArg(args, 1)
+		if err != nil {
+			return Call{}, err
+		}
+		return returns(smt.FloatValue(f(sCtx, x, y))), nil
+	}
+}
+
+// uninterpreted is the model of the float function as the uninterpreted function with the name. The axioms
+// constrain the result of the application by the argument
+func uninterpreted(name string, axioms func(sCtx *smt.SymContext, x z3.Float, result z3.Float) z3.Bool) Model {
+	return func(sCtx *smt.SymContext, args []smt.SymValue) (Call, error) {
+		x, err := floatArg(args, 0)
+		if err != nil {
+			return Call{}, err
+		}
+
+		sort := x.Sort()
+		result := sCtx.Ctx.FuncDecl(name, []z3.Sort{sort}, sort).Apply(x).(z3.Float)
+		return Call{
+			Results:     []smt.SymValue{smt.FloatValue(result)},
+			Facts:       []z3.Bool{axioms(sCtx, x, result)},
+			Approximate: true,
+		}, nil
+	}
+}
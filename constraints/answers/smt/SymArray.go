@@ -27,17 +27,21 @@ func (arr *SymSimpleArray) Arr() z3.Array {
 }
 
 type SymStructArray struct {
+	sCtx   *SymContext
+	desc   *StructDescriptor
 	len    z3.Int
 	arrays map[string]z3.Array
 }
 
-type SymStructure = map[string]z3.Value
-
-func (sCtx *SymContext) NewStructArray(name string, elementDesc map[string]z3.Sort) SymStructArray {
+// NewStructArray creates an array of structs. Every leaf field of the struct is stored in an array of its own
+func (sCtx *SymContext) NewStructArray(name string, desc *StructDescriptor) SymStructArray {
 	lenVal := sCtx.Ctx.IntConst(name + "." + "len")
 	zeroConst := sCtx.Ctx.FromInt(-1, sCtx.Ctx.IntSort()).(z3.Int)
 	sCtx.Solver.Assert(lenVal.GT(zeroConst))
 
+	elementDesc := make(map[string]z3.Sort)
+	sCtx.leafSorts(desc, "", elementDesc)
+
 	arrays := make(map[string]z3.Array)
 
 	for fieldName, fieldSort := range elementDesc {
@@ -45,19 +49,16 @@ func (sCtx *SymContext) NewStructArray(name string, elementDesc map[string]z3.So
 		arrays[fieldName] = sCtx.Ctx.Const(name+"."+fieldName+".array", arrSort).(z3.Array)
 	}
 
-	return SymStructArray{len: lenVal, arrays: arrays}
+	return SymStructArray{sCtx: sCtx, desc: desc, len: lenVal, arrays: arrays}
 }
 
 func (arr *SymStructArray) Len() z3.Int {
 	return arr.len
 }
 
-func (arr *SymStructArray) GetStructure(index z3.Int) SymStructure {
-	resultFields := make(SymStructure)
-
-	for fieldName, innerArray := range arr.arrays {
-		resultFields[fieldName] = innerArray.Select(index)
-	}
-
-	return resultFields
+// GetStructure returns the element of the array. The element is a copy, changing it doesn't affect the array
+func (arr *SymStructArray) GetStructure(index z3.Int) SymStruct {
+	return arr.sCtx.buildStruct(arr.desc, "", func(path string, sort z3.Sort) z3.Value {
+		return arr.arrays[path].Select(index)
+	})
 }
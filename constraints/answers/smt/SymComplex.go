@@ -1,6 +1,9 @@
 package smt
 
-import "github.com/aclements/go-z3/z3"
+import (
+	"github.com/aclements/go-z3/z3"
+	"reflect"
+)
 
 type SymComplex struct {
 	re z3.Float
@@ -19,3 +22,36 @@ func (sCtx *SymContext) NewComplexConst(name string) SymComplex {
 
 func (complex SymComplex) Real() z3.Float { return complex.re }
 func (complex SymComplex) Imag() z3.Float { return complex.im }
+
+func (sCtx *SymContext) NewComplexArgument(name string) SymComplex {
+	result := sCtx.NewComplexConst(name)
+	sCtx.RegisterArgument(name, func(model *z3.Model) (reflect.Value, error) {
+		value, err := result.Decode(model)
+		return reflect.ValueOf(value), err
+	})
+
+	return result
+}
+
+func (complex SymComplex) Decode(model *z3.Model) (complex128, error) {
+	return decodeComplex(model, complex.re, complex.im)
+}
+
+func decodeComplex(model *z3.Model, re z3.Float, im z3.Float) (complex128, error) {
+	reValue, err := DecodeValue(model, re, reflect.TypeOf(0.0))
+	if err != nil {
+		return 0, err
+	}
+
+	imValue, err := DecodeValue(model, im, reflect.TypeOf(0.0))
+	if err != nil {
+		return 0, err
+	}
+
+	return complex(reValue.Float(), imValue.Float()), nil
+}
+
+// ComplexValue builds the complex number of the real and the imaginary parts
+func ComplexValue(re z3.Float, im z3.Float) SymComplex {
+	return SymComplex{re: re, im: im}
+}
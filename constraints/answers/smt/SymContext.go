@@ -1,11 +1,21 @@
 package smt
 
-import "github.com/aclements/go-z3/z3"
+import (
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	"reflect"
+)
 
 type SymContext struct {
 	Solver   *z3.Solver
 	Ctx      *z3.Context
 	TypesCtx TypesContext
+
+	arguments []symArgument
+	results   []func(model *z3.Model) (reflect.Value, error)
+
+	// names counts the names returned by FreshName
+	names map[string]int
 }
 
 type TypesContext struct {
@@ -16,4 +26,18 @@ type TypesContext struct {
 	MaxFloat64  float64
 	MinFloat64  float64
 	Float64Size int64
+
+	MaxStringLength int
+	MaxSliceLength  int
+}
+
+// FreshName returns the name numbered apart from the names returned before, as types1 and types2. The encodings
+// which may be made more than once in the context prefix their declarations by it, so they don't share them
+func (sCtx *SymContext) FreshName(name string) string {
+	if sCtx.names == nil {
+		sCtx.names = make(map[string]int)
+	}
+	sCtx.names[name]++
+
+	return fmt.Sprintf("%s%d", name, sCtx.names[name])
 }
@@ -0,0 +1,164 @@
+package smt
+
+import (
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	"go/types"
+	"reflect"
+)
+
+// SymFunction models a function argument with basic parameters and results as uninterpreted functions,
+// one for every result. Nothing constrains the function but the results of the calls the program makes.
+// Strings are passed to the functions and returned by them as their canonical arrays, see SymString.key
+type SymFunction struct {
+	params  []reflect.Type
+	results []reflect.Type
+	decls   []z3.FuncDecl
+
+	origin *functionOrigin
+}
+
+// functionOrigin is shared by all the copies of one function. It remembers the arguments of every call
+// to decode the function as the table of those calls
+type functionOrigin struct {
+	sCtx  *SymContext
+	calls [][]z3.Value
+}
+
+// FunctionCall is a row of the decoded function: the arguments of a call and the results it returns
+type FunctionCall struct {
+	Arguments []reflect.Value
+	Results   []reflect.Value
+}
+
+func (sCtx *SymContext) NewFunctionArgument(name string, params []*types.Basic, results []*types.Basic) (SymFunction, error) {
+	result, err := sCtx.newFunction(name, params, results, sCtx.Ctx.FreshFuncDecl)
+	if err != nil {
+		return SymFunction{}, err
+	}
+
+	sCtx.RegisterArgument(name, func(model *z3.Model) (reflect.Value, error) {
+		calls, err := result.Decode(model)
+		return reflect.ValueOf(calls), err
+	})
+
+	return result, nil
+}
+
+// NewAbstractFunction models the function the program calls but can't be explored. The uninterpreted functions
+// are named after it, so all its calls share them and return equal results for equal arguments
+func (sCtx *SymContext) NewAbstractFunction(name string, params []*types.Basic, results []*types.Basic) (SymFunction, error) {
+	return sCtx.newFunction(name, params, results, sCtx.Ctx.FuncDecl)
+}
+
+func (sCtx *SymContext) newFunction(name string, params []*types.Basic, results []*types.Basic, declare func(string, []z3.Sort, z3.Sort) z3.FuncDecl) (SymFunction, error) {
+	result := SymFunction{origin: &functionOrigin{sCtx: sCtx}}
+
+	domain := make([]z3.Sort, 0, len(params))
+	for _, param := range params {
+		t, sort, err := sCtx.functionSort(param)
+		if err != nil {
+			return SymFunction{}, err
+		}
+		result.params = append(result.params, t)
+		domain = append(domain, sort)
+	}
+
+	for i, res := range results {
+		t, sort, err := sCtx.functionSort(res)
+		if err != nil {
+			return SymFunction{}, err
+		}
+		result.results = append(result.results, t)
+		result.decls = append(result.decls, declare(fmt.Sprintf("%s.%d", name, i), domain, sort))
+	}
+
+	return result, nil
+}
+
+// functionSort returns the sort of the parameter or the result of a function argument,
+// complex numbers aren't scalars and can't be passed to uninterpreted functions
+func (sCtx *SymContext) functionSort(basic *types.Basic) (reflect.Type, z3.Sort, error) {
+	t, err := BasicType(basic)
+	if err != nil {
+		return nil, z3.Sort{}, err
+	}
+
+	sort, err := sCtx.keySort(t)
+	if err != nil {
+		return nil, z3.Sort{}, err
+	}
+
+	return t, sort, nil
+}
+
+// Call returns the results of the function for the arguments. The results are bounded the same way
+// as arguments of their types are and the strings are well-formed, the bounds hold for every call
+// so they're asserted globally
+func (f SymFunction) Call(args []SymValue) ([]SymValue, error) {
+	sCtx := f.origin.sCtx
+
+	terms := make([]z3.Value, 0, len(args))
+	for _, arg := range args {
+		term, err := keyTerm(arg)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+	f.origin.calls = append(f.origin.calls, terms)
+
+	results := make([]SymValue, 0, len(f.decls))
+	for i, decl := range f.decls {
+		term := decl.Apply(terms...)
+		if f.results[i].Kind() == reflect.String {
+			result := sCtx.stringOfKey(term.(z3.Array))
+			sCtx.Solver.Assert(result.wellFormed())
+			results = append(results, result)
+			continue
+		}
+		sCtx.Solver.Assert(sCtx.scalarBounds(term, f.results[i].Kind()))
+
+		result, err := ScalarValue(term)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// Decode builds the table of the calls in the model, the calls with the same arguments are listed once
+func (f SymFunction) Decode(model *z3.Model) ([]FunctionCall, error) {
+	result := make([]FunctionCall, 0, len(f.origin.calls))
+	seen := make(map[string]bool)
+	for _, terms := range f.origin.calls {
+		call := FunctionCall{}
+		key := make([]any, 0, len(terms))
+		for i, term := range terms {
+			arg, err := f.origin.sCtx.decodeKeyTerm(model, term, f.params[i])
+			if err != nil {
+				return nil, err
+			}
+			call.Arguments = append(call.Arguments, arg)
+			key = append(key, arg.Interface())
+		}
+
+		if seen[fmt.Sprint(key)] {
+			continue
+		}
+		seen[fmt.Sprint(key)] = true
+
+		for i, decl := range f.decls {
+			res, err := f.origin.sCtx.decodeKeyTerm(model, decl.Apply(terms...), f.results[i])
+			if err != nil {
+				return nil, err
+			}
+			call.Results = append(call.Results, res)
+		}
+		result = append(result, call)
+	}
+
+	return result, nil
+}
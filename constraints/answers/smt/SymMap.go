@@ -0,0 +1,305 @@
+package smt
+
+import (
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	"reflect"
+	"strconv"
+)
+
+// SymMap models map[K]V as an array of presence flags and an array of values, both indexed by keys.
+// String keys index the arrays by their canonical arrays, see SymString.key.
+// Operations never modify the map in place, every write returns a new state of the map
+type SymMap struct {
+	mapType   reflect.Type
+	keySort   z3.Sort
+	valueSort z3.Sort
+
+	isNil   z3.Bool
+	len     z3.Int
+	present z3.Array
+	values  z3.Array
+
+	origin *mapOrigin
+}
+
+// mapOrigin is shared by all the states of one map. Array models can't be enumerated,
+// so it remembers every key the program has touched to be able to decode the map later.
+// count is the number of the distinct touched keys present in the initial map
+type mapOrigin struct {
+	sCtx    *SymContext
+	len     z3.Int
+	present z3.Array
+	keys    []z3.Value
+	count   z3.Int
+}
+
+func (sCtx *SymContext) NewMapArgument(name string, mapType reflect.Type) (SymMap, error) {
+	keySort, valueSort, err := sCtx.mapSorts(mapType)
+	if err != nil {
+		return SymMap{}, err
+	}
+
+	isNil := sCtx.Ctx.BoolConst(name + ".isNil")
+	lenVal := sCtx.Ctx.IntConst(name + ".len")
+	zeroConst := sCtx.Ctx.FromInt(0, sCtx.Ctx.IntSort()).(z3.Int)
+	sCtx.Solver.Assert(lenVal.GE(zeroConst))
+	sCtx.Solver.Assert(isNil.Implies(lenVal.Eq(zeroConst)))
+
+	present := sCtx.Ctx.Const(name+".present", sCtx.Ctx.ArraySort(keySort, sCtx.Ctx.BoolSort())).(z3.Array)
+	values := sCtx.Ctx.Const(name+".values", sCtx.Ctx.ArraySort(keySort, valueSort)).(z3.Array)
+
+	result := SymMap{
+		mapType:   mapType,
+		keySort:   keySort,
+		valueSort: valueSort,
+		isNil:     isNil,
+		len:       lenVal,
+		present:   present,
+		values:    values,
+		origin:    &mapOrigin{sCtx: sCtx, len: lenVal, present: present, count: zeroConst},
+	}
+	sCtx.RegisterArgument(name, result.Decode)
+
+	return result, nil
+}
+
+// NewMap creates an empty non-nil map, as make(map[K]V) does
+func (sCtx *SymContext) NewMap(mapType reflect.Type) (SymMap, error) {
+	keySort, valueSort, err := sCtx.mapSorts(mapType)
+	if err != nil {
+		return SymMap{}, err
+	}
+
+	zeroConst := sCtx.Ctx.FromInt(0, sCtx.Ctx.IntSort()).(z3.Int)
+	present := sCtx.Ctx.ConstArray(keySort, sCtx.Ctx.FromBool(false))
+	values := sCtx.Ctx.ConstArray(keySort, sCtx.zeroValue(valueSort))
+
+	return SymMap{
+		mapType:   mapType,
+		keySort:   keySort,
+		valueSort: valueSort,
+		isNil:     sCtx.Ctx.FromBool(false),
+		len:       zeroConst,
+		present:   present,
+		values:    values,
+		origin:    &mapOrigin{sCtx: sCtx, len: zeroConst, present: present, count: zeroConst},
+	}, nil
+}
+
+// NewNilMap returns the nil map, reading it gives zero values and writing it panics
+func (sCtx *SymContext) NewNilMap(mapType reflect.Type) (SymMap, error) {
+	result, err := sCtx.NewMap(mapType)
+	result.isNil = sCtx.Ctx.FromBool(true)
+
+	return result, err
+}
+
+func (sCtx *SymContext) mapSorts(mapType reflect.Type) (z3.Sort, z3.Sort, error) {
+	if mapType.Kind() != reflect.Map {
+		return z3.Sort{}, z3.Sort{}, fmt.Errorf("%s is not a map type", mapType)
+	}
+
+	keySort, err := sCtx.keySort(mapType.Key())
+	if err != nil {
+		return z3.Sort{}, z3.Sort{}, err
+	}
+
+	valueSort, err := sCtx.SortOf(mapType.Elem())
+	if err != nil {
+		return z3.Sort{}, z3.Sort{}, err
+	}
+
+	return keySort, valueSort, nil
+}
+
+func (sCtx *SymContext) keySort(keyType reflect.Type) (z3.Sort, error) {
+	if keyType.Kind() == reflect.String {
+		return sCtx.Ctx.ArraySort(sCtx.Ctx.IntSort(), sCtx.Ctx.IntSort()), nil
+	}
+
+	return sCtx.SortOf(keyType)
+}
+
+// Key is the term indexing the map by the key
+func (m SymMap) Key(key SymValue) (z3.Value, error) {
+	return keyTerm(key)
+}
+
+// KeyValue is the key indexed by the term, it's the inverse of Key
+func (m SymMap) KeyValue(term z3.Value) (SymValue, error) {
+	if m.mapType.Key().Kind() == reflect.String {
+		return m.origin.sCtx.stringOfKey(term.(z3.Array)), nil
+	}
+
+	return ScalarValue(term)
+}
+
+func (m SymMap) Len() z3.Int {
+	return m.len
+}
+
+func (m SymMap) IsNil() z3.Bool {
+	return m.isNil
+}
+
+// Touch remembers the key the program accesses, the map is decoded with the touched keys. The result bounds
+// the number of the distinct touched keys present in the initial map by its length, the path accessing
+// the key assumes it. Lookup, Store and Delete take the touched keys
+func (m SymMap) Touch(key z3.Value) z3.Bool {
+	return m.origin.touch(key)
+}
+
+// Lookup encodes value, ok := m[key]. The value is the zero value of V when the key is absent
+func (m SymMap) Lookup(key z3.Value) (z3.Value, z3.Bool) {
+	ok := m.present.Select(key).(z3.Bool)
+	value := ok.IfThenElse(m.values.Select(key), m.origin.sCtx.zeroValue(m.valueSort))
+
+	return value, ok
+}
+
+// NextKey picks the key of the next iteration of `for key := range m` after the visited keys. The key is
+// a fresh constant, so the iterations may visit the keys in any order. The condition holds when the key
+// is present in the map and differs from the visited ones, it includes the bound of the touched key
+func (m SymMap) NextKey(visited []z3.Value) (z3.Value, z3.Bool) {
+	sCtx := m.origin.sCtx
+	var key z3.Value
+	cond := sCtx.Ctx.FromBool(true)
+	if m.mapType.Key().Kind() == reflect.String {
+		// a fresh array may differ from the canonical ones beyond the bound, the key of a fresh string can't
+		lenVal := sCtx.Ctx.FreshConst("key.len", sCtx.Ctx.IntSort()).(z3.Int)
+		bytes := sCtx.Ctx.FreshConst("key.bytes", m.keySort).(z3.Array)
+		s := sCtx.newString(lenVal, bytes, sCtx.TypesCtx.MaxStringLength)
+		key, cond = s.key(), s.wellFormed()
+	} else {
+		key = sCtx.Ctx.FreshConst("key", m.keySort)
+	}
+	cond = cond.And(m.origin.touch(key), m.present.Select(key).(z3.Bool))
+	for _, other := range visited {
+		cond = cond.And(sCtx.Eq(key, other).Not())
+	}
+
+	return key, cond
+}
+
+// Store encodes m[key] = value. The second result holds when the write panics because the map is nil
+func (m SymMap) Store(key z3.Value, value z3.Value) (SymMap, z3.Bool) {
+	oneConst := m.origin.sCtx.Ctx.FromInt(1, m.origin.sCtx.Ctx.IntSort()).(z3.Int)
+	wasPresent := m.present.Select(key).(z3.Bool)
+
+	result := m
+	result.len = wasPresent.IfThenElse(m.len, m.len.Add(oneConst)).(z3.Int)
+	result.present = m.present.Store(key, m.origin.sCtx.Ctx.FromBool(true))
+	result.values = m.values.Store(key, value)
+
+	return result, m.isNil
+}
+
+// Delete encodes delete(m, key). Deleting from a nil map is a no-op
+func (m SymMap) Delete(key z3.Value) SymMap {
+	oneConst := m.origin.sCtx.Ctx.FromInt(1, m.origin.sCtx.Ctx.IntSort()).(z3.Int)
+	wasPresent := m.present.Select(key).(z3.Bool)
+
+	result := m
+	result.len = wasPresent.IfThenElse(m.len.Sub(oneConst), m.len).(z3.Int)
+	result.present = m.present.Store(key, m.origin.sCtx.Ctx.FromBool(false))
+
+	return result
+}
+
+// touch remembers the key and returns the bound of the count of the distinct touched keys present
+// in the initial map. The count grows by the new key, so the bound of the last key implies the others
+func (origin *mapOrigin) touch(key z3.Value) z3.Bool {
+	ctx := origin.sCtx.Ctx
+	zeroConst := ctx.FromInt(0, ctx.IntSort()).(z3.Int)
+	oneConst := ctx.FromInt(1, ctx.IntSort()).(z3.Int)
+
+	isNew := origin.present.Select(key).(z3.Bool)
+	for _, prevKey := range origin.keys {
+		isNew = isNew.And(origin.sCtx.Eq(key, prevKey).Not())
+	}
+	origin.keys = append(origin.keys, key)
+	origin.count = origin.count.Add(isNew.IfThenElse(oneConst, zeroConst).(z3.Int))
+
+	return origin.count.LE(origin.len)
+}
+
+// Decode builds the concrete Go map described by the model. Keys which were never touched
+// by the program are filled with arbitrary distinct keys mapped to zero values
+func (m SymMap) Decode(model *z3.Model) (reflect.Value, error) {
+	isNil, _ := model.Eval(m.isNil, true).(z3.Bool).AsBool()
+	if isNil {
+		return reflect.Zero(m.mapType), nil
+	}
+
+	result := reflect.MakeMap(m.mapType)
+	// the arbitrary keys mustn't be the touched keys which are absent
+	absent := make(map[any]bool)
+	for _, key := range m.origin.keys {
+		goKey, err := m.origin.sCtx.decodeKeyTerm(model, key, m.mapType.Key())
+		if err != nil {
+			return result, err
+		}
+
+		isPresent, _ := model.Eval(m.present.Select(key), true).(z3.Bool).AsBool()
+		if !isPresent {
+			absent[goKey.Interface()] = true
+			continue
+		}
+
+		goValue, err := DecodeValue(model, m.values.Select(key), m.mapType.Elem())
+		if err != nil {
+			return result, err
+		}
+
+		result.SetMapIndex(goKey, goValue)
+	}
+
+	lenVal, err := DecodeValue(model, m.len, reflect.TypeOf(0))
+	if err != nil {
+		return result, err
+	}
+
+	for i := 0; result.Len() < int(lenVal.Int()); i++ {
+		candidate, ok := candidateKey(m.mapType.Key(), i)
+		if !ok {
+			return result, fmt.Errorf("can't find %d distinct keys of type %s", lenVal.Int(), m.mapType.Key())
+		}
+
+		if !result.MapIndex(candidate).IsValid() && !absent[candidate.Interface()] {
+			result.SetMapIndex(candidate, reflect.Zero(m.mapType.Elem()))
+		}
+	}
+
+	return result, nil
+}
+
+func candidateKey(keyType reflect.Type, i int) (reflect.Value, bool) {
+	result := reflect.New(keyType).Elem()
+
+	switch keyType.Kind() {
+	case reflect.Bool:
+		if i > 1 {
+			return result, false
+		}
+		result.SetBool(i == 1)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if result.OverflowInt(int64(i)) {
+			return result, false
+		}
+		result.SetInt(int64(i))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if result.OverflowUint(uint64(i)) {
+			return result, false
+		}
+		result.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		result.SetFloat(float64(i))
+	case reflect.String:
+		result.SetString(strconv.Itoa(i))
+	default:
+		return result, false
+	}
+
+	return result, true
+}
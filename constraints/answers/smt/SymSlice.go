@@ -0,0 +1,165 @@
+package smt
+
+import (
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	"go/types"
+)
+
+// SymSlice models a Go slice of a basic type as its length and an array of elements.
+// Like strings, slice arguments have a static bound on their length. Slices of structs keep
+// their elements in a SymStructArray instead. Go arrays are slices of the constant length
+type SymSlice struct {
+	sCtx *SymContext
+
+	len      z3.Int
+	elements z3.Array
+	structs  *SymStructArray
+	bound    int
+
+	// isNil tells the nil slice from the empty one, nothing turns the nil slice into a non-nil one except assignment
+	isNil bool
+}
+
+// NewSliceArgument creates a slice argument of at most MaxSliceLength elements of the basic type,
+// the elements are bounded the same way as arguments of that type are
+func (sCtx *SymContext) NewSliceArgument(name string, elem *types.Basic) (SymSlice, error) {
+	t, err := BasicType(elem)
+	if err != nil {
+		return SymSlice{}, err
+	}
+
+	sort, err := sCtx.SortOf(t)
+	if err != nil {
+		return SymSlice{}, err
+	}
+
+	lenVal := sCtx.Ctx.IntConst(name + "." + "len")
+	elements := sCtx.Ctx.Const(name+"."+"elements", sCtx.Ctx.ArraySort(sCtx.Ctx.IntSort(), sort)).(z3.Array)
+	result := SymSlice{sCtx: sCtx, len: lenVal, elements: elements, bound: sCtx.TypesCtx.MaxSliceLength}
+
+	sCtx.Solver.Assert(lenVal.GE(sCtx.intConst(0)).And(lenVal.LE(sCtx.intConst(result.bound))))
+	for i := 0; i < result.bound; i++ {
+		sCtx.Solver.Assert(sCtx.scalarBounds(elements.Select(sCtx.intConst(i)), t.Kind()))
+	}
+
+	return result, nil
+}
+
+// NewSliceFromArray makes the slice of integers holding the elements of the array, like []byte(s) and []rune(s) do.
+// The array comes from a string, so the slice is bounded by MaxStringLength
+func (sCtx *SymContext) NewSliceFromArray(arr SymSimpleArray) SymSlice {
+	return SymSlice{sCtx: sCtx, len: arr.len, elements: arr.arr, bound: sCtx.TypesCtx.MaxStringLength}
+}
+
+// Array returns the length and the elements of the slice of integers, like string(b) reads them
+func (s SymSlice) Array() SymSimpleArray {
+	return SymSimpleArray{len: s.len, arr: s.elements}
+}
+
+// NewStructSliceArgument creates a slice argument of at most MaxSliceLength structs
+func (sCtx *SymContext) NewStructSliceArgument(name string, desc *StructDescriptor) SymSlice {
+	structs := sCtx.NewStructArray(name, desc)
+	bound := sCtx.TypesCtx.MaxSliceLength
+	sCtx.Solver.Assert(structs.len.LE(sCtx.intConst(bound)))
+
+	return SymSlice{sCtx: sCtx, len: structs.len, structs: &structs, bound: bound}
+}
+
+// NewArrayArgument creates an argument of the array type [length]elem, its elements are bounded
+// the same way as arguments of the basic type are
+func (sCtx *SymContext) NewArrayArgument(name string, elem *types.Basic, length int) (SymSlice, error) {
+	t, err := BasicType(elem)
+	if err != nil {
+		return SymSlice{}, err
+	}
+
+	sort, err := sCtx.SortOf(t)
+	if err != nil {
+		return SymSlice{}, err
+	}
+
+	elements := sCtx.Ctx.Const(name+"."+"elements", sCtx.Ctx.ArraySort(sCtx.Ctx.IntSort(), sort)).(z3.Array)
+	for i := 0; i < length; i++ {
+		sCtx.Solver.Assert(sCtx.scalarBounds(elements.Select(sCtx.intConst(i)), t.Kind()))
+	}
+
+	return SymSlice{sCtx: sCtx, len: sCtx.intConst(length), elements: elements, bound: length}, nil
+}
+
+// NewZeroArray returns the array [length]elem of zero values
+func (sCtx *SymContext) NewZeroArray(elem *types.Basic, length int) (SymSlice, error) {
+	result, err := sCtx.NewNilSlice(elem)
+	result.len, result.bound, result.isNil = sCtx.intConst(length), length, false
+
+	return result, err
+}
+
+// NewNilSlice returns the nil slice of the basic type
+func (sCtx *SymContext) NewNilSlice(elem *types.Basic) (SymSlice, error) {
+	t, err := BasicType(elem)
+	if err != nil {
+		return SymSlice{}, err
+	}
+
+	sort, err := sCtx.SortOf(t)
+	if err != nil {
+		return SymSlice{}, err
+	}
+
+	elements := sCtx.Ctx.ConstArray(sCtx.Ctx.IntSort(), sCtx.zeroValue(sort))
+	return SymSlice{sCtx: sCtx, len: sCtx.intConst(0), elements: elements, isNil: true}, nil
+}
+
+// NewNilStructSlice returns the nil slice of structs
+func (sCtx *SymContext) NewNilStructSlice(desc *StructDescriptor) SymSlice {
+	sorts := make(map[string]z3.Sort)
+	sCtx.leafSorts(desc, "", sorts)
+
+	arrays := make(map[string]z3.Array, len(sorts))
+	for path, sort := range sorts {
+		if sort.Kind() == z3.KindArray {
+			arrays[path] = sCtx.Ctx.ConstArray(sCtx.Ctx.IntSort(), sCtx.Ctx.ConstArray(sCtx.Ctx.IntSort(), sCtx.intConst(0)))
+			continue
+		}
+		arrays[path] = sCtx.Ctx.ConstArray(sCtx.Ctx.IntSort(), sCtx.zeroValue(sort))
+	}
+
+	structs := SymStructArray{sCtx: sCtx, desc: desc, len: sCtx.intConst(0), arrays: arrays}
+	return SymSlice{sCtx: sCtx, len: structs.len, structs: &structs, isNil: true}
+}
+
+func (s SymSlice) Len() z3.Int {
+	return s.len
+}
+
+func (s SymSlice) IsNil() bool {
+	return s.isNil
+}
+
+// At returns s[i] and the condition of i being out of range
+func (s SymSlice) At(i z3.Int) (SymValue, z3.Bool) {
+	outOfRange := i.LT(s.sCtx.intConst(0)).Or(i.GE(s.len))
+	if s.structs != nil {
+		return s.structs.GetStructure(i), outOfRange
+	}
+	value, _ := ScalarValue(s.elements.Select(i))
+
+	return value, outOfRange
+}
+
+// Store returns the slice with s[i] replaced by the value, the index must be in range
+func (s SymSlice) Store(i z3.Int, value z3.Value) SymSlice {
+	s.elements = s.elements.Store(i, value)
+	return s
+}
+
+// DecodeLen evaluates the length of the slice in the model
+func (s SymSlice) DecodeLen(model *z3.Model) (int, error) {
+	length, isLiteral, ok := model.Eval(s.len, true).(z3.Int).AsInt64()
+	if !isLiteral || !ok {
+		return 0, fmt.Errorf("can't evaluate %s", s.len)
+	}
+
+	return int(length), nil
+}
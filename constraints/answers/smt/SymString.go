@@ -0,0 +1,414 @@
+package smt
+
+import (
+	"github.com/aclements/go-z3/z3"
+	"reflect"
+	"unicode/utf8"
+)
+
+// SymString models a Go string as its byte length and an array of bytes.
+// Z3 sequences aren't available through the bindings, so every string has a static bound on its length
+// and the operations which need quantifiers over positions unroll them up to that bound
+type SymString struct {
+	sCtx *SymContext
+
+	len    z3.Int
+	bytes  z3.Array
+	offset z3.Int
+	bound  int
+}
+
+// RangeStep is one iteration of `for index, r := range s`. The iteration happens only when Active holds
+type RangeStep struct {
+	Active z3.Bool
+	Index  z3.Int
+	Rune   z3.Int
+}
+
+func (sCtx *SymContext) NewStringArgument(name string) SymString {
+	lenVal := sCtx.Ctx.IntConst(name + "." + "len")
+	bytes := sCtx.Ctx.Const(name+"."+"bytes", sCtx.Ctx.ArraySort(sCtx.Ctx.IntSort(), sCtx.Ctx.IntSort())).(z3.Array)
+
+	result := sCtx.newString(lenVal, bytes, sCtx.TypesCtx.MaxStringLength)
+	sCtx.Solver.Assert(result.wellFormed())
+	sCtx.RegisterArgument(name, func(model *z3.Model) (reflect.Value, error) {
+		value, err := result.Decode(model)
+		return reflect.ValueOf(value), err
+	})
+
+	return result
+}
+
+func (sCtx *SymContext) NewStringConst(value string) SymString {
+	bytes := sCtx.Ctx.ConstArray(sCtx.Ctx.IntSort(), sCtx.Ctx.FromInt(0, sCtx.Ctx.IntSort()))
+	for i := 0; i < len(value); i++ {
+		bytes = bytes.Store(sCtx.intConst(i), sCtx.intConst(int(value[i])))
+	}
+
+	return sCtx.newString(sCtx.intConst(len(value)), bytes, len(value))
+}
+
+// StringFromBytes encodes string(b). The second result holds when the bytes fit into MaxStringLength,
+// the caller adds it to the path condition, so the longer slices stay possible on the other paths
+func (sCtx *SymContext) StringFromBytes(b SymSimpleArray) (SymString, z3.Bool) {
+	result := sCtx.newString(b.len, b.arr, sCtx.TypesCtx.MaxStringLength)
+
+	return result, result.wellFormed()
+}
+
+// StringFromRunes encodes string(r), invalid runes are replaced by utf8.RuneError. The second result holds
+// when the runes fit into MaxStringLength, the caller adds it to the path condition as for StringFromBytes
+func (sCtx *SymContext) StringFromRunes(r SymSimpleArray) (SymString, z3.Bool) {
+	bound := sCtx.TypesCtx.MaxStringLength
+	fits := r.len.LE(sCtx.intConst(bound))
+
+	bytes := sCtx.Ctx.FreshConst("runes.bytes", sCtx.Ctx.ArraySort(sCtx.Ctx.IntSort(), sCtx.Ctx.IntSort())).(z3.Array)
+	position := sCtx.intConst(0)
+
+	for k := 0; k < bound; k++ {
+		isActive := sCtx.intConst(k).LT(r.len)
+		encoded, width := sCtx.encodeRune(r.arr.Select(sCtx.intConst(k)).(z3.Int))
+
+		for j, b := range encoded {
+			isWritten := isActive.And(sCtx.intConst(j).LT(width))
+			index := position.Add(sCtx.intConst(j))
+			sCtx.Solver.Assert(isWritten.Implies(bytes.Select(index).(z3.Int).Eq(b)))
+		}
+
+		position = isActive.IfThenElse(position.Add(width), position).(z3.Int)
+	}
+
+	return sCtx.newString(position, bytes, 4*bound), fits
+}
+
+// StringIfThenElse merges two strings into the one which is equal to cons when cond holds and to alt otherwise
+func (sCtx *SymContext) StringIfThenElse(cond z3.Bool, cons SymString, alt SymString) SymString {
+	return SymString{
+		sCtx:   sCtx,
+		len:    cond.IfThenElse(cons.len, alt.len).(z3.Int),
+		bytes:  cond.IfThenElse(cons.bytes, alt.bytes).(z3.Array),
+		offset: cond.IfThenElse(cons.offset, alt.offset).(z3.Int),
+		bound:  max(cons.bound, alt.bound),
+	}
+}
+
+func (sCtx *SymContext) newString(lenVal z3.Int, bytes z3.Array, bound int) SymString {
+	return SymString{sCtx: sCtx, len: lenVal, bytes: bytes, offset: sCtx.intConst(0), bound: bound}
+}
+
+func (sCtx *SymContext) intConst(value int) z3.Int {
+	return sCtx.Ctx.FromInt(int64(value), sCtx.Ctx.IntSort()).(z3.Int)
+}
+
+func (s SymString) Len() z3.Int {
+	return s.len
+}
+
+// At encodes s[i]. The second result holds when the index is out of range
+func (s SymString) At(i z3.Int) (z3.Int, z3.Bool) {
+	outOfRange := i.LT(s.sCtx.intConst(0)).Or(i.GE(s.len))
+	return s.at(i), outOfRange
+}
+
+func (s SymString) at(i z3.Int) z3.Int {
+	return s.bytes.Select(s.offset.Add(i)).(z3.Int)
+}
+
+// Slice encodes s[low:high]. The second result holds when the bounds are out of range
+func (s SymString) Slice(low z3.Int, high z3.Int) (SymString, z3.Bool) {
+	zeroConst := s.sCtx.intConst(0)
+	outOfRange := zeroConst.LE(low).And(low.LE(high), high.LE(s.len)).Not()
+
+	result := s
+	result.offset = s.offset.Add(low)
+	result.len = high.Sub(low)
+
+	return result, outOfRange
+}
+
+// Concat encodes s + other
+func (s SymString) Concat(other SymString) SymString {
+	sCtx := s.sCtx
+	bytes := sCtx.Ctx.FreshConst("concat.bytes", sCtx.Ctx.ArraySort(sCtx.Ctx.IntSort(), sCtx.Ctx.IntSort())).(z3.Array)
+
+	for i := 0; i < s.bound; i++ {
+		index := sCtx.intConst(i)
+		sCtx.Solver.Assert(index.LT(s.len).Implies(bytes.Select(index).(z3.Int).Eq(s.at(index))))
+	}
+
+	for i := 0; i < other.bound; i++ {
+		index := sCtx.intConst(i)
+		sCtx.Solver.Assert(index.LT(other.len).Implies(bytes.Select(s.len.Add(index)).(z3.Int).Eq(other.at(index))))
+	}
+
+	return sCtx.newString(s.len.Add(other.len), bytes, s.bound+other.bound)
+}
+
+// Eq encodes s == other
+func (s SymString) Eq(other SymString) z3.Bool {
+	result := s.len.Eq(other.len)
+	for i := 0; i < min(s.bound, other.bound); i++ {
+		index := s.sCtx.intConst(i)
+		result = result.And(index.LT(s.len).Implies(s.at(index).Eq(other.at(index))))
+	}
+
+	return result
+}
+
+// Compare encodes strings.Compare(s, other), the result is -1, 0 or 1
+func (s SymString) Compare(other SymString) z3.Int {
+	result := s.sCtx.intConst(0)
+	minusOneConst := s.sCtx.intConst(-1)
+	oneConst := s.sCtx.intConst(1)
+
+	for i := max(s.bound, other.bound) - 1; i >= 0; i-- {
+		index := s.sCtx.intConst(i)
+		sEnded := index.GE(s.len)
+		otherEnded := index.GE(other.len)
+
+		byteResult := s.at(index).LT(other.at(index)).IfThenElse(minusOneConst,
+			s.at(index).GT(other.at(index)).IfThenElse(oneConst, result))
+		result = sEnded.And(otherEnded).IfThenElse(s.sCtx.intConst(0),
+			sEnded.IfThenElse(minusOneConst,
+				otherEnded.IfThenElse(oneConst, byteResult))).(z3.Int)
+	}
+
+	return result
+}
+
+// LT encodes s < other
+func (s SymString) LT(other SymString) z3.Bool {
+	return s.Compare(other).Eq(s.sCtx.intConst(-1))
+}
+
+// HasPrefix encodes strings.HasPrefix(s, prefix)
+func (s SymString) HasPrefix(prefix SymString) z3.Bool {
+	return s.matchesAt(prefix, s.sCtx.intConst(0))
+}
+
+// Index encodes strings.Index(s, substr)
+func (s SymString) Index(substr SymString) z3.Int {
+	result := s.sCtx.intConst(-1)
+	for offset := s.bound; offset >= 0; offset-- {
+		offsetConst := s.sCtx.intConst(offset)
+		result = s.matchesAt(substr, offsetConst).IfThenElse(offsetConst, result).(z3.Int)
+	}
+
+	return result
+}
+
+// Contains encodes strings.Contains(s, substr)
+func (s SymString) Contains(substr SymString) z3.Bool {
+	return s.Index(substr).GE(s.sCtx.intConst(0))
+}
+
+func (s SymString) matchesAt(substr SymString, offset z3.Int) z3.Bool {
+	result := offset.Add(substr.len).LE(s.len)
+	for i := 0; i < substr.bound; i++ {
+		index := s.sCtx.intConst(i)
+		result = result.And(index.LT(substr.len).Implies(s.at(offset.Add(index)).Eq(substr.at(index))))
+	}
+
+	return result
+}
+
+// ToBytes encodes []byte(s)
+func (s SymString) ToBytes() SymSimpleArray {
+	sCtx := s.sCtx
+	arr := sCtx.Ctx.FreshConst("bytes", sCtx.Ctx.ArraySort(sCtx.Ctx.IntSort(), sCtx.Ctx.IntSort())).(z3.Array)
+
+	for i := 0; i < s.bound; i++ {
+		index := sCtx.intConst(i)
+		sCtx.Solver.Assert(index.LT(s.len).Implies(arr.Select(index).(z3.Int).Eq(s.at(index))))
+	}
+
+	return SymSimpleArray{len: s.len, arr: arr}
+}
+
+// ToRunes encodes []rune(s)
+func (s SymString) ToRunes() SymSimpleArray {
+	sCtx := s.sCtx
+	arr := sCtx.Ctx.FreshConst("runes", sCtx.Ctx.ArraySort(sCtx.Ctx.IntSort(), sCtx.Ctx.IntSort())).(z3.Array)
+	count := sCtx.intConst(0)
+
+	for k, step := range s.Range() {
+		sCtx.Solver.Assert(step.Active.Implies(arr.Select(sCtx.intConst(k)).(z3.Int).Eq(step.Rune)))
+		count = step.Active.IfThenElse(count.Add(sCtx.intConst(1)), count).(z3.Int)
+	}
+
+	return SymSimpleArray{len: count, arr: arr}
+}
+
+// Range unrolls `for index, r := range s`, decoding UTF-8 the same way as utf8.DecodeRuneInString
+func (s SymString) Range() []RangeStep {
+	steps := make([]RangeStep, 0, s.bound)
+	position := s.sCtx.intConst(0)
+
+	for k := 0; k < s.bound; k++ {
+		r, width := s.DecodeRune(position)
+		active := position.LT(s.len)
+		steps = append(steps, RangeStep{Active: active, Index: position, Rune: r})
+		position = position.Add(width)
+	}
+
+	return steps
+}
+
+// DecodeRune encodes utf8.DecodeRuneInString(s[position:]), the rune and its width in bytes
+func (s SymString) DecodeRune(position z3.Int) (z3.Int, z3.Int) {
+	sCtx := s.sCtx
+	b := make([]z3.Int, 4)
+	for j := range b {
+		b[j] = s.at(position.Add(sCtx.intConst(j)))
+	}
+	remaining := s.len.Sub(position)
+
+	inRange := func(value z3.Int, low int, high int) z3.Bool {
+		return sCtx.intConst(low).LE(value).And(value.LE(sCtx.intConst(high)))
+	}
+	continuation := func(value z3.Int) z3.Bool {
+		return inRange(value, 0x80, 0xBF)
+	}
+	payload := func(value z3.Int, lead int, shift int) z3.Int {
+		return value.Sub(sCtx.intConst(lead)).Mul(sCtx.intConst(1 << shift))
+	}
+
+	isTwoBytes := inRange(b[0], 0xC2, 0xDF).And(
+		remaining.GE(sCtx.intConst(2)),
+		continuation(b[1]))
+	twoBytesRune := payload(b[0], 0xC0, 6).Add(payload(b[1], 0x80, 0))
+
+	isThreeBytes := remaining.GE(sCtx.intConst(3)).And(
+		continuation(b[2]),
+		b[0].Eq(sCtx.intConst(0xE0)).And(inRange(b[1], 0xA0, 0xBF)).Or(
+			inRange(b[0], 0xE1, 0xEC).And(continuation(b[1])),
+			b[0].Eq(sCtx.intConst(0xED)).And(inRange(b[1], 0x80, 0x9F)),
+			inRange(b[0], 0xEE, 0xEF).And(continuation(b[1]))))
+	threeBytesRune := payload(b[0], 0xE0, 12).Add(payload(b[1], 0x80, 6), payload(b[2], 0x80, 0))
+
+	isFourBytes := remaining.GE(sCtx.intConst(4)).And(
+		continuation(b[2]),
+		continuation(b[3]),
+		b[0].Eq(sCtx.intConst(0xF0)).And(inRange(b[1], 0x90, 0xBF)).Or(
+			inRange(b[0], 0xF1, 0xF3).And(continuation(b[1])),
+			b[0].Eq(sCtx.intConst(0xF4)).And(inRange(b[1], 0x80, 0x8F))))
+	fourBytesRune := payload(b[0], 0xF0, 18).Add(payload(b[1], 0x80, 12), payload(b[2], 0x80, 6), payload(b[3], 0x80, 0))
+
+	r := b[0].LT(sCtx.intConst(utf8.RuneSelf)).IfThenElse(b[0],
+		isTwoBytes.IfThenElse(twoBytesRune,
+			isThreeBytes.IfThenElse(threeBytesRune,
+				isFourBytes.IfThenElse(fourBytesRune, sCtx.intConst(utf8.RuneError))))).(z3.Int)
+	width := isTwoBytes.IfThenElse(sCtx.intConst(2),
+		isThreeBytes.IfThenElse(sCtx.intConst(3),
+			isFourBytes.IfThenElse(sCtx.intConst(4), sCtx.intConst(1)))).(z3.Int)
+
+	return r, width
+}
+
+// encodeRune returns the UTF-8 bytes of r and their count, the same way as utf8.EncodeRune
+func (sCtx *SymContext) encodeRune(r z3.Int) ([]z3.Int, z3.Int) {
+	inRange := func(low int, high int) z3.Bool {
+		return sCtx.intConst(low).LE(r).And(r.LE(sCtx.intConst(high)))
+	}
+	part := func(shift int, mask int, prefix int) z3.Int {
+		return r.Div(sCtx.intConst(1 << shift)).Mod(sCtx.intConst(mask + 1)).Add(sCtx.intConst(prefix))
+	}
+
+	isValid := inRange(0, utf8.MaxRune).And(inRange(0xD800, 0xDFFF).Not())
+	r = isValid.IfThenElse(r, sCtx.intConst(utf8.RuneError)).(z3.Int)
+
+	isOneByte := inRange(0, 0x7F)
+	isTwoBytes := inRange(0x80, 0x7FF)
+	isThreeBytes := inRange(0x800, 0xFFFF)
+
+	oneByte := []z3.Int{r, sCtx.intConst(0), sCtx.intConst(0), sCtx.intConst(0)}
+	twoBytes := []z3.Int{part(6, 0x1F, 0xC0), part(0, 0x3F, 0x80), sCtx.intConst(0), sCtx.intConst(0)}
+	threeBytes := []z3.Int{part(12, 0x0F, 0xE0), part(6, 0x3F, 0x80), part(0, 0x3F, 0x80), sCtx.intConst(0)}
+	fourBytes := []z3.Int{part(18, 0x07, 0xF0), part(12, 0x3F, 0x80), part(6, 0x3F, 0x80), part(0, 0x3F, 0x80)}
+
+	result := make([]z3.Int, 4)
+	for j := range result {
+		result[j] = isOneByte.IfThenElse(oneByte[j],
+			isTwoBytes.IfThenElse(twoBytes[j],
+				isThreeBytes.IfThenElse(threeBytes[j], fourBytes[j]))).(z3.Int)
+	}
+
+	width := isOneByte.IfThenElse(sCtx.intConst(1),
+		isTwoBytes.IfThenElse(sCtx.intConst(2),
+			isThreeBytes.IfThenElse(sCtx.intConst(3), sCtx.intConst(4)))).(z3.Int)
+
+	return result, width
+}
+
+// wellFormed bounds the length of the string and keeps every byte in [0, 255]
+func (s SymString) wellFormed() z3.Bool {
+	zeroConst := s.sCtx.intConst(0)
+	result := zeroConst.LE(s.len).And(s.len.LE(s.sCtx.intConst(s.bound)))
+
+	for i := 0; i < s.bound; i++ {
+		index := s.sCtx.intConst(i)
+		b := s.at(index)
+		result = result.And(index.LT(s.len).Implies(zeroConst.LE(b).And(b.LE(s.sCtx.intConst(0xFF)))))
+	}
+
+	return result
+}
+
+// key is the canonical array of the string, the keys of maps are encoded by it: the length is at -1,
+// the bytes are at their positions and the rest is zero, so equal strings have equal keys
+func (s SymString) key() z3.Array {
+	sCtx := s.sCtx
+	zeroConst := sCtx.intConst(0)
+
+	result := sCtx.Ctx.ConstArray(sCtx.Ctx.IntSort(), zeroConst).Store(sCtx.intConst(-1), s.len)
+	for i := 0; i < s.bound; i++ {
+		index := sCtx.intConst(i)
+		result = result.Store(index, index.LT(s.len).IfThenElse(s.at(index), zeroConst))
+	}
+
+	return result
+}
+
+// stringOfKey is the string encoded by the canonical array
+func (sCtx *SymContext) stringOfKey(key z3.Array) SymString {
+	return sCtx.newString(key.Select(sCtx.intConst(-1)).(z3.Int), key, sCtx.TypesCtx.MaxStringLength)
+}
+
+// keyTerm is the term the arrays of maps and the uninterpreted functions take the value by,
+// strings are taken by their canonical arrays
+func keyTerm(value SymValue) (z3.Value, error) {
+	if s, ok := value.(SymString); ok {
+		return s.key(), nil
+	}
+
+	return ScalarTerm(value)
+}
+
+// decodeKeyTerm builds the value of type t taken by the term, it's the inverse of keyTerm
+func (sCtx *SymContext) decodeKeyTerm(model *z3.Model, term z3.Value, t reflect.Type) (reflect.Value, error) {
+	if t.Kind() != reflect.String {
+		return DecodeValue(model, term, t)
+	}
+
+	value, err := sCtx.stringOfKey(term.(z3.Array)).Decode(model)
+	return reflect.ValueOf(value).Convert(t), err
+}
+
+func (s SymString) Decode(model *z3.Model) (string, error) {
+	lenVal, err := DecodeValue(model, s.len, reflect.TypeOf(0))
+	if err != nil {
+		return "", err
+	}
+
+	result := make([]byte, lenVal.Int())
+	for i := range result {
+		b, err := DecodeValue(model, s.at(s.sCtx.intConst(i)), reflect.TypeOf(byte(0)))
+		if err != nil {
+			return "", err
+		}
+
+		result[i] = byte(b.Uint())
+	}
+
+	return string(result), nil
+}
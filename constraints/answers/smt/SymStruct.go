@@ -0,0 +1,549 @@
+package smt
+
+import (
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	"go/types"
+	"math/big"
+	"reflect"
+	"unsafe"
+)
+
+type fieldKind int
+
+const (
+	scalarField fieldKind = iota
+	stringField
+	complexField
+	structField
+	refField
+)
+
+// StructDescriptor is the layout of a Go struct. It's derived from the Go type,
+// nested structs get descriptors of their own
+type StructDescriptor struct {
+	Name   string
+	Fields []FieldDescriptor
+
+	// goType is nil when the descriptor is derived from go/types, such structs can't be decoded
+	goType reflect.Type
+}
+
+type FieldDescriptor struct {
+	Name     string
+	TypeName string
+	Embedded bool
+	Struct   *StructDescriptor
+
+	kind   fieldKind
+	basic  reflect.Kind
+	sort   z3.Sort
+	goType reflect.Type
+}
+
+// SymStruct is a symbolic value of a struct type. Like Go structs it has value semantics:
+// WithField returns an updated copy and never changes the original
+type SymStruct struct {
+	sCtx   *SymContext
+	desc   *StructDescriptor
+	fields []SymValue
+}
+
+// DescribeStruct derives the layout of the struct type t
+func (sCtx *SymContext) DescribeStruct(t reflect.Type) (*StructDescriptor, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%s is not a struct type", t)
+	}
+
+	desc := &StructDescriptor{Name: t.Name(), goType: t}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldDesc := FieldDescriptor{
+			Name:     field.Name,
+			TypeName: field.Type.String(),
+			Embedded: field.Anonymous,
+			basic:    field.Type.Kind(),
+			goType:   field.Type,
+		}
+
+		switch field.Type.Kind() {
+		case reflect.String:
+			fieldDesc.kind = stringField
+		case reflect.Complex128:
+			fieldDesc.kind = complexField
+		case reflect.Pointer:
+			fieldDesc.kind = refField
+		case reflect.Struct:
+			nested, err := sCtx.DescribeStruct(field.Type)
+			if err != nil {
+				return nil, err
+			}
+			fieldDesc.kind = structField
+			fieldDesc.Struct = nested
+		default:
+			sort, err := sCtx.SortOf(field.Type)
+			if err != nil {
+				return nil, fmt.Errorf("field %s.%s: %w", t.Name(), field.Name, err)
+			}
+			fieldDesc.kind = scalarField
+			fieldDesc.sort = sort
+		}
+
+		desc.Fields = append(desc.Fields, fieldDesc)
+	}
+
+	return desc, nil
+}
+
+// DescribeStructType derives the layout of the struct from its go/types description
+func (sCtx *SymContext) DescribeStructType(name string, t *types.Struct) (*StructDescriptor, error) {
+	desc := &StructDescriptor{Name: name}
+	for i := 0; i < t.NumFields(); i++ {
+		field := t.Field(i)
+		fieldType := field.Type()
+		fieldDesc := FieldDescriptor{Name: field.Name(), TypeName: fieldType.String(), Embedded: field.Embedded()}
+
+		if _, ok := fieldType.Underlying().(*types.Pointer); ok {
+			fieldDesc.kind = refField
+			desc.Fields = append(desc.Fields, fieldDesc)
+			continue
+		}
+
+		if nested, ok := fieldType.Underlying().(*types.Struct); ok {
+			nestedName := fieldType.String()
+			if named, ok := fieldType.(*types.Named); ok {
+				nestedName = named.Obj().Name()
+			}
+
+			nestedDesc, err := sCtx.DescribeStructType(nestedName, nested)
+			if err != nil {
+				return nil, err
+			}
+			fieldDesc.kind = structField
+			fieldDesc.Struct = nestedDesc
+			desc.Fields = append(desc.Fields, fieldDesc)
+			continue
+		}
+
+		basic, ok := fieldType.Underlying().(*types.Basic)
+		if !ok {
+			return nil, fmt.Errorf("field %s.%s: type %s has no symbolic encoding", name, field.Name(), fieldType)
+		}
+
+		fieldDesc.basic = basicKinds[basic.Kind()]
+		switch fieldDesc.basic {
+		case reflect.String:
+			fieldDesc.kind = stringField
+		case reflect.Complex128:
+			fieldDesc.kind = complexField
+		case reflect.Invalid:
+			return nil, fmt.Errorf("field %s.%s: type %s has no symbolic encoding", name, field.Name(), fieldType)
+		default:
+			sort, err := sCtx.SortOf(kindTypes[fieldDesc.basic])
+			if err != nil {
+				return nil, err
+			}
+			fieldDesc.kind = scalarField
+			fieldDesc.sort = sort
+		}
+
+		desc.Fields = append(desc.Fields, fieldDesc)
+	}
+
+	return desc, nil
+}
+
+var basicKinds = map[types.BasicKind]reflect.Kind{
+	types.Bool:       reflect.Bool,
+	types.Int:        reflect.Int,
+	types.Int8:       reflect.Int8,
+	types.Int16:      reflect.Int16,
+	types.Int32:      reflect.Int32,
+	types.Int64:      reflect.Int64,
+	types.Uint:       reflect.Uint,
+	types.Uint8:      reflect.Uint8,
+	types.Uint16:     reflect.Uint16,
+	types.Uint32:     reflect.Uint32,
+	types.Uint64:     reflect.Uint64,
+	types.Uintptr:    reflect.Uintptr,
+	types.Float32:    reflect.Float32,
+	types.Float64:    reflect.Float64,
+	types.Complex128: reflect.Complex128,
+	types.String:     reflect.String,
+}
+
+var kindTypes = map[reflect.Kind]reflect.Type{
+	reflect.Bool:    reflect.TypeOf(false),
+	reflect.Int:     reflect.TypeOf(int(0)),
+	reflect.Int8:    reflect.TypeOf(int8(0)),
+	reflect.Int16:   reflect.TypeOf(int16(0)),
+	reflect.Int32:   reflect.TypeOf(int32(0)),
+	reflect.Int64:   reflect.TypeOf(int64(0)),
+	reflect.Uint:    reflect.TypeOf(uint(0)),
+	reflect.Uint8:   reflect.TypeOf(uint8(0)),
+	reflect.Uint16:  reflect.TypeOf(uint16(0)),
+	reflect.Uint32:  reflect.TypeOf(uint32(0)),
+	reflect.Uint64:  reflect.TypeOf(uint64(0)),
+	reflect.Uintptr: reflect.TypeOf(uintptr(0)),
+	reflect.Float32: reflect.TypeOf(float32(0)),
+	reflect.Float64: reflect.TypeOf(float64(0)),
+}
+
+func (sCtx *SymContext) NewStructArgument(name string, desc *StructDescriptor) SymStruct {
+	result := sCtx.buildStruct(desc, name, func(path string, sort z3.Sort) z3.Value {
+		return sCtx.Ctx.Const(path, sort)
+	})
+	sCtx.RegisterArgument(name, result.Decode)
+
+	return result
+}
+
+// NewZeroStruct returns the zero value of the struct
+func (sCtx *SymContext) NewZeroStruct(desc *StructDescriptor) SymStruct {
+	result := SymStruct{sCtx: sCtx, desc: desc, fields: make([]SymValue, len(desc.Fields))}
+	for i, field := range desc.Fields {
+		switch field.kind {
+		case scalarField:
+			result.fields[i], _ = ScalarValue(sCtx.zeroValue(field.sort))
+		case refField:
+			result.fields[i] = RefValue(sCtx.intConst(0))
+		case stringField:
+			result.fields[i] = sCtx.NewStringConst("")
+		case complexField:
+			zeroConst := sCtx.Ctx.FloatZero(sCtx.Ctx.FloatSort(11, 53), false)
+			result.fields[i] = SymComplex{re: zeroConst, im: zeroConst}
+		case structField:
+			result.fields[i] = sCtx.NewZeroStruct(field.Struct)
+		}
+	}
+
+	return result
+}
+
+// buildStruct creates the struct from the leaf values provided by leaf, which gets dotted paths of the leaves.
+// Strings and complex numbers are split into several leaves
+func (sCtx *SymContext) buildStruct(desc *StructDescriptor, prefix string, leaf func(path string, sort z3.Sort) z3.Value) SymStruct {
+	result := SymStruct{sCtx: sCtx, desc: desc, fields: make([]SymValue, len(desc.Fields))}
+	for i, field := range desc.Fields {
+		path := field.Name
+		if prefix != "" {
+			path = prefix + "." + field.Name
+		}
+
+		switch field.kind {
+		case scalarField:
+			value := leaf(path, field.sort)
+			sCtx.Solver.Assert(sCtx.scalarBounds(value, field.basic))
+			result.fields[i], _ = ScalarValue(value)
+		case refField:
+			address := leaf(path, sCtx.Ctx.IntSort()).(z3.Int)
+			sCtx.Solver.Assert(address.GE(sCtx.intConst(0)))
+			result.fields[i] = RefValue(address)
+		case stringField:
+			lenVal := leaf(path+".len", sCtx.Ctx.IntSort()).(z3.Int)
+			bytes := leaf(path+".bytes", sCtx.Ctx.ArraySort(sCtx.Ctx.IntSort(), sCtx.Ctx.IntSort())).(z3.Array)
+			value := sCtx.newString(lenVal, bytes, sCtx.TypesCtx.MaxStringLength)
+			sCtx.Solver.Assert(value.wellFormed())
+			result.fields[i] = value
+		case complexField:
+			floatSort := sCtx.Ctx.FloatSort(11, 53)
+			result.fields[i] = SymComplex{re: leaf(path+".real", floatSort).(z3.Float), im: leaf(path+".imag", floatSort).(z3.Float)}
+		case structField:
+			result.fields[i] = sCtx.buildStruct(field.Struct, path, leaf)
+		}
+	}
+
+	return result
+}
+
+// leafSorts lists the sorts of all the leaves of the struct the same way as buildStruct names them
+func (sCtx *SymContext) leafSorts(desc *StructDescriptor, prefix string, result map[string]z3.Sort) {
+	for _, field := range desc.Fields {
+		path := field.Name
+		if prefix != "" {
+			path = prefix + "." + field.Name
+		}
+
+		switch field.kind {
+		case scalarField:
+			result[path] = field.sort
+		case refField:
+			result[path] = sCtx.Ctx.IntSort()
+		case stringField:
+			result[path+".len"] = sCtx.Ctx.IntSort()
+			result[path+".bytes"] = sCtx.Ctx.ArraySort(sCtx.Ctx.IntSort(), sCtx.Ctx.IntSort())
+		case complexField:
+			result[path+".real"] = sCtx.Ctx.FloatSort(11, 53)
+			result[path+".imag"] = sCtx.Ctx.FloatSort(11, 53)
+		case structField:
+			sCtx.leafSorts(field.Struct, path, result)
+		}
+	}
+}
+
+// scalarBounds bounds int and uint by the types context and the sized integers by the ranges of their types
+func (sCtx *SymContext) scalarBounds(value z3.Value, kind reflect.Kind) z3.Bool {
+	switch kind {
+	case reflect.Int:
+		minValueConst := sCtx.Ctx.FromInt(sCtx.TypesCtx.MinInt, sCtx.Ctx.IntSort()).(z3.Int)
+		maxValueConst := sCtx.Ctx.FromInt(sCtx.TypesCtx.MaxInt, sCtx.Ctx.IntSort()).(z3.Int)
+		return value.(z3.Int).GT(minValueConst).And(value.(z3.Int).LT(maxValueConst))
+	case reflect.Uint, reflect.Uintptr:
+		maxValueConst := sCtx.Ctx.FromInt(sCtx.TypesCtx.MaxInt, sCtx.Ctx.IntSort()).(z3.Int)
+		return value.(z3.Int).GE(sCtx.intConst(0)).And(value.(z3.Int).LT(maxValueConst))
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		unsigned := kind >= reflect.Uint8 && kind <= reflect.Uint64
+		minValue, maxValue := IntRange(kindTypes[kind].Bits(), unsigned)
+		minValueConst := sCtx.Ctx.FromBigInt(minValue, sCtx.Ctx.IntSort()).(z3.Int)
+		maxValueConst := sCtx.Ctx.FromBigInt(maxValue, sCtx.Ctx.IntSort()).(z3.Int)
+		return value.(z3.Int).GE(minValueConst).And(value.(z3.Int).LE(maxValueConst))
+	case reflect.Float64:
+		minValueConst := sCtx.Ctx.FromFloat64(sCtx.TypesCtx.MinFloat64, sCtx.Ctx.FloatSort(11, 53))
+		maxValueConst := sCtx.Ctx.FromFloat64(sCtx.TypesCtx.MaxFloat64, sCtx.Ctx.FloatSort(11, 53))
+		return value.(z3.Float).GT(minValueConst).And(value.(z3.Float).LT(maxValueConst))
+	default:
+		return sCtx.Ctx.FromBool(true)
+	}
+}
+
+// IntRange is the smallest and the largest value of the integer of the size in bits
+func IntRange(size int, unsigned bool) (*big.Int, *big.Int) {
+	if unsigned {
+		maxValue := new(big.Int).Lsh(big.NewInt(1), uint(size))
+		return big.NewInt(0), maxValue.Sub(maxValue, big.NewInt(1))
+	}
+
+	minValue := new(big.Int).Lsh(big.NewInt(-1), uint(size-1))
+	maxValue := new(big.Int).Lsh(big.NewInt(1), uint(size-1))
+	return minValue, maxValue.Sub(maxValue, big.NewInt(1))
+}
+
+func (s SymStruct) Descriptor() *StructDescriptor {
+	return s.desc
+}
+
+// Field returns the value of the field. Fields of embedded structs are promoted the same way as in Go
+func (s SymStruct) Field(name string) (SymValue, error) {
+	path, err := s.desc.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var result SymValue = s
+	for _, index := range path {
+		result = result.(SymStruct).fields[index]
+	}
+
+	return result, nil
+}
+
+// WithField returns a copy of the struct with the field set to value.
+// It fails when there is no such field or the value doesn't fit its type
+func (s SymStruct) WithField(name string, value SymValue) (SymStruct, error) {
+	path, err := s.desc.lookup(name)
+	if err != nil {
+		return s, err
+	}
+
+	fieldDesc := s.desc.fieldAt(path)
+	if !fieldDesc.accepts(value) {
+		return s, fmt.Errorf("can't assign %T to field %s.%s of type %s", value, s.desc.Name, name, fieldDesc.TypeName)
+	}
+
+	return s.withPath(path, value), nil
+}
+
+func (s SymStruct) withPath(path []int, value SymValue) SymStruct {
+	result := s
+	result.fields = append([]SymValue(nil), s.fields...)
+
+	if len(path) == 1 {
+		result.fields[path[0]] = value
+	} else {
+		result.fields[path[0]] = s.fields[path[0]].(SymStruct).withPath(path[1:], value)
+	}
+
+	return result
+}
+
+func (desc *StructDescriptor) fieldAt(path []int) FieldDescriptor {
+	current := desc
+	for _, index := range path[:len(path)-1] {
+		current = current.Fields[index].Struct
+	}
+
+	return current.Fields[path[len(path)-1]]
+}
+
+// accepts checks that the value may be stored in the field
+func (field FieldDescriptor) accepts(value SymValue) bool {
+	switch field.kind {
+	case scalarField:
+		switch value := value.(type) {
+		case SymBool:
+			return field.sort.Kind() == z3.KindBool
+		case SymInt:
+			return field.sort.Kind() == z3.KindInt
+		case SymFloat:
+			ebits, sbits := value.Float().Sort().FloatSize()
+			fieldEbits, fieldSbits := field.sort.FloatSize()
+			return field.sort.Kind() == z3.KindFloatingPoint && ebits == fieldEbits && sbits == fieldSbits
+		}
+		return false
+	case refField:
+		_, ok := value.(SymRef)
+		return ok
+	case stringField:
+		_, ok := value.(SymString)
+		return ok
+	case complexField:
+		_, ok := value.(SymComplex)
+		return ok
+	case structField:
+		structure, ok := value.(SymStruct)
+		return ok && structure.desc.sameLayout(field.Struct)
+	default:
+		return false
+	}
+}
+
+// sameLayout tells that the descriptors have the same fields. The descriptors of nested structs are derived
+// anew with the struct holding them, so one type may have several descriptors
+func (desc *StructDescriptor) sameLayout(other *StructDescriptor) bool {
+	if desc == other {
+		return true
+	}
+	if len(desc.Fields) != len(other.Fields) {
+		return false
+	}
+
+	for i, field := range desc.Fields {
+		otherField := other.Fields[i]
+		if field.Name != otherField.Name || field.kind != otherField.kind || field.basic != otherField.basic {
+			return false
+		}
+		if field.kind == structField && !field.Struct.sameLayout(otherField.Struct) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// lookup finds the indices leading to the field, descending into embedded structs
+// breadth-first the same way as Go selectors do
+func (desc *StructDescriptor) lookup(name string) ([]int, error) {
+	level := [][]int{nil}
+
+	for len(level) > 0 {
+		var found []int
+		var nextLevel [][]int
+
+		for _, prefix := range level {
+			current := desc
+			for _, index := range prefix {
+				current = current.Fields[index].Struct
+			}
+
+			for i, field := range current.Fields {
+				path := append(append([]int(nil), prefix...), i)
+				if field.Name == name {
+					if found != nil {
+						return nil, fmt.Errorf("ambiguous selector %s.%s", desc.Name, name)
+					}
+					found = path
+				}
+
+				if field.Embedded && field.kind == structField {
+					nextLevel = append(nextLevel, path)
+				}
+			}
+		}
+
+		if found != nil {
+			return found, nil
+		}
+		level = nextLevel
+	}
+
+	return nil, fmt.Errorf("%s has no field %s", desc.Name, name)
+}
+
+// Eq encodes s == other
+func (s SymStruct) Eq(other SymStruct) z3.Bool {
+	result := s.sCtx.Ctx.FromBool(true)
+	for i, field := range s.fields {
+		switch field := field.(type) {
+		case SymBool:
+			result = result.And(field.Bool().Eq(other.fields[i].(SymBool).Bool()))
+		case SymInt:
+			result = result.And(field.Int().Eq(other.fields[i].(SymInt).Int()))
+		case SymFloat:
+			result = result.And(field.Float().IEEEEq(other.fields[i].(SymFloat).Float()))
+		case SymRef:
+			result = result.And(field.Address().Eq(other.fields[i].(SymRef).Address()))
+		case SymString:
+			result = result.And(field.Eq(other.fields[i].(SymString)))
+		case SymComplex:
+			otherField := other.fields[i].(SymComplex)
+			result = result.And(field.re.IEEEEq(otherField.re), field.im.IEEEEq(otherField.im))
+		case SymStruct:
+			result = result.And(field.Eq(other.fields[i].(SymStruct)))
+		}
+	}
+
+	return result
+}
+
+// Decode builds the concrete Go struct described by the model.
+// Non-nil pointers are decoded as pointers to zero values, since the heap isn't a part of the struct
+func (s SymStruct) Decode(model *z3.Model) (reflect.Value, error) {
+	if s.desc.goType == nil {
+		return reflect.Value{}, fmt.Errorf("struct %s has no Go type to decode into", s.desc.Name)
+	}
+
+	result := reflect.New(s.desc.goType).Elem()
+	for i, field := range s.fields {
+		fieldType := s.desc.Fields[i].goType
+
+		var value reflect.Value
+		var err error
+
+		switch field := field.(type) {
+		case SymBool:
+			value, err = DecodeValue(model, field.Bool(), fieldType)
+		case SymInt:
+			value, err = DecodeValue(model, field.Int(), fieldType)
+		case SymFloat:
+			value, err = DecodeValue(model, field.Float(), fieldType)
+		case SymRef:
+			var isNil reflect.Value
+			isNil, err = DecodeValue(model, field.IsNil(), reflect.TypeOf(false))
+			value = reflect.Zero(fieldType)
+			if err == nil && !isNil.Bool() {
+				value = reflect.New(fieldType.Elem())
+			}
+		case SymString:
+			var str string
+			str, err = field.Decode(model)
+			value = reflect.ValueOf(str)
+		case SymComplex:
+			var c complex128
+			c, err = field.Decode(model)
+			value = reflect.ValueOf(c)
+		case SymStruct:
+			value, err = field.Decode(model)
+		}
+		if err != nil {
+			return result, fmt.Errorf("field %s: %w", s.desc.Fields[i].Name, err)
+		}
+
+		// unexported fields can't be set via reflection directly
+		resultField := result.Field(i)
+		resultField = reflect.NewAt(resultField.Type(), unsafe.Pointer(resultField.UnsafeAddr())).Elem()
+		resultField.Set(value.Convert(fieldType))
+	}
+
+	return result, nil
+}
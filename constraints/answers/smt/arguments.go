@@ -1,7 +1,10 @@
 package smt
 
 import (
+	"fmt"
 	"github.com/aclements/go-z3/z3"
+	"go/types"
+	"reflect"
 )
 
 func (sCtx *SymContext) NewIntArgument(name string) z3.Int {
@@ -13,6 +16,9 @@ func (sCtx *SymContext) NewIntArgument(name string) z3.Int {
 
 	// int max and int min are intentionally excluded
 	sCtx.Solver.Assert(result.GT(minValueConst).And(result.LT(maxValueConst)))
+	sCtx.RegisterArgument(name, func(model *z3.Model) (reflect.Value, error) {
+		return DecodeValue(model, result, reflect.TypeOf(0))
+	})
 
 	return result
 }
@@ -27,6 +33,79 @@ func (sCtx *SymContext) NewFloat64Argument(name string) z3.Float {
 
 	// int max and int min are intentionally excluded
 	sCtx.Solver.Assert(result.GT(minValueConst).And(result.LT(maxValueConst)))
+	sCtx.RegisterArgument(name, func(model *z3.Model) (reflect.Value, error) {
+		return DecodeValue(model, result, reflect.TypeOf(0.0))
+	})
 
 	return result
 }
+
+// NewBasicArgument creates an argument of the basic type. Integers of all sizes are bounded the same way
+// as fields of structs are, floats and strings are created by NewFloat64Argument and NewStringArgument
+func (sCtx *SymContext) NewBasicArgument(name string, basic *types.Basic) (SymValue, error) {
+	t, err := BasicType(basic)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t.Kind() {
+	case reflect.Float64:
+		return FloatValue(sCtx.NewFloat64Argument(name)), nil
+	case reflect.String:
+		return sCtx.NewStringArgument(name), nil
+	case reflect.Complex128:
+		return sCtx.NewComplexArgument(name), nil
+	}
+
+	sort, err := sCtx.SortOf(t)
+	if err != nil {
+		return nil, err
+	}
+
+	result := sCtx.Ctx.Const(name, sort)
+	sCtx.Solver.Assert(sCtx.scalarBounds(result, t.Kind()))
+	sCtx.RegisterArgument(name, func(model *z3.Model) (reflect.Value, error) {
+		return DecodeValue(model, result, t)
+	})
+
+	return ScalarValue(result)
+}
+
+// NewBasicParameter creates a constant of the bool, integer or float type. Unlike arguments it isn't bounded,
+// so it stands for any value a caller may pass
+func (sCtx *SymContext) NewBasicParameter(name string, basic *types.Basic) (SymValue, error) {
+	t, err := BasicType(basic)
+	if err != nil {
+		return nil, err
+	}
+
+	sort, err := sCtx.SortOf(t)
+	if err != nil {
+		return nil, err
+	}
+
+	return ScalarValue(sCtx.Ctx.FreshConst(name, sort))
+}
+
+// ZeroValue returns the zero value of the basic type
+func (sCtx *SymContext) ZeroValue(basic *types.Basic) (SymValue, error) {
+	t, err := BasicType(basic)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return sCtx.NewStringConst(""), nil
+	case reflect.Complex128:
+		zeroConst := sCtx.Ctx.FloatZero(sCtx.Ctx.FloatSort(11, 53), false)
+		return SymComplex{re: zeroConst, im: zeroConst}, nil
+	}
+
+	sort, err := sCtx.SortOf(t)
+	if err != nil {
+		return nil, fmt.Errorf("zero value of %s: %w", basic, err)
+	}
+
+	return ScalarValue(sCtx.zeroValue(sort))
+}
@@ -0,0 +1,178 @@
+package smt
+
+import (
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	"go/types"
+	"math"
+	"reflect"
+)
+
+type symArgument struct {
+	name   string
+	decode func(model *z3.Model) (reflect.Value, error)
+}
+
+type DecodedArgument struct {
+	Name  string
+	Value reflect.Value
+}
+
+func (sCtx *SymContext) registerResult(decode func(model *z3.Model) (reflect.Value, error)) {
+	sCtx.results = append(sCtx.results, decode)
+}
+
+// RegisterArgument adds an argument which is decoded by DecodeArguments. Packages encoding their own kinds
+// of values use it, values from this package are registered by their New*Argument constructors
+func (sCtx *SymContext) RegisterArgument(name string, decode func(model *z3.Model) (reflect.Value, error)) {
+	sCtx.arguments = append(sCtx.arguments, symArgument{name: name, decode: decode})
+}
+
+// DecodeArguments turns every argument created via New*Argument into a concrete Go value
+// according to the model. Arguments are returned in the order they were created
+func (sCtx *SymContext) DecodeArguments(model *z3.Model) ([]DecodedArgument, error) {
+	result := make([]DecodedArgument, 0, len(sCtx.arguments))
+	for _, arg := range sCtx.arguments {
+		value, err := arg.decode(model)
+		if err != nil {
+			return nil, fmt.Errorf("argument %s: %w", arg.name, err)
+		}
+
+		result = append(result, DecodedArgument{Name: arg.name, Value: value})
+	}
+
+	return result, nil
+}
+
+// AddResult marks the value as a value of type t returned by the encoded function
+func (sCtx *SymContext) AddResult(result z3.Value, t reflect.Type) {
+	sCtx.registerResult(func(model *z3.Model) (reflect.Value, error) {
+		return DecodeValue(model, result, t)
+	})
+}
+
+// AddStringResult marks the string as a value returned by the encoded function
+func (sCtx *SymContext) AddStringResult(result SymString) {
+	sCtx.registerResult(func(model *z3.Model) (reflect.Value, error) {
+		value, err := result.Decode(model)
+		return reflect.ValueOf(value), err
+	})
+}
+
+// DecodeResults turns the values returned by the encoded function into concrete Go values according to the model
+func (sCtx *SymContext) DecodeResults(model *z3.Model) ([]reflect.Value, error) {
+	result := make([]reflect.Value, 0, len(sCtx.results))
+	for i, decode := range sCtx.results {
+		value, err := decode(model)
+		if err != nil {
+			return nil, fmt.Errorf("result %d: %w", i, err)
+		}
+
+		result = append(result, value)
+	}
+
+	return result, nil
+}
+
+// BasicType returns the reflect type of the basic go/types type, it fails for untyped and unsafe types
+func BasicType(basic *types.Basic) (reflect.Type, error) {
+	kind, ok := basicKinds[basic.Kind()]
+	if !ok {
+		return nil, fmt.Errorf("type %s has no symbolic encoding", basic)
+	}
+
+	if kind == reflect.String {
+		return reflect.TypeOf(""), nil
+	}
+	if kind == reflect.Complex128 {
+		return reflect.TypeOf(complex128(0)), nil
+	}
+
+	return kindTypes[kind], nil
+}
+
+// SortOf returns the sort used to encode values of the Go type t
+func (sCtx *SymContext) SortOf(t reflect.Type) (z3.Sort, error) {
+	switch t.Kind() {
+	case reflect.Bool:
+		return sCtx.Ctx.BoolSort(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return sCtx.Ctx.IntSort(), nil
+	case reflect.Float32:
+		return sCtx.Ctx.FloatSort(8, 24), nil
+	case reflect.Float64:
+		return sCtx.Ctx.FloatSort(11, 53), nil
+	default:
+		return z3.Sort{}, fmt.Errorf("type %s has no symbolic encoding", t)
+	}
+}
+
+func (sCtx *SymContext) zeroValue(sort z3.Sort) z3.Value {
+	switch sort.Kind() {
+	case z3.KindBool:
+		return sCtx.Ctx.FromBool(false)
+	case z3.KindFloatingPoint:
+		return sCtx.Ctx.FloatZero(sort, false)
+	default:
+		return sCtx.Ctx.FromInt(0, sort)
+	}
+}
+
+// Eq builds l == r for values of any sort
+func (sCtx *SymContext) Eq(l z3.Value, r z3.Value) z3.Bool {
+	switch l := l.(type) {
+	case z3.Int:
+		return l.Eq(r.(z3.Int))
+	case z3.Bool:
+		return l.Eq(r.(z3.Bool))
+	case z3.Float:
+		return l.IEEEEq(r.(z3.Float))
+	case z3.BV:
+		return l.Eq(r.(z3.BV))
+	default:
+		return sCtx.Ctx.Distinct(l, r).Not()
+	}
+}
+
+// DecodeValue evaluates the value of a basic sort in the model and converts it to the Go type t
+func DecodeValue(model *z3.Model, value z3.Value, t reflect.Type) (reflect.Value, error) {
+	result := reflect.New(t).Elem()
+	evaluated := model.Eval(value, true)
+
+	switch t.Kind() {
+	case reflect.Bool:
+		val, isLiteral := evaluated.(z3.Bool).AsBool()
+		if !isLiteral {
+			return result, fmt.Errorf("can't evaluate %s", value)
+		}
+		result.SetBool(val)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		val, isLiteral, ok := evaluated.(z3.Int).AsInt64()
+		if !isLiteral || !ok {
+			return result, fmt.Errorf("can't evaluate %s", value)
+		}
+		result.SetInt(val)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		val, isLiteral, ok := evaluated.(z3.Int).AsUint64()
+		if !isLiteral || !ok {
+			return result, fmt.Errorf("can't evaluate %s", value)
+		}
+		result.SetUint(val)
+	case reflect.Float32, reflect.Float64:
+		val, isLiteral := evaluated.(z3.Float).AsBigFloat()
+		if !isLiteral {
+			return result, fmt.Errorf("can't evaluate %s", value)
+		}
+		if val == nil {
+			result.SetFloat(math.NaN())
+		} else {
+			f, _ := val.Float64()
+			result.SetFloat(f)
+		}
+	default:
+		return result, fmt.Errorf("can't decode a value of type %s", t)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,164 @@
+package types
+
+import (
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	"strings"
+)
+
+// Join returns the least upper bound of the types a and b. It fails when the types
+// have several minimal upper bounds, like two classes implementing the same two interfaces
+func (h *Hierarchy) Join(a string, b string) (string, error) {
+	typeA, typeB, err := h.lookupPair(a, b)
+	if err != nil {
+		return "", err
+	}
+
+	bounds := h.minimalUpperBounds(typeA, typeB)
+	if len(bounds) == 0 {
+		return "", fmt.Errorf("%s and %s have no upper bounds", a, b)
+	}
+	if len(bounds) > 1 {
+		return "", fmt.Errorf("%s and %s have no least upper bound, minimal upper bounds are %s", a, b, typeNames(bounds))
+	}
+
+	return bounds[0].Name, nil
+}
+
+// Meet returns the greatest lower bound of the types a and b. Unrelated classes
+// have no lower bounds at all, so the meet exists less often than the join
+func (h *Hierarchy) Meet(a string, b string) (string, error) {
+	typeA, typeB, err := h.lookupPair(a, b)
+	if err != nil {
+		return "", err
+	}
+
+	bounds := h.maximalLowerBounds(typeA, typeB)
+	if len(bounds) == 0 {
+		return "", fmt.Errorf("%s and %s have no lower bounds", a, b)
+	}
+	if len(bounds) > 1 {
+		return "", fmt.Errorf("%s and %s have no greatest lower bound, maximal lower bounds are %s", a, b, typeNames(bounds))
+	}
+
+	return bounds[0].Name, nil
+}
+
+func (h *Hierarchy) lookupPair(a string, b string) (*Type, *Type, error) {
+	typeA, ok := h.Lookup(a)
+	if !ok {
+		return nil, nil, fmt.Errorf("type %s isn't declared", a)
+	}
+
+	typeB, ok := h.Lookup(b)
+	if !ok {
+		return nil, nil, fmt.Errorf("type %s isn't declared", b)
+	}
+
+	return typeA, typeB, nil
+}
+
+func typeNames(types []*Type) string {
+	names := make([]string, 0, len(types))
+	for _, t := range types {
+		names = append(names, t.Name)
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// isSubtype checks sub <: super on the declared graph
+func (h *Hierarchy) isSubtype(sub *Type, super *Type) bool {
+	for _, index := range h.supertypes(sub) {
+		if index == super.index {
+			return true
+		}
+	}
+
+	return false
+}
+
+// minimalUpperBounds returns common supertypes of a and b which have no common supertypes of a and b below them
+func (h *Hierarchy) minimalUpperBounds(a *Type, b *Type) []*Type {
+	return h.extremes(func(t *Type) bool {
+		return h.isSubtype(a, t) && h.isSubtype(b, t)
+	}, func(t *Type, other *Type) bool {
+		return h.isSubtype(other, t)
+	})
+}
+
+// maximalLowerBounds returns common subtypes of a and b which have no common subtypes of a and b above them
+func (h *Hierarchy) maximalLowerBounds(a *Type, b *Type) []*Type {
+	return h.extremes(func(t *Type) bool {
+		return h.isSubtype(t, a) && h.isSubtype(t, b)
+	}, func(t *Type, other *Type) bool {
+		return h.isSubtype(t, other)
+	})
+}
+
+// extremes returns the bounds no other bound is strictly closer to a and b than.
+// farther(t, other) tells that t is at least as far from a and b as other is
+func (h *Hierarchy) extremes(isBound func(t *Type) bool, farther func(t *Type, other *Type) bool) []*Type {
+	bounds := make([]*Type, 0)
+	for _, t := range h.Types {
+		if isBound(t) {
+			bounds = append(bounds, t)
+		}
+	}
+
+	result := make([]*Type, 0)
+	for _, bound := range bounds {
+		isExtreme := true
+		for _, other := range bounds {
+			if other != bound && farther(bound, other) && !farther(other, bound) {
+				isExtreme = false
+				break
+			}
+		}
+
+		if isExtreme {
+			result = append(result, bound)
+		}
+	}
+
+	return result
+}
+
+// IsJoin encodes that t is the least upper bound of a and b. The other upper bounds are enumerated,
+// since the set of types is finite, so the formula has no quantifiers
+func (ts *TypeSystem) IsJoin(t z3.Uninterpreted, a z3.Uninterpreted, b z3.Uninterpreted) z3.Bool {
+	isUpperBound := func(t z3.Uninterpreted) z3.Bool {
+		return ts.IsSupertypeOf(t, a).And(ts.IsSupertypeOf(t, b))
+	}
+
+	result := isUpperBound(t)
+	for _, other := range ts.consts {
+		result = result.And(isUpperBound(other).Implies(ts.IsSubtypeOf(t, other)))
+	}
+
+	return result
+}
+
+// IsMeet encodes that t is the greatest lower bound of a and b
+func (ts *TypeSystem) IsMeet(t z3.Uninterpreted, a z3.Uninterpreted, b z3.Uninterpreted) z3.Bool {
+	isLowerBound := func(t z3.Uninterpreted) z3.Bool {
+		return ts.IsSubtypeOf(t, a).And(ts.IsSubtypeOf(t, b))
+	}
+
+	result := isLowerBound(t)
+	for _, other := range ts.consts {
+		result = result.And(isLowerBound(other).Implies(ts.IsSupertypeOf(t, other)))
+	}
+
+	return result
+}
+
+// hasJoin encodes that a and b have the least upper bound
+func (ts *TypeSystem) hasJoin(a z3.Uninterpreted, b z3.Uninterpreted) z3.Bool {
+	result := ts.sCtx.Ctx.FromBool(false)
+	for _, candidate := range ts.consts {
+		result = result.Or(ts.IsJoin(candidate, a, b))
+	}
+
+	return result
+}
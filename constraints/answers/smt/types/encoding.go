@@ -0,0 +1,167 @@
+package types
+
+import (
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+	"math/big"
+	"reflect"
+)
+
+// TypeSystem is a hierarchy encoded into the solver. Types are constants of the uninterpreted sort Type.
+// Every type is labelled with the bit set of its supertypes, so a <: b is checked
+// as supertypes(a) & supertypes(b) == supertypes(b) and no quantifiers are needed.
+// The declarations of every encoding are prefixed apart, as types1.Type, so the hierarchies encoded
+// into one context don't share them
+type TypeSystem struct {
+	sCtx      *smt.SymContext
+	hierarchy *Hierarchy
+
+	sort   z3.Sort
+	consts []z3.Uninterpreted
+
+	supertypes   z3.FuncDecl
+	superclasses z3.FuncDecl
+}
+
+func Encode(sCtx *smt.SymContext, hierarchy *Hierarchy) (*TypeSystem, error) {
+	if err := hierarchy.Validate(); err != nil {
+		return nil, err
+	}
+
+	ctx := sCtx.Ctx
+	size := len(hierarchy.Types)
+	prefix := sCtx.FreshName("types") + "."
+
+	ts := &TypeSystem{
+		sCtx:      sCtx,
+		hierarchy: hierarchy,
+		sort:      ctx.UninterpretedSort(prefix + "Type"),
+		consts:    make([]z3.Uninterpreted, size),
+	}
+
+	labelSort := ctx.BVSort(size)
+	ts.supertypes = ctx.FuncDecl(prefix+"supertypes", []z3.Sort{ts.sort}, labelSort)
+	ts.superclasses = ctx.FuncDecl(prefix+"superclasses", []z3.Sort{ts.sort}, labelSort)
+
+	consts := make([]z3.Value, size)
+	for i, t := range hierarchy.Types {
+		ts.consts[i] = ctx.Const(prefix+t.Name, ts.sort).(z3.Uninterpreted)
+		consts[i] = ts.consts[i]
+	}
+	if size > 1 {
+		sCtx.Solver.Assert(ctx.Distinct(consts...))
+	}
+
+	for i, t := range hierarchy.Types {
+		supertypes := ctx.FromBigInt(label(hierarchy.supertypes(t)), labelSort).(z3.BV)
+		sCtx.Solver.Assert(ts.supertypes.Apply(ts.consts[i]).(z3.BV).Eq(supertypes))
+
+		superclasses := ctx.FromBigInt(label(hierarchy.superclasses(t)), labelSort).(z3.BV)
+		sCtx.Solver.Assert(ts.superclasses.Apply(ts.consts[i]).(z3.BV).Eq(superclasses))
+	}
+
+	return ts, nil
+}
+
+func label(indices []int) *big.Int {
+	result := new(big.Int)
+	for _, index := range indices {
+		result.SetBit(result, index, 1)
+	}
+
+	return result
+}
+
+func (ts *TypeSystem) Hierarchy() *Hierarchy {
+	return ts.hierarchy
+}
+
+func (ts *TypeSystem) Sort() z3.Sort {
+	return ts.sort
+}
+
+// Type returns the constant of the declared type
+func (ts *TypeSystem) Type(name string) (z3.Uninterpreted, error) {
+	t, ok := ts.hierarchy.Lookup(name)
+	if !ok {
+		return z3.Uninterpreted{}, fmt.Errorf("type %s isn't declared", name)
+	}
+
+	return ts.consts[t.index], nil
+}
+
+// NewTypeVar creates a variable ranging over the declared types
+func (ts *TypeSystem) NewTypeVar(name string) z3.Uninterpreted {
+	variable := ts.sCtx.Ctx.Const(name, ts.sort).(z3.Uninterpreted)
+
+	oneOf := make([]z3.Bool, 0, len(ts.consts))
+	for _, c := range ts.consts {
+		oneOf = append(oneOf, variable.Eq(c))
+	}
+	ts.sCtx.Solver.Assert(ts.sCtx.Ctx.FromBool(false).Or(oneOf...))
+
+	return variable
+}
+
+// NewTypeArgument creates a type variable which is decoded into the name of the type
+func (ts *TypeSystem) NewTypeArgument(name string) z3.Uninterpreted {
+	variable := ts.NewTypeVar(name)
+	ts.sCtx.RegisterArgument(name, func(model *z3.Model) (reflect.Value, error) {
+		t, err := ts.Decode(model, variable)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		return reflect.ValueOf(t.Name), nil
+	})
+
+	return variable
+}
+
+// IsSubtypeOf encodes a <: b. The relation is reflexive and transitive
+func (ts *TypeSystem) IsSubtypeOf(a z3.Uninterpreted, b z3.Uninterpreted) z3.Bool {
+	return includes(ts.supertypes.Apply(a).(z3.BV), ts.supertypes.Apply(b).(z3.BV))
+}
+
+func (ts *TypeSystem) IsSupertypeOf(a z3.Uninterpreted, b z3.Uninterpreted) z3.Bool {
+	return ts.IsSubtypeOf(b, a)
+}
+
+// IsSubclassOf encodes that a extends b, directly or not. Unlike IsSubtypeOf
+// it only follows superclasses, so it never holds when one of the types is an interface
+func (ts *TypeSystem) IsSubclassOf(a z3.Uninterpreted, b z3.Uninterpreted) z3.Bool {
+	superclassesA := ts.superclasses.Apply(a).(z3.BV)
+	superclassesB := ts.superclasses.Apply(b).(z3.BV)
+	zero := ts.sCtx.Ctx.FromInt(0, superclassesB.Sort()).(z3.BV)
+
+	return superclassesB.NE(zero).And(includes(superclassesA, superclassesB))
+}
+
+func (ts *TypeSystem) IsInterface(a z3.Uninterpreted) z3.Bool {
+	result := ts.sCtx.Ctx.FromBool(false)
+	for i, t := range ts.hierarchy.Types {
+		if t.Kind == Interface {
+			result = result.Or(a.Eq(ts.consts[i]))
+		}
+	}
+
+	return result
+}
+
+// includes checks that the bit set a contains the bit set b
+func includes(a z3.BV, b z3.BV) z3.Bool {
+	return a.And(b).Eq(b)
+}
+
+// Decode returns the type the value is equal to in the model
+func (ts *TypeSystem) Decode(model *z3.Model, value z3.Uninterpreted) (*Type, error) {
+	for i, t := range ts.hierarchy.Types {
+		isEqual, isLiteral := model.Eval(value.Eq(ts.consts[i]), true).(z3.Bool).AsBool()
+		if isLiteral && isEqual {
+			return t, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s isn't equal to any declared type", value)
+}
@@ -0,0 +1,103 @@
+package types
+
+import (
+	"github.com/aclements/go-z3/z3"
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+	"testing"
+)
+
+func newSymContext() *smt.SymContext {
+	ctx := z3.NewContext(&z3.Config{})
+	return &smt.SymContext{Solver: z3.NewSolver(ctx), Ctx: ctx}
+}
+
+// chain declares the classes in the order of the names, every class extends the previous one
+func chain(names ...string) *Hierarchy {
+	hierarchy := NewHierarchy("Object")
+	super := ""
+	for _, name := range names {
+		hierarchy.AddClass(name, super)
+		super = name
+	}
+
+	return hierarchy
+}
+
+// valid checks that the negation of the condition is unsatisfiable and the solver itself is satisfiable
+func valid(t *testing.T, sCtx *smt.SymContext, cond z3.Bool) bool {
+	t.Helper()
+
+	sat, err := sCtx.Solver.Check()
+	if err != nil || !sat {
+		t.Fatalf("the encodings are unsatisfiable: %v", err)
+	}
+
+	sCtx.Solver.Push()
+	defer sCtx.Solver.Pop()
+	sCtx.Solver.Assert(cond.Not())
+	sat, err = sCtx.Solver.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return !sat
+}
+
+func subtype(t *testing.T, ts *TypeSystem, a string, b string) z3.Bool {
+	t.Helper()
+
+	typeA, err := ts.Type(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	typeB, err := ts.Type(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ts.IsSubtypeOf(typeA, typeB)
+}
+
+func TestEncodeTwice(t *testing.T) {
+	sCtx := newSymContext()
+	forward, err := Encode(sCtx, chain("A", "B"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	backward, err := Encode(sCtx, chain("B", "A"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !valid(t, sCtx, subtype(t, forward, "B", "A")) {
+		t.Errorf("B <: A doesn't hold in the first hierarchy")
+	}
+	if !valid(t, sCtx, subtype(t, backward, "A", "B")) {
+		t.Errorf("A <: B doesn't hold in the second hierarchy")
+	}
+	if valid(t, sCtx, subtype(t, backward, "B", "A")) {
+		t.Errorf("B <: A holds in the second hierarchy")
+	}
+}
+
+func TestEncodeSameHierarchyTwice(t *testing.T) {
+	sCtx := newSymContext()
+	hierarchy := chain("A", "B")
+	first, err := Encode(sCtx, hierarchy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Encode(sCtx, hierarchy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.Sort().String() == second.Sort().String() {
+		t.Errorf("both encodings declare the sort %s", first.Sort())
+	}
+	for _, ts := range []*TypeSystem{first, second} {
+		if !valid(t, sCtx, subtype(t, ts, "B", "Object")) {
+			t.Errorf("B <: Object doesn't hold in %s", ts.Sort())
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package types
+
+import (
+	"fmt"
+)
+
+type Kind int
+
+const (
+	Class Kind = iota
+	Interface
+)
+
+func (kind Kind) String() string {
+	if kind == Interface {
+		return "interface"
+	}
+
+	return "class"
+}
+
+// Type is a declared type of the hierarchy. Super is the direct superclass of a class,
+// Interfaces are the interfaces a class implements or an interface extends
+type Type struct {
+	Name       string
+	Kind       Kind
+	Super      string
+	Interfaces []string
+
+	index int
+}
+
+// Hierarchy is a Java-like type hierarchy: classes have a single superclass, interfaces may extend
+// several interfaces. The root is a supertype of every type, like Object in Java or any in Go
+type Hierarchy struct {
+	Root  string
+	Types []*Type
+
+	byName map[string]*Type
+}
+
+// NewHierarchy creates a hierarchy with the root class
+func NewHierarchy(root string) *Hierarchy {
+	return newHierarchy(root, Class)
+}
+
+// NewInterfaceHierarchy creates a hierarchy with the root interface, which is implemented by every type
+func NewInterfaceHierarchy(root string) *Hierarchy {
+	return newHierarchy(root, Interface)
+}
+
+func newHierarchy(root string, kind Kind) *Hierarchy {
+	hierarchy := &Hierarchy{Root: root, byName: make(map[string]*Type)}
+	hierarchy.add(&Type{Name: root, Kind: kind})
+
+	return hierarchy
+}
+
+// AddClass declares a class. The empty super means the class has no superclass but the root
+func (h *Hierarchy) AddClass(name string, super string, interfaces ...string) {
+	h.add(&Type{Name: name, Kind: Class, Super: super, Interfaces: interfaces})
+}
+
+func (h *Hierarchy) AddInterface(name string, supers ...string) {
+	h.add(&Type{Name: name, Kind: Interface, Interfaces: supers})
+}
+
+func (h *Hierarchy) add(t *Type) {
+	t.index = len(h.Types)
+	h.Types = append(h.Types, t)
+
+	// duplicates are reported by Validate, the first declaration is the one that's looked up
+	if _, ok := h.byName[t.Name]; !ok {
+		h.byName[t.Name] = t
+	}
+}
+
+func (h *Hierarchy) Lookup(name string) (*Type, bool) {
+	t, ok := h.byName[name]
+	return t, ok
+}
+
+// Validate checks that every referenced type is declared and the kinds of supertypes are correct
+func (h *Hierarchy) Validate() error {
+	seen := make(map[string]bool)
+	for _, t := range h.Types {
+		if seen[t.Name] {
+			return fmt.Errorf("type %s is declared twice", t.Name)
+		}
+		seen[t.Name] = true
+
+		if t.Super != "" {
+			super, ok := h.byName[t.Super]
+			if !ok {
+				return fmt.Errorf("superclass %s of %s isn't declared", t.Super, t.Name)
+			}
+			if super.Kind != Class {
+				return fmt.Errorf("%s extends %s, which is an interface", t.Name, t.Super)
+			}
+		}
+
+		for _, name := range t.Interfaces {
+			iface, ok := h.byName[name]
+			if !ok {
+				return fmt.Errorf("interface %s of %s isn't declared", name, t.Name)
+			}
+			if iface.Kind != Interface {
+				return fmt.Errorf("%s implements %s, which is a class", t.Name, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// supertypes returns indices of all the supertypes of t including t itself.
+// Cycles in declarations are allowed, types on a cycle are supertypes of each other
+func (h *Hierarchy) supertypes(t *Type) []int {
+	visited := make(map[int]bool)
+
+	var visit func(t *Type)
+	visit = func(t *Type) {
+		if visited[t.index] {
+			return
+		}
+		visited[t.index] = true
+
+		for _, super := range h.directSupertypes(t) {
+			visit(super)
+		}
+	}
+	visit(t)
+
+	result := make([]int, 0, len(visited))
+	for index := range h.Types {
+		if visited[index] {
+			result = append(result, index)
+		}
+	}
+
+	return result
+}
+
+// superclasses returns indices of t and the classes it extends, directly or not
+func (h *Hierarchy) superclasses(t *Type) []int {
+	if t.Kind != Class {
+		return nil
+	}
+
+	visited := make(map[int]bool)
+	result := make([]int, 0)
+	for current := t; current != nil && !visited[current.index]; current = h.byName[current.Super] {
+		visited[current.index] = true
+		result = append(result, current.index)
+	}
+
+	root := h.byName[h.Root]
+	if root.Kind == Class && !visited[root.index] {
+		result = append(result, root.index)
+	}
+
+	return result
+}
+
+func (h *Hierarchy) directSupertypes(t *Type) []*Type {
+	result := make([]*Type, 0, len(t.Interfaces)+1)
+	if t.Super != "" {
+		result = append(result, h.byName[t.Super])
+	}
+	for _, name := range t.Interfaces {
+		result = append(result, h.byName[name])
+	}
+
+	if t.Name != h.Root {
+		result = append(result, h.byName[h.Root])
+	}
+
+	return result
+}
@@ -0,0 +1,139 @@
+package types
+
+import (
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+	"reflect"
+)
+
+// SymInterface is a value of an interface type: the dynamic type and the dynamic values
+// for the types it may have. The dynamic values are provided by the code creating the interface
+type SymInterface struct {
+	smt.SymValueBase
+
+	Static      string
+	DynamicType z3.Uninterpreted
+	IsNil       z3.Bool
+
+	values map[string]smt.SymValue
+}
+
+// NewInterfaceValue converts the value of the declared concrete type to the interface static
+func (ts *TypeSystem) NewInterfaceValue(static string, dynamicType string, value smt.SymValue) (SymInterface, error) {
+	t, err := ts.Type(dynamicType)
+	if err != nil {
+		return SymInterface{}, err
+	}
+
+	return SymInterface{
+		Static:      static,
+		DynamicType: t,
+		IsNil:       ts.sCtx.Ctx.FromBool(false),
+		values:      map[string]smt.SymValue{dynamicType: value},
+	}, nil
+}
+
+// NewNilInterface returns the nil value of the interface static
+func (ts *TypeSystem) NewNilInterface(static string) SymInterface {
+	return SymInterface{
+		Static:      static,
+		DynamicType: ts.consts[0],
+		IsNil:       ts.sCtx.Ctx.FromBool(true),
+	}
+}
+
+// DynamicValue returns the dynamic value the interface has when its dynamic type is typeName
+func (i SymInterface) DynamicValue(typeName string) (smt.SymValue, bool) {
+	value, ok := i.values[typeName]
+	return value, ok
+}
+
+// WithDynamicValue returns a copy of the interface with the dynamic value for typeName set
+func (i SymInterface) WithDynamicValue(typeName string, value smt.SymValue) SymInterface {
+	result := i
+	result.values = make(map[string]smt.SymValue, len(i.values)+1)
+	for name, v := range i.values {
+		result.values[name] = v
+	}
+	result.values[typeName] = value
+
+	return result
+}
+
+// WithStatic returns the same value seen as a value of another interface
+func (i SymInterface) WithStatic(static string) SymInterface {
+	result := i
+	result.Static = static
+
+	return result
+}
+
+// NewInterfaceArgument creates a value of the interface type static. The dynamic type
+// may be any concrete type implementing the interface, the value may also be nil
+func (ts *TypeSystem) NewInterfaceArgument(name string, static string) (SymInterface, error) {
+	staticType, err := ts.Type(static)
+	if err != nil {
+		return SymInterface{}, err
+	}
+
+	t, _ := ts.hierarchy.Lookup(static)
+	if t.Kind != Interface {
+		return SymInterface{}, fmt.Errorf("%s isn't an interface", static)
+	}
+
+	result := SymInterface{
+		Static:      static,
+		DynamicType: ts.NewTypeVar(name + ".type"),
+		IsNil:       ts.sCtx.Ctx.BoolConst(name + ".isNil"),
+	}
+	ts.sCtx.Solver.Assert(ts.IsSubtypeOf(result.DynamicType, staticType))
+	ts.sCtx.Solver.Assert(ts.IsInterface(result.DynamicType).Not())
+
+	ts.sCtx.RegisterArgument(name, func(model *z3.Model) (reflect.Value, error) {
+		dynamicType, err := ts.DecodeDynamicType(model, result)
+		return reflect.ValueOf(dynamicType), err
+	})
+
+	return result, nil
+}
+
+// HasDynamicType encodes that the value isn't nil and its dynamic type is exactly the given one,
+// like the type assertion v.(T) for a concrete T does
+func (ts *TypeSystem) HasDynamicType(value SymInterface, name string) (z3.Bool, error) {
+	t, err := ts.Type(name)
+	if err != nil {
+		return z3.Bool{}, err
+	}
+
+	return value.IsNil.Not().And(value.DynamicType.Eq(t)), nil
+}
+
+// Implements encodes that the value isn't nil and its dynamic type implements the interface,
+// like the type assertion v.(I) for an interface I does
+func (ts *TypeSystem) Implements(value SymInterface, iface string) (z3.Bool, error) {
+	t, err := ts.Type(iface)
+	if err != nil {
+		return z3.Bool{}, err
+	}
+
+	return value.IsNil.Not().And(ts.IsSubtypeOf(value.DynamicType, t)), nil
+}
+
+// DecodeDynamicType returns the name of the dynamic type of the value in the model, nil values have the type "nil"
+func (ts *TypeSystem) DecodeDynamicType(model *z3.Model, value SymInterface) (string, error) {
+	isNil, isLiteral := model.Eval(value.IsNil, true).(z3.Bool).AsBool()
+	if !isLiteral {
+		return "", fmt.Errorf("can't evaluate %s", value.IsNil)
+	}
+	if isNil {
+		return "nil", nil
+	}
+
+	t, err := ts.Decode(model, value.DynamicType)
+	if err != nil {
+		return "", err
+	}
+
+	return t.Name, nil
+}
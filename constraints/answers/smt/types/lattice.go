@@ -0,0 +1,160 @@
+package types
+
+import (
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	"sort"
+	"strings"
+)
+
+// Violation is a counterexample to one of the properties of a semilattice
+type Violation struct {
+	Property string
+	Types    []string
+	Details  string
+}
+
+func (v Violation) String() string {
+	result := fmt.Sprintf("%s: %s", v.Property, strings.Join(v.Types, ", "))
+	if v.Details != "" {
+		result += " (" + v.Details + ")"
+	}
+
+	return result
+}
+
+type latticeProperty struct {
+	name string
+	// variables is the number of type variables the property talks about
+	variables int
+	// violated encodes the negation of the property
+	violated func(ts *TypeSystem, vars []z3.Uninterpreted) z3.Bool
+	// symmetric properties don't distinguish counterexamples which differ in the order of the variables
+	symmetric bool
+	details   func(ts *TypeSystem, types []*Type) string
+}
+
+var latticeProperties = []latticeProperty{
+	{
+		name:      "reflexivity",
+		variables: 1,
+		violated: func(ts *TypeSystem, vars []z3.Uninterpreted) z3.Bool {
+			return ts.IsSubtypeOf(vars[0], vars[0]).Not()
+		},
+	},
+	{
+		name:      "antisymmetry",
+		variables: 2,
+		violated: func(ts *TypeSystem, vars []z3.Uninterpreted) z3.Bool {
+			return ts.IsSubtypeOf(vars[0], vars[1]).And(ts.IsSubtypeOf(vars[1], vars[0]), vars[0].NE(vars[1]))
+		},
+		symmetric: true,
+		details: func(ts *TypeSystem, types []*Type) string {
+			return fmt.Sprintf("%s <: %s and %s <: %s", types[0].Name, types[1].Name, types[1].Name, types[0].Name)
+		},
+	},
+	{
+		name:      "transitivity",
+		variables: 3,
+		violated: func(ts *TypeSystem, vars []z3.Uninterpreted) z3.Bool {
+			return ts.IsSubtypeOf(vars[0], vars[1]).And(ts.IsSubtypeOf(vars[1], vars[2]), ts.IsSubtypeOf(vars[0], vars[2]).Not())
+		},
+	},
+	{
+		name:      "join",
+		variables: 2,
+		violated: func(ts *TypeSystem, vars []z3.Uninterpreted) z3.Bool {
+			return ts.hasJoin(vars[0], vars[1]).Not()
+		},
+		symmetric: true,
+		details: func(ts *TypeSystem, types []*Type) string {
+			bounds := ts.hierarchy.minimalUpperBounds(types[0], types[1])
+			if len(bounds) == 0 {
+				return "no upper bounds"
+			}
+
+			return "minimal upper bounds: " + typeNames(bounds)
+		},
+	},
+}
+
+// CheckLattice asks the solver for counterexamples to reflexivity, antisymmetry and transitivity
+// of <: and to the existence of joins. Every counterexample is reported once
+func (ts *TypeSystem) CheckLattice() ([]Violation, error) {
+	result := make([]Violation, 0)
+	for _, property := range latticeProperties {
+		violations, err := ts.counterexamples(property)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", property.name, err)
+		}
+
+		result = append(result, violations...)
+	}
+
+	return result, nil
+}
+
+func (ts *TypeSystem) counterexamples(property latticeProperty) ([]Violation, error) {
+	solver := ts.sCtx.Solver
+	solver.Push()
+	defer solver.Pop()
+
+	vars := make([]z3.Uninterpreted, property.variables)
+	for i := range vars {
+		// ? can't appear in the declared type names, so the variables don't clash with the types
+		vars[i] = ts.NewTypeVar(fmt.Sprintf("?%s%d", property.name, i))
+	}
+	solver.Assert(property.violated(ts, vars))
+
+	result := make([]Violation, 0)
+	for {
+		sat, err := solver.Check()
+		if err != nil {
+			return nil, err
+		}
+		if !sat {
+			// models come in no particular order, sorting makes the report stable
+			sort.Slice(result, func(i, j int) bool {
+				return strings.Join(result[i].Types, " ") < strings.Join(result[j].Types, " ")
+			})
+
+			return result, nil
+		}
+
+		model := solver.Model()
+		types := make([]*Type, len(vars))
+		for i, variable := range vars {
+			types[i], err = ts.Decode(model, variable)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		solver.Assert(ts.blocking(vars, types))
+		if property.symmetric {
+			solver.Assert(ts.blocking([]z3.Uninterpreted{vars[1], vars[0]}, types))
+			if types[0].index > types[1].index {
+				types[0], types[1] = types[1], types[0]
+			}
+		}
+
+		violation := Violation{Property: property.name}
+		for _, t := range types {
+			violation.Types = append(violation.Types, t.Name)
+		}
+		if property.details != nil {
+			violation.Details = property.details(ts, types)
+		}
+		result = append(result, violation)
+	}
+}
+
+// blocking excludes the assignment of types to the variables from the following models
+func (ts *TypeSystem) blocking(vars []z3.Uninterpreted, types []*Type) z3.Bool {
+	differs := make([]z3.Bool, 0, len(vars))
+	for i, variable := range vars {
+		differs = append(differs, variable.NE(ts.consts[types[i].index]))
+	}
+
+	return ts.sCtx.Ctx.FromBool(false).Or(differs...)
+}
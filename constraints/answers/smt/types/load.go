@@ -0,0 +1,203 @@
+package types
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	gotypes "go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LoadedPackage is a type checked Go package
+type LoadedPackage struct {
+	Fset    *token.FileSet
+	Files   []*ast.File
+	Package *gotypes.Package
+	Info    *gotypes.Info
+}
+
+// Load loads the package of the directory or the package made of the single Go file, as a file
+// of a directory holding files which aren't Go is
+func Load(path string) (*LoadedPackage, error) {
+	if strings.HasSuffix(path, ".go") {
+		return LoadFiles(path)
+	}
+
+	return LoadDir(path)
+}
+
+// LoadDir parses and type checks the non-test Go files of the directory.
+// Imports are type checked from source, so the standard library doesn't have to be compiled
+func LoadDir(dir string) (*LoadedPackage, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, name))
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+
+	return LoadFiles(paths...)
+}
+
+// LoadFiles parses and type checks the Go files as one package
+func LoadFiles(paths ...string) (*LoadedPackage, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no Go files to load")
+	}
+
+	fset := token.NewFileSet()
+	files := make([]*ast.File, 0, len(paths))
+	for _, path := range paths {
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	info := &gotypes.Info{
+		Types:      make(map[ast.Expr]gotypes.TypeAndValue),
+		Defs:       make(map[*ast.Ident]gotypes.Object),
+		Uses:       make(map[*ast.Ident]gotypes.Object),
+		Implicits:  make(map[ast.Node]gotypes.Object),
+		Selections: make(map[*ast.SelectorExpr]*gotypes.Selection),
+		Scopes:     make(map[ast.Node]*gotypes.Scope),
+		Instances:  make(map[*ast.Ident]gotypes.Instance),
+	}
+	config := gotypes.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+
+	pkg, err := config.Check(files[0].Name.Name, fset, files, info)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoadedPackage{Fset: fset, Files: files, Package: pkg, Info: info}, nil
+}
+
+// FromPackage builds the hierarchy of the named types of the package, the most common predeclared types and LibraryError.
+// The root is any, every interface is a supertype of the types implementing it. T and *T are different types,
+// since methods with pointer receivers belong to the method set of *T only.
+// Generic types aren't included, their instances are different types
+func FromPackage(pkg *gotypes.Package) *Hierarchy {
+	hierarchy := NewInterfaceHierarchy("any")
+
+	interfaces := make([]*gotypes.Named, 0)
+	concrete := make([]*gotypes.Named, 0)
+
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		typeName, ok := scope.Lookup(name).(*gotypes.TypeName)
+		if !ok || typeName.IsAlias() {
+			continue
+		}
+
+		named, ok := typeName.Type().(*gotypes.Named)
+		if !ok || named.TypeParams().Len() > 0 {
+			continue
+		}
+
+		if gotypes.IsInterface(named) {
+			// interfaces with type constraints can only be used as constraints
+			if named.Underlying().(*gotypes.Interface).IsMethodSet() {
+				interfaces = append(interfaces, named)
+			}
+		} else {
+			concrete = append(concrete, named)
+		}
+	}
+
+	// error is the interface which is implemented the most often, so it's always included
+	errorType := gotypes.Universe.Lookup("error").Type().(*gotypes.Named)
+	interfaces = append(interfaces, errorType)
+
+	basics := make([]gotypes.Type, 0, len(dynamicBasics))
+	for _, kind := range dynamicBasics {
+		basics = append(basics, gotypes.Typ[kind])
+	}
+
+	implemented := func(t gotypes.Type, self *gotypes.Named) []string {
+		result := make([]string, 0)
+		for _, iface := range interfaces {
+			if iface != self && gotypes.Implements(t, iface.Underlying().(*gotypes.Interface)) {
+				result = append(result, GoTypeName(iface))
+			}
+		}
+
+		return result
+	}
+
+	for _, iface := range interfaces {
+		hierarchy.AddInterface(GoTypeName(iface), implemented(iface, iface)...)
+	}
+	for _, basic := range basics {
+		hierarchy.AddClass(GoTypeName(basic), "", implemented(basic, nil)...)
+	}
+	// the errors made by the library have the same methods as error
+	hierarchy.AddClass(LibraryError, "", append(implemented(errorType, errorType), GoTypeName(errorType))...)
+	for _, named := range concrete {
+		hierarchy.AddClass(GoTypeName(named), "", implemented(named, nil)...)
+
+		pointer := gotypes.NewPointer(named)
+		hierarchy.AddClass(GoTypeName(pointer), "", implemented(pointer, nil)...)
+	}
+
+	return hierarchy
+}
+
+// LibraryError is the dynamic type of the errors made by errors.New and fmt.Errorf. Packages can't name it,
+// so it's in the hierarchy of every package
+const LibraryError = "*errors.errorString"
+
+// dynamicBasics are the predeclared types which are included into the hierarchy,
+// so values of them may be stored in interfaces
+var dynamicBasics = []gotypes.BasicKind{
+	gotypes.Bool, gotypes.String,
+	gotypes.Int, gotypes.Int8, gotypes.Int16, gotypes.Int32, gotypes.Int64,
+	gotypes.Uint, gotypes.Uint8, gotypes.Uint16, gotypes.Uint32, gotypes.Uint64,
+	gotypes.Float32, gotypes.Float64,
+}
+
+// GoTypeName is the name of the type in the hierarchy built by FromPackage
+func GoTypeName(t gotypes.Type) string {
+	return gotypes.TypeString(t, func(*gotypes.Package) string { return "" })
+}
+
+// Implementations returns the names of the concrete types implementing the interface, sorted
+func (h *Hierarchy) Implementations(iface string) []string {
+	result := make([]string, 0)
+	target, ok := h.byName[iface]
+	if !ok {
+		return result
+	}
+
+	for _, t := range h.Types {
+		if t.Kind != Class {
+			continue
+		}
+
+		for _, index := range h.supertypes(t) {
+			if index == target.index {
+				result = append(result, t.Name)
+				break
+			}
+		}
+	}
+	sort.Strings(result)
+
+	return result
+}
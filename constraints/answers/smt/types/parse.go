@@ -0,0 +1,103 @@
+package types
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseHierarchy reads a hierarchy description. The first declaration names the root class,
+// every other line declares a class or an interface, # starts a comment:
+//
+//	root Object
+//	interface Collection
+//	interface List extends Collection
+//	class AbstractList implements List
+//	class ArrayList extends AbstractList implements List
+func ParseHierarchy(reader io.Reader) (*Hierarchy, error) {
+	var hierarchy *Hierarchy
+
+	scanner := bufio.NewScanner(reader)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		words := strings.Fields(line)
+		if len(words) == 0 {
+			continue
+		}
+
+		if hierarchy == nil {
+			if len(words) != 2 || words[0] != "root" {
+				return nil, fmt.Errorf("line %d: expected the root declaration, got %q", lineNumber, line)
+			}
+
+			hierarchy = NewHierarchy(words[1])
+			continue
+		}
+
+		if err := parseDeclaration(hierarchy, words); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if hierarchy == nil {
+		return nil, fmt.Errorf("the description has no root declaration")
+	}
+
+	return hierarchy, nil
+}
+
+func parseDeclaration(hierarchy *Hierarchy, words []string) error {
+	if len(words) < 2 {
+		return fmt.Errorf("expected a type name after %s", words[0])
+	}
+	kind, name, rest := words[0], words[1], words[2:]
+
+	clauses := make(map[string][]string)
+	clause := ""
+	for _, word := range rest {
+		if word == "extends" || word == "implements" {
+			if _, ok := clauses[word]; ok {
+				return fmt.Errorf("%s of %s is given twice", word, name)
+			}
+			clause = word
+			clauses[clause] = []string{}
+			continue
+		}
+		if clause == "" {
+			return fmt.Errorf("unexpected %q in the declaration of %s", word, name)
+		}
+
+		for _, typeName := range strings.Split(word, ",") {
+			if typeName != "" {
+				clauses[clause] = append(clauses[clause], typeName)
+			}
+		}
+	}
+
+	switch kind {
+	case "class":
+		supers := clauses["extends"]
+		if len(supers) > 1 {
+			return fmt.Errorf("class %s extends several classes", name)
+		}
+
+		super := ""
+		if len(supers) == 1 {
+			super = supers[0]
+		}
+		hierarchy.AddClass(name, super, clauses["implements"]...)
+	case "interface":
+		if _, ok := clauses["implements"]; ok {
+			return fmt.Errorf("interface %s can't implement interfaces", name)
+		}
+		hierarchy.AddInterface(name, clauses["extends"]...)
+	default:
+		return fmt.Errorf("unknown declaration %s", kind)
+	}
+
+	return nil
+}
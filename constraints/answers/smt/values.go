@@ -0,0 +1,118 @@
+package smt
+
+import (
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	"reflect"
+)
+
+// SymValue is a symbolic value of some Go type. Values of basic types are SymBool, SymInt and SymFloat,
+// pointers are SymRef, composite values are SymString, SymComplex, SymStruct, SymMap, SymSlice and SymSimpleArray
+type SymValue interface {
+	isSymValue()
+}
+
+// SymValueBase makes types of other packages SymValues when it's embedded into them
+type SymValueBase struct{}
+
+func (SymValueBase) isSymValue() {}
+
+type SymBool interface {
+	SymValue
+	Bool() z3.Bool
+}
+
+type SymInt interface {
+	SymValue
+	Int() z3.Int
+}
+
+type SymFloat interface {
+	SymValue
+	Float() z3.Float
+}
+
+// SymRef is a pointer, encoded as an address in the heap. The nil pointer has the zero address
+type SymRef interface {
+	SymValue
+	Address() z3.Int
+	IsNil() z3.Bool
+}
+
+type symBool struct{ value z3.Bool }
+type symInt struct{ value z3.Int }
+type symFloat struct{ value z3.Float }
+type symRef struct{ address z3.Int }
+
+func BoolValue(value z3.Bool) SymBool    { return symBool{value} }
+func IntValue(value z3.Int) SymInt       { return symInt{value} }
+func FloatValue(value z3.Float) SymFloat { return symFloat{value} }
+func RefValue(address z3.Int) SymRef     { return symRef{address} }
+
+func (value symBool) Bool() z3.Bool    { return value.value }
+func (value symInt) Int() z3.Int       { return value.value }
+func (value symFloat) Float() z3.Float { return value.value }
+func (value symRef) Address() z3.Int   { return value.address }
+
+func (value symRef) IsNil() z3.Bool {
+	ctx := value.address.Context()
+	return value.address.Eq(ctx.FromInt(0, ctx.IntSort()).(z3.Int))
+}
+
+func (symBool) isSymValue()        {}
+func (symInt) isSymValue()         {}
+func (symFloat) isSymValue()       {}
+func (symRef) isSymValue()         {}
+func (SymString) isSymValue()      {}
+func (SymComplex) isSymValue()     {}
+func (SymStruct) isSymValue()      {}
+func (SymMap) isSymValue()         {}
+func (SymSimpleArray) isSymValue() {}
+func (SymSlice) isSymValue()       {}
+func (SymFunction) isSymValue()    {}
+
+// ScalarValue wraps a value of a basic sort into the matching SymValue
+func ScalarValue(value z3.Value) (SymValue, error) {
+	switch value := value.(type) {
+	case z3.Bool:
+		return BoolValue(value), nil
+	case z3.Int:
+		return IntValue(value), nil
+	case z3.Float:
+		return FloatValue(value), nil
+	default:
+		return nil, fmt.Errorf("values of sort %s aren't supported", value.Sort())
+	}
+}
+
+// ScalarTerm unwraps the value of a basic sort, it's the inverse of ScalarValue
+func ScalarTerm(value SymValue) (z3.Value, error) {
+	switch value := value.(type) {
+	case SymBool:
+		return value.Bool(), nil
+	case SymInt:
+		return value.Int(), nil
+	case SymFloat:
+		return value.Float(), nil
+	default:
+		return nil, fmt.Errorf("%T isn't a value of a basic sort", value)
+	}
+}
+
+// GetField returns the field of the struct as T, the field may be promoted from an embedded struct.
+// It fails when there is no such field or its type doesn't match T
+func GetField[T SymValue](structure SymStruct, name string) (T, error) {
+	var result T
+
+	value, err := structure.Field(name)
+	if err != nil {
+		return result, err
+	}
+
+	result, ok := value.(T)
+	if !ok {
+		return result, fmt.Errorf("field %s.%s can't be accessed as %s", structure.desc.Name, name, reflect.TypeFor[T]().Name())
+	}
+
+	return result, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+)
+
+func solveStrings() {
+	solveParseCommand()
+}
+
+//	func parseCommand(cmd string) int {
+//		if !strings.HasPrefix(cmd, "go ") {
+//			return -1								(1)
+//		}
+//
+//		target := cmd[3:]
+//		if strings.Index(target, "/") == 0 {
+//			return 0								(2)
+//		}
+//
+//		if len([]rune(target)) != len(target) {
+//			return 1								(3)
+//		}
+//
+//		return 2									(4)
+//	}
+func solveParseCommand() {
+	fmt.Println("func parseCommand(cmd string) int")
+	runForCase(parseCommand1)
+	runForCase(parseCommand2)
+	runForCase(parseCommand3)
+	runForCase(parseCommand4)
+}
+
+func parseCommand1(sCtx *smt.SymContext) string {
+	argCmd := sCtx.NewStringArgument("cmd")
+
+	cond := argCmd.HasPrefix(sCtx.NewStringConst("go "))
+	sCtx.Solver.Assert(cond.Not())
+
+	return "!strings.HasPrefix(cmd, \"go \")"
+}
+
+func parseCommand2(sCtx *smt.SymContext) string {
+	argCmd := sCtx.NewStringArgument("cmd")
+
+	prevCond := argCmd.HasPrefix(sCtx.NewStringConst("go "))
+	sCtx.Solver.Assert(prevCond)
+
+	threeIntConst := sCtx.Ctx.FromInt(3, sCtx.Ctx.IntSort()).(z3.Int)
+	target, outOfRange := argCmd.Slice(threeIntConst, argCmd.Len())
+	sCtx.Solver.Assert(outOfRange.Not())
+
+	zeroIntConst := sCtx.Ctx.FromInt(0, sCtx.Ctx.IntSort()).(z3.Int)
+	cond := target.Index(sCtx.NewStringConst("/")).Eq(zeroIntConst)
+	sCtx.Solver.Assert(cond)
+
+	return "strings.HasPrefix(cmd, \"go \") && (strings.Index(target, \"/\") == 0)"
+}
+
+func parseCommand3(sCtx *smt.SymContext) string {
+	argCmd := sCtx.NewStringArgument("cmd")
+
+	prevCond1 := argCmd.HasPrefix(sCtx.NewStringConst("go "))
+	sCtx.Solver.Assert(prevCond1)
+
+	threeIntConst := sCtx.Ctx.FromInt(3, sCtx.Ctx.IntSort()).(z3.Int)
+	target, outOfRange := argCmd.Slice(threeIntConst, argCmd.Len())
+	sCtx.Solver.Assert(outOfRange.Not())
+
+	zeroIntConst := sCtx.Ctx.FromInt(0, sCtx.Ctx.IntSort()).(z3.Int)
+	prevCond2 := target.Index(sCtx.NewStringConst("/")).Eq(zeroIntConst)
+	sCtx.Solver.Assert(prevCond2.Not())
+
+	runes := target.ToRunes()
+	cond := runes.Len().NE(target.Len())
+	sCtx.Solver.Assert(cond)
+
+	return "strings.HasPrefix(cmd, \"go \") && !(strings.Index(target, \"/\") == 0) && (len([]rune(target)) != len(target))"
+}
+
+func parseCommand4(sCtx *smt.SymContext) string {
+	argCmd := sCtx.NewStringArgument("cmd")
+
+	prevCond1 := argCmd.HasPrefix(sCtx.NewStringConst("go "))
+	sCtx.Solver.Assert(prevCond1)
+
+	threeIntConst := sCtx.Ctx.FromInt(3, sCtx.Ctx.IntSort()).(z3.Int)
+	target, outOfRange := argCmd.Slice(threeIntConst, argCmd.Len())
+	sCtx.Solver.Assert(outOfRange.Not())
+
+	zeroIntConst := sCtx.Ctx.FromInt(0, sCtx.Ctx.IntSort()).(z3.Int)
+	prevCond2 := target.Index(sCtx.NewStringConst("/")).Eq(zeroIntConst)
+	sCtx.Solver.Assert(prevCond2.Not())
+
+	runes := target.ToRunes()
+	prevCond3 := runes.Len().NE(target.Len())
+	sCtx.Solver.Assert(prevCond3.Not())
+
+	return "strings.HasPrefix(cmd, \"go \") && !(strings.Index(target, \"/\") == 0) && !(len([]rune(target)) != len(target))"
+}
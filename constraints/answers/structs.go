@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+	"reflect"
+)
+
+type Point struct {
+	X int
+	Y int
+}
+
+type Named struct {
+	Name string
+}
+
+type Label struct {
+	Named
+	Position Point
+	Size     int
+}
+
+func solveStructs() {
+	solveShiftLabel()
+}
+
+//	func shiftLabel(l Label, dx int) int {
+//		shifted := l
+//		shifted.Position.X += dx
+//
+//		if shifted.Position.X == l.Position.X {
+//			return 0								(1)
+//		}
+//
+//		if len(l.Name) == 0 {
+//			return -1								(2)
+//		}
+//
+//		return 1									(3)
+//	}
+func solveShiftLabel() {
+	fmt.Println("func shiftLabel(l Label, dx int) int")
+	runForTestCase("shiftLabel", shiftLabel1)
+	runForTestCase("shiftLabel", shiftLabel2)
+	runForTestCase("shiftLabel", shiftLabel3)
+}
+
+// shiftLabelPositions encodes the copy of l and the assignment to its nested field,
+// it returns X of the original label and X of the shifted copy
+func shiftLabelPositions(argL smt.SymStruct, argDx z3.Int) (z3.Int, z3.Int) {
+	shifted := argL
+
+	position := field[smt.SymStruct](shifted, "Position")
+	shiftedPosition, err := position.WithField("X", smt.IntValue(field[smt.SymInt](position, "X").Int().Add(argDx)))
+	if err != nil {
+		panic(err)
+	}
+
+	shifted, err = shifted.WithField("Position", shiftedPosition)
+	if err != nil {
+		panic(err)
+	}
+
+	originalX := field[smt.SymInt](field[smt.SymStruct](argL, "Position"), "X").Int()
+	shiftedX := field[smt.SymInt](field[smt.SymStruct](shifted, "Position"), "X").Int()
+
+	return originalX, shiftedX
+}
+
+func field[T smt.SymValue](structure smt.SymStruct, name string) T {
+	value, err := smt.GetField[T](structure, name)
+	if err != nil {
+		panic(err)
+	}
+
+	return value
+}
+
+func newLabelArgument(sCtx *smt.SymContext) smt.SymStruct {
+	labelDescriptor, err := sCtx.DescribeStruct(reflect.TypeOf(Label{}))
+	if err != nil {
+		panic(err)
+	}
+
+	return sCtx.NewStructArgument("l", labelDescriptor)
+}
+
+func shiftLabel1(sCtx *smt.SymContext) string {
+	argL := newLabelArgument(sCtx)
+	argDx := sCtx.NewIntArgument("dx")
+
+	originalX, shiftedX := shiftLabelPositions(argL, argDx)
+	cond := shiftedX.Eq(originalX)
+	sCtx.Solver.Assert(cond)
+
+	sCtx.AddResult(sCtx.Ctx.FromInt(0, sCtx.Ctx.IntSort()), reflect.TypeOf(0))
+
+	return "shifted.Position.X == l.Position.X"
+}
+
+func shiftLabel2(sCtx *smt.SymContext) string {
+	argL := newLabelArgument(sCtx)
+	argDx := sCtx.NewIntArgument("dx")
+
+	originalX, shiftedX := shiftLabelPositions(argL, argDx)
+	prevCond := shiftedX.Eq(originalX)
+	sCtx.Solver.Assert(prevCond.Not())
+
+	// Name is promoted from the embedded Named
+	zeroIntConst := sCtx.Ctx.FromInt(0, sCtx.Ctx.IntSort()).(z3.Int)
+	cond := field[smt.SymString](argL, "Name").Len().Eq(zeroIntConst)
+	sCtx.Solver.Assert(cond)
+
+	sCtx.AddResult(sCtx.Ctx.FromInt(-1, sCtx.Ctx.IntSort()), reflect.TypeOf(0))
+
+	return "!(shifted.Position.X == l.Position.X) && (len(l.Name) == 0)"
+}
+
+func shiftLabel3(sCtx *smt.SymContext) string {
+	argL := newLabelArgument(sCtx)
+	argDx := sCtx.NewIntArgument("dx")
+
+	originalX, shiftedX := shiftLabelPositions(argL, argDx)
+	prevCond1 := shiftedX.Eq(originalX)
+	sCtx.Solver.Assert(prevCond1.Not())
+
+	zeroIntConst := sCtx.Ctx.FromInt(0, sCtx.Ctx.IntSort()).(z3.Int)
+	prevCond2 := field[smt.SymString](argL, "Name").Len().Eq(zeroIntConst)
+	sCtx.Solver.Assert(prevCond2.Not())
+
+	sCtx.AddResult(sCtx.Ctx.FromInt(1, sCtx.Ctx.IntSort()), reflect.TypeOf(0))
+
+	return "!(shifted.Position.X == l.Position.X) && !(len(l.Name) == 0)"
+}
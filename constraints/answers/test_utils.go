@@ -4,13 +4,59 @@ import (
 	"fmt"
 	"github.com/aclements/go-z3/z3"
 	"github.com/vldF/symbolic_execution_course/constraints/smt"
+	"github.com/vldF/symbolic_execution_course/constraints/testgen"
 	"math"
 	"math/bits"
+	"reflect"
+	"runtime"
+	"strings"
 )
 
 type Z3AwareFunction func(sCtx *smt.SymContext) string
 
 func runForCase(function Z3AwareFunction) {
+	solveCase(function)
+}
+
+// runForTestCase solves the case like runForCase does and prints a test checking
+// that targetFunction returns the encoded results on the arguments from the model
+func runForTestCase(targetFunction string, function Z3AwareFunction) {
+	sCtx, model := solveCase(function)
+	if model == nil {
+		return
+	}
+
+	arguments, err := sCtx.DecodeArguments(model)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	results, err := sCtx.DecodeResults(model)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	caseName := runtime.FuncForPC(reflect.ValueOf(function).Pointer()).Name()
+	caseName = strings.TrimPrefix(caseName, "main.")
+
+	testCase := testgen.Case{
+		Name:     "Test" + strings.ToUpper(caseName[:1]) + caseName[1:],
+		Function: targetFunction,
+		Results:  results,
+	}
+	for _, argument := range arguments {
+		testCase.Arguments = append(testCase.Arguments, argument.Value)
+	}
+
+	fmt.Println("generated test:")
+	fmt.Println(testCase.Render())
+}
+
+// solveCase prints the constraints of the case and the model satisfying them.
+// The model is nil when the constraints can't be satisfied
+func solveCase(function Z3AwareFunction) (*smt.SymContext, *z3.Model) {
 	sCtx := CreateSymContext()
 	solver := sCtx.Solver
 
@@ -23,7 +69,7 @@ func runForCase(function Z3AwareFunction) {
 	check, err := solver.Check()
 	if err != nil {
 		fmt.Println(err)
-		return
+		return &sCtx, nil
 	}
 
 	fmt.Println("is satisfied: ", check)
@@ -33,10 +79,23 @@ func runForCase(function Z3AwareFunction) {
 			fmt.Println(unsatCore[i])
 		}
 
-		return
+		return &sCtx, nil
 	}
 
-	fmt.Println(solver.Model().String())
+	model := solver.Model()
+	fmt.Println(model.String())
+
+	arguments, err := sCtx.DecodeArguments(model)
+	if err != nil {
+		fmt.Println(err)
+		return &sCtx, model
+	}
+
+	for _, argument := range arguments {
+		fmt.Printf("%s = %#v\n", argument.Name, argument.Value)
+	}
+
+	return &sCtx, model
 }
 
 func CreateSymContext() smt.SymContext {
@@ -51,6 +110,9 @@ func CreateSymContext() smt.SymContext {
 		MaxFloat64:  math.MaxFloat64,
 		MinFloat64:  -math.MaxFloat64,
 		Float64Size: 64,
+
+		MaxStringLength: 8,
+		MaxSliceLength:  4,
 	}
 
 	sCtx := smt.SymContext{
@@ -0,0 +1,445 @@
+package testgen
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Case is a single generated test: a call of the function with concrete arguments
+// and the values it's expected to return
+type Case struct {
+	Name      string
+	Function  string
+	Arguments []reflect.Value
+	Results   []reflect.Value
+
+	// Panics means the call is expected to panic, Results are ignored then
+	Panics bool
+	// Unchecked are the functions with the unknown results the path calls when the test isn't confirmed
+	// to follow it, the outcome of the call isn't checked then
+	Unchecked []string
+
+	// Receiver is the value the method is called on, it's the zero Value for functions
+	Receiver reflect.Value
+	// Globals are the package variables set before the call, the test restores their values
+	Globals []Global
+}
+
+// Global is the value of a package variable
+type Global struct {
+	Name  string
+	Value reflect.Value
+}
+
+// Source is a Go expression which is rendered as is. It's used for values
+// of types which exist only in the package under test
+type Source string
+
+// PointerSource is a Source evaluating to a new pointer or to a slice, which aren't compared with ==.
+// Tests compare them with reflect.DeepEqual
+type PointerSource string
+
+// Error is the expected error result. Tests check that the error is nil or that it has the dynamic type,
+// its message isn't checked. The empty Type means the nil error. Made means the error is made by errors.New
+// or fmt.Errorf, its type is unexported and tests check the Message instead
+type Error struct {
+	Type    string
+	Made    bool
+	Message string
+}
+
+// Function is the expected function result. Functions aren't comparable, tests only check whether it's nil
+type Function struct {
+	Nil bool
+}
+
+// Stub is a function argument returning the results of the rows for their arguments
+// and zero values for any other arguments. Params and Results are the names of the types
+type Stub struct {
+	Params  []string
+	Results []string
+	Rows    []StubRow
+}
+
+type StubRow struct {
+	Arguments []reflect.Value
+	Results   []reflect.Value
+}
+
+// Source returns the function literal of the stub
+func (s Stub) Source() Source {
+	params := make([]string, 0, len(s.Params))
+	for i, param := range s.Params {
+		params = append(params, fmt.Sprintf("a%d %s", i, param))
+	}
+	results := make([]string, 0, len(s.Results))
+	for i, result := range s.Results {
+		results = append(results, fmt.Sprintf("r%d %s", i, result))
+	}
+
+	builder := strings.Builder{}
+	fmt.Fprintf(&builder, "func(%s) (%s) {", strings.Join(params, ", "), strings.Join(results, ", "))
+	for _, row := range s.Rows {
+		conds := make([]string, 0, len(row.Arguments))
+		for i, arg := range row.Arguments {
+			conds = append(conds, fmt.Sprintf("a%d == %s", i, Literal(arg)))
+		}
+		values := make([]string, 0, len(row.Results))
+		for _, result := range row.Results {
+			values = append(values, Literal(result))
+		}
+
+		if len(conds) == 0 {
+			fmt.Fprintf(&builder, " return %s }", strings.Join(values, ", "))
+			return Source(builder.String())
+		}
+		fmt.Fprintf(&builder, " if %s { return %s };", strings.Join(conds, " && "), strings.Join(values, ", "))
+	}
+	builder.WriteString(" return }")
+
+	return Source(builder.String())
+}
+
+// Render returns the source of a test function checking the case
+func (c Case) Render() string {
+	builder := strings.Builder{}
+
+	arguments := make([]string, 0, len(c.Arguments))
+	for _, argument := range c.Arguments {
+		arguments = append(arguments, Literal(argument))
+	}
+	call := fmt.Sprintf("%s(%s)", c.Function, strings.Join(arguments, ", "))
+	if c.Receiver.IsValid() {
+		call = fmt.Sprintf("(%s).%s", Literal(c.Receiver), call)
+	}
+
+	fmt.Fprintf(&builder, "func %s(t *testing.T) {\n", c.Name)
+	for i, global := range c.Globals {
+		fmt.Fprintf(&builder, "\tsaved%d := %s\n", i, global.Name)
+		fmt.Fprintf(&builder, "\tdefer func() { %s = saved%d }()\n", global.Name, i)
+		fmt.Fprintf(&builder, "\t%s = %s\n", global.Name, Literal(global.Value))
+	}
+	if len(c.Unchecked) > 0 {
+		fmt.Fprintf(&builder, "\t// the outcome isn't checked, the results of %s aren't known\n", strings.Join(c.Unchecked, ", "))
+		fmt.Fprintf(&builder, "\t%s\n", call)
+		builder.WriteString("}\n")
+		return builder.String()
+	}
+	if c.Panics {
+		builder.WriteString("\tdefer func() {\n")
+		builder.WriteString("\t\tif recover() == nil {\n")
+		fmt.Fprintf(&builder, "\t\t\tt.Errorf(\"%s: expected a panic\")\n", c.Function)
+		builder.WriteString("\t\t}\n")
+		builder.WriteString("\t}()\n")
+	}
+	if len(c.Results) == 0 || c.Panics {
+		fmt.Fprintf(&builder, "\t%s\n", call)
+		builder.WriteString("}\n")
+		return builder.String()
+	}
+
+	errorResults := 0
+	for _, result := range c.Results {
+		if result.Type() == reflect.TypeOf(Error{}) {
+			errorResults++
+		}
+	}
+
+	got := make([]string, 0, len(c.Results))
+	for i, result := range c.Results {
+		// the only error result is err as in the code calling the function
+		if errorResults == 1 && result.Type() == reflect.TypeOf(Error{}) {
+			got = append(got, "err")
+			continue
+		}
+		got = append(got, fmt.Sprintf("got%d", i))
+	}
+	fmt.Fprintf(&builder, "\t%s := %s\n", strings.Join(got, ", "), call)
+
+	for i, result := range c.Results {
+		if expected, ok := result.Interface().(Error); ok {
+			expected.check(&builder, c.Function, got[i])
+			continue
+		}
+		if expected, ok := result.Interface().(Function); ok {
+			expected.check(&builder, c.Function, got[i])
+			continue
+		}
+
+		want := Literal(result)
+		fmt.Fprintf(&builder, "\tif %s {\n", mismatch(got[i], want, result))
+		fmt.Fprintf(&builder, "\t\tt.Errorf(\"%s: got %%v, want %%v\", %s, %s)\n", c.Function, got[i], want)
+		builder.WriteString("\t}\n")
+	}
+	builder.WriteString("}\n")
+
+	return builder.String()
+}
+
+// check writes the statements checking the error result held by the variable
+func (expected Error) check(builder *strings.Builder, function string, variable string) {
+	if expected.Type == "" {
+		fmt.Fprintf(builder, "\tif %s != nil {\n", variable)
+		fmt.Fprintf(builder, "\t\tt.Errorf(\"%s: unexpected error %%v\", %s)\n", function, variable)
+		builder.WriteString("\t}\n")
+		return
+	}
+
+	fmt.Fprintf(builder, "\tif %s == nil {\n", variable)
+	fmt.Fprintf(builder, "\t\tt.Errorf(\"%s: expected an error\")\n", function)
+	if expected.Made {
+		fmt.Fprintf(builder, "\t} else if %s.Error() != %q {\n", variable, expected.Message)
+		fmt.Fprintf(builder, "\t\tt.Errorf(\"%s: got the error %%q, want %%q\", %s.Error(), %q)\n", function, variable, expected.Message)
+		builder.WriteString("\t}\n")
+		return
+	}
+	fmt.Fprintf(builder, "\t} else if _, ok := %s.(%s); !ok {\n", variable, expected.Type)
+	fmt.Fprintf(builder, "\t\tt.Errorf(\"%s: got the error %%T, want %s\", %s)\n", function, expected.Type, variable)
+	builder.WriteString("\t}\n")
+}
+
+// check writes the statements checking whether the function result held by the variable is nil
+func (expected Function) check(builder *strings.Builder, function string, variable string) {
+	if expected.Nil {
+		fmt.Fprintf(builder, "\tif %s != nil {\n", variable)
+		fmt.Fprintf(builder, "\t\tt.Errorf(\"%s: expected the nil function\")\n", function)
+	} else {
+		fmt.Fprintf(builder, "\tif %s == nil {\n", variable)
+		fmt.Fprintf(builder, "\t\tt.Errorf(\"%s: unexpected nil function\")\n", function)
+	}
+	builder.WriteString("\t}\n")
+}
+
+// uses tells that the code outside the comments mentions the name
+func uses(source string, name string) bool {
+	for _, line := range strings.Split(source, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "//") && strings.Contains(line, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RenderFile returns the source of a test file with all the cases, the notes are comments above the imports.
+// The file without cases would import testing for nothing, so it's empty
+func RenderFile(pkg string, cases []Case, notes ...string) string {
+	if len(cases) == 0 {
+		return ""
+	}
+
+	body := strings.Builder{}
+	for _, c := range cases {
+		body.WriteString("\n")
+		body.WriteString(c.Render())
+	}
+
+	imports := []string{"testing"}
+	if uses(body.String(), "math.") {
+		imports = append(imports, "math")
+	}
+	if uses(body.String(), "errors.New(") {
+		imports = append(imports, "errors")
+	}
+	if uses(body.String(), "reflect.DeepEqual") {
+		imports = append(imports, "reflect")
+	}
+	sort.Strings(imports)
+
+	builder := strings.Builder{}
+	fmt.Fprintf(&builder, "package %s\n\n", pkg)
+	for _, note := range notes {
+		fmt.Fprintf(&builder, "// %s\n", note)
+	}
+	if len(notes) > 0 {
+		builder.WriteString("\n")
+	}
+	if len(imports) == 1 {
+		fmt.Fprintf(&builder, "import %q\n", imports[0])
+	} else {
+		builder.WriteString("import (\n")
+		for _, imp := range imports {
+			fmt.Fprintf(&builder, "\t%q\n", imp)
+		}
+		builder.WriteString(")\n")
+	}
+	builder.WriteString(body.String())
+
+	return builder.String()
+}
+
+func mismatch(got string, want string, value reflect.Value) string {
+	if value.Type() == reflect.TypeOf(PointerSource("")) || value.Kind() == reflect.Pointer {
+		return fmt.Sprintf("!reflect.DeepEqual(%s, %s)", got, want)
+	}
+
+	// composite literals need parentheses in the condition of if
+	if value.Kind() == reflect.Struct || value.Type() == reflect.TypeOf(Source("")) && strings.HasSuffix(want, "}") {
+		want = "(" + want + ")"
+	}
+
+	if value.Kind() == reflect.Float32 || value.Kind() == reflect.Float64 {
+		if math.IsNaN(value.Float()) {
+			return fmt.Sprintf("%s == %s", got, got)
+		}
+	}
+
+	if value.Comparable() && value.Kind() != reflect.Interface {
+		return fmt.Sprintf("%s != %s", got, want)
+	}
+
+	return fmt.Sprintf("!reflect.DeepEqual(%s, %s)", got, want)
+}
+
+// Literal returns a Go expression evaluating to the value. Types of the package under test are
+// referred to without the package name, since the tests are generated into the same package
+func Literal(value reflect.Value) string {
+	if value.Type() == reflect.TypeOf(Source("")) || value.Type() == reflect.TypeOf(PointerSource("")) {
+		return value.String()
+	}
+
+	switch value.Kind() {
+	case reflect.Bool:
+		return typed(value.Type(), strconv.FormatBool(value.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return typed(value.Type(), strconv.FormatInt(value.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return typed(value.Type(), strconv.FormatUint(value.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		return typed(value.Type(), floatLiteral(value.Float()))
+	case reflect.Complex64, reflect.Complex128:
+		c := value.Complex()
+		return typed(value.Type(), fmt.Sprintf("complex(%s, %s)", floatLiteral(real(c)), floatLiteral(imag(c))))
+	case reflect.String:
+		return typed(value.Type(), strconv.Quote(value.String()))
+	case reflect.Map:
+		if value.IsNil() {
+			return fmt.Sprintf("%s(nil)", typeName(value.Type()))
+		}
+
+		entries := make([]string, 0, value.Len())
+		for _, key := range value.MapKeys() {
+			entries = append(entries, fmt.Sprintf("%s: %s", Literal(key), Literal(value.MapIndex(key))))
+		}
+		sort.Strings(entries)
+
+		return fmt.Sprintf("%s{%s}", typeName(value.Type()), strings.Join(entries, ", "))
+	case reflect.Slice:
+		if value.IsNil() {
+			return fmt.Sprintf("%s(nil)", typeName(value.Type()))
+		}
+		fallthrough
+	case reflect.Array:
+		elements := make([]string, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			elements = append(elements, Literal(value.Index(i)))
+		}
+
+		return fmt.Sprintf("%s{%s}", typeName(value.Type()), strings.Join(elements, ", "))
+	case reflect.Struct:
+		fields := make([]string, 0, value.NumField())
+		for i := 0; i < value.NumField(); i++ {
+			fields = append(fields, fmt.Sprintf("%s: %s", value.Type().Field(i).Name, Literal(value.Field(i))))
+		}
+
+		return fmt.Sprintf("%s{%s}", typeName(value.Type()), strings.Join(fields, ", "))
+	case reflect.Pointer:
+		if value.IsNil() {
+			return fmt.Sprintf("(%s)(nil)", typeName(value.Type()))
+		}
+
+		if value.Elem().Kind() == reflect.Struct {
+			return "&" + Literal(value.Elem())
+		}
+
+		return fmt.Sprintf("func() %s { v := %s; return &v }()", typeName(value.Type()), Literal(value.Elem()))
+	case reflect.Interface:
+		if value.IsNil() {
+			return "nil"
+		}
+
+		return dynamicLiteral(value.Elem())
+	default:
+		return fmt.Sprintf("%#v", value.Interface())
+	}
+}
+
+func floatLiteral(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "math.NaN()"
+	case math.IsInf(value, 1):
+		return "math.Inf(1)"
+	case math.IsInf(value, -1):
+		return "math.Inf(-1)"
+	case value == 0 && math.Signbit(value):
+		return "math.Copysign(0, -1)"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+// dynamicLiteral is the literal of the dynamic value of an interface. The untyped constant would take
+// its default type, so the constants of the other predeclared types are converted to their types
+func dynamicLiteral(value reflect.Value) string {
+	literal := Literal(value)
+	t := value.Type()
+	if t.PkgPath() != "" || t.Name() != t.Kind().String() {
+		return literal
+	}
+
+	switch t.Kind() {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64:
+		return fmt.Sprintf("%s(%s)", t.Name(), literal)
+	default:
+		return literal
+	}
+}
+
+// typed converts untyped constants to named types, the predeclared ones are inferred from the context
+func typed(t reflect.Type, literal string) string {
+	if t.Name() == t.Kind().String() && t.PkgPath() == "" {
+		return literal
+	}
+
+	return fmt.Sprintf("%s(%s)", typeName(t), literal)
+}
+
+// typeName spells the type as it's seen from the package under test
+func typeName(t reflect.Type) string {
+	if t.Name() != "" {
+		if isStandardPackage(t.PkgPath()) {
+			return t.String()
+		}
+
+		return t.Name()
+	}
+
+	switch t.Kind() {
+	case reflect.Map:
+		return fmt.Sprintf("map[%s]%s", typeName(t.Key()), typeName(t.Elem()))
+	case reflect.Slice:
+		return "[]" + typeName(t.Elem())
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%s", t.Len(), typeName(t.Elem()))
+	case reflect.Pointer:
+		return "*" + typeName(t.Elem())
+	default:
+		return t.String()
+	}
+}
+
+func isStandardPackage(path string) bool {
+	if path == "main" {
+		return false
+	}
+
+	firstElement, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(firstElement, ".")
+}
@@ -0,0 +1,100 @@
+package testgen
+
+import (
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRenderFileWithoutCases(t *testing.T) {
+	if file := RenderFile("unknown", nil); file != "" {
+		t.Errorf("RenderFile: got %q, want the empty file", file)
+	}
+	if file := RenderFile("unknown", []Case{}, "a note"); file != "" {
+		t.Errorf("RenderFile: got %q, want the empty file", file)
+	}
+}
+
+func TestRenderFile(t *testing.T) {
+	cases := []Case{{
+		Name:      "TestDigit1",
+		Function:  "Digit",
+		Arguments: []reflect.Value{reflect.ValueOf(int32('7'))},
+		Results:   []reflect.Value{reflect.ValueOf(7)},
+	}}
+
+	file := RenderFile("unknown", cases, "a note")
+	parsed, err := parser.ParseFile(token.NewFileSet(), "unknown_test.go", file, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("RenderFile: the file doesn't parse: %v\n%s", err, file)
+	}
+	if len(parsed.Imports) != 1 || parsed.Imports[0].Path.Value != `"testing"` {
+		t.Errorf("RenderFile: got the imports %v, want testing", parsed.Imports)
+	}
+	if !strings.Contains(file, "// a note\n") {
+		t.Errorf("RenderFile: the note is missing in\n%s", file)
+	}
+}
+
+func TestRenderUnchecked(t *testing.T) {
+	c := Case{
+		Name:      "TestGrowth1",
+		Function:  "Growth",
+		Arguments: []reflect.Value{reflect.ValueOf(-2.225073858507202e-308)},
+		Results:   []reflect.Value{reflect.ValueOf("decay")},
+		Unchecked: []string{"math.Exp (approximated)"},
+	}
+
+	rendered := c.Render()
+	if strings.Contains(rendered, "t.Errorf") {
+		t.Errorf("Render: the outcome is checked in\n%s", rendered)
+	}
+	if !strings.Contains(rendered, "math.Exp") {
+		t.Errorf("Render: the approximated function isn't named in\n%s", rendered)
+	}
+
+	file := RenderFile("libraries", []Case{c})
+	parsed, err := parser.ParseFile(token.NewFileSet(), "libraries_test.go", file, 0)
+	if err != nil {
+		t.Fatalf("RenderFile: the file doesn't parse: %v\n%s", err, file)
+	}
+	if len(parsed.Imports) != 1 {
+		t.Errorf("RenderFile: got the imports %v, want only testing", parsed.Imports)
+	}
+}
+
+func TestLiteralInInterface(t *testing.T) {
+	value := []any{int8(5), float64(1), uint(3), float32(2), 7, "s", true}
+	want := `[]interface {}{int8(5), float64(1), uint(3), float32(2), 7, "s", true}`
+	if got := Literal(reflect.ValueOf(value)); got != want {
+		t.Errorf("Literal: got %s, want %s", got, want)
+	}
+}
+
+func TestRenderMadeError(t *testing.T) {
+	c := Case{
+		Name:      "TestDescribe1",
+		Function:  "Describe",
+		Arguments: []reflect.Value{reflect.ValueOf(Source(`errors.New("odd")`))},
+		Results:   []reflect.Value{reflect.ValueOf(Error{Type: "*errors.errorString", Made: true, Message: "odd"})},
+	}
+
+	rendered := c.Render()
+	if !strings.Contains(rendered, `.Error() != "odd"`) {
+		t.Errorf("Render: the message isn't checked in\n%s", rendered)
+	}
+	if strings.Contains(rendered, "*errors.errorString") {
+		t.Errorf("Render: the unexported type is asserted in\n%s", rendered)
+	}
+
+	file := RenderFile("errs", []Case{c})
+	parsed, err := parser.ParseFile(token.NewFileSet(), "errs_test.go", file, 0)
+	if err != nil {
+		t.Fatalf("RenderFile: the file doesn't parse: %v\n%s", err, file)
+	}
+	if len(parsed.Imports) != 2 || parsed.Imports[0].Path.Value != `"errors"` {
+		t.Errorf("RenderFile: got the imports %v, want errors and testing", parsed.Imports)
+	}
+}
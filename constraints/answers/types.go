@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"github.com/aclements/go-z3/z3"
+	"github.com/vldF/symbolic_execution_course/constraints/smt"
+	"github.com/vldF/symbolic_execution_course/constraints/smt/types"
+)
+
+// class Object
+// interface Serializable
+// interface Comparable
+// interface CharSequence
+// class Number implements Serializable
+// class Integer extends Number implements Comparable
+// class Long extends Number implements Comparable
+// class String implements Serializable, Comparable, CharSequence
+// class StringBuilder implements Serializable, CharSequence
+func solveTypes() {
+	fmt.Println("type hierarchy of java.lang")
+	runForCase(types1)
+	runForCase(types2)
+	runForCase(types3)
+	runForCase(types4)
+	runForCase(types5)
+	runForCase(types6)
+
+	printBounds(javaHierarchy(), "Integer", "Number")
+	printBounds(javaHierarchy(), "Integer", "StringBuilder")
+	printBounds(javaHierarchy(), "String", "StringBuilder")
+	printBounds(javaHierarchy(), "Comparable", "CharSequence")
+}
+
+// printBounds prints the join and the meet of the types computed on the declared hierarchy
+func printBounds(hierarchy *types.Hierarchy, a string, b string) {
+	fmt.Println("===================")
+	fmt.Printf("bounds of %s and %s\n", a, b)
+
+	if join, err := hierarchy.Join(a, b); err != nil {
+		fmt.Println("join:", err)
+	} else {
+		fmt.Println("join:", join)
+	}
+
+	if meet, err := hierarchy.Meet(a, b); err != nil {
+		fmt.Println("meet:", err)
+	} else {
+		fmt.Println("meet:", meet)
+	}
+}
+
+func javaHierarchy() *types.Hierarchy {
+	hierarchy := types.NewHierarchy("Object")
+	hierarchy.AddInterface("Serializable")
+	hierarchy.AddInterface("Comparable")
+	hierarchy.AddInterface("CharSequence")
+	hierarchy.AddClass("Number", "", "Serializable")
+	hierarchy.AddClass("Integer", "Number", "Comparable")
+	hierarchy.AddClass("Long", "Number", "Comparable")
+	hierarchy.AddClass("String", "", "Serializable", "Comparable", "CharSequence")
+	hierarchy.AddClass("StringBuilder", "", "Serializable", "CharSequence")
+
+	return hierarchy
+}
+
+func encodeJavaHierarchy(sCtx *smt.SymContext) *types.TypeSystem {
+	ts, err := types.Encode(sCtx, javaHierarchy())
+	if err != nil {
+		panic(err)
+	}
+
+	return ts
+}
+
+func javaType(ts *types.TypeSystem, name string) z3.Uninterpreted {
+	t, err := ts.Type(name)
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
+
+func types1(sCtx *smt.SymContext) string {
+	ts := encodeJavaHierarchy(sCtx)
+	a := ts.NewTypeArgument("a")
+
+	sCtx.Solver.Assert(ts.IsSubtypeOf(a, javaType(ts, "Number")).Not())
+	sCtx.Solver.Assert(ts.IsSubtypeOf(a, javaType(ts, "Comparable")))
+	sCtx.Solver.Assert(ts.IsInterface(a).Not())
+
+	return "!(a <: Number) && (a <: Comparable) && !isInterface(a)"
+}
+
+func types2(sCtx *smt.SymContext) string {
+	ts := encodeJavaHierarchy(sCtx)
+	a := ts.NewTypeArgument("a")
+	b := ts.NewTypeArgument("b")
+
+	sCtx.Solver.Assert(ts.IsSubclassOf(a, javaType(ts, "Number")))
+	sCtx.Solver.Assert(a.NE(javaType(ts, "Number")))
+	sCtx.Solver.Assert(ts.IsSupertypeOf(b, a))
+	sCtx.Solver.Assert(ts.IsInterface(b))
+	sCtx.Solver.Assert(ts.IsSupertypeOf(b, javaType(ts, "Number")).Not())
+
+	return "(a subclass of Number) && (a != Number) && (b :> a) && isInterface(b) && !(b :> Number)"
+}
+
+func types3(sCtx *smt.SymContext) string {
+	ts := encodeJavaHierarchy(sCtx)
+	a := ts.NewTypeArgument("a")
+
+	sCtx.Solver.Assert(ts.IsSubtypeOf(a, javaType(ts, "CharSequence")))
+	sCtx.Solver.Assert(ts.IsSubclassOf(a, javaType(ts, "Object")))
+	sCtx.Solver.Assert(a.NE(javaType(ts, "String")))
+
+	return "(a <: CharSequence) && (a subclass of Object) && (a != String)"
+}
+
+func types4(sCtx *smt.SymContext) string {
+	ts := encodeJavaHierarchy(sCtx)
+	a := ts.NewTypeArgument("a")
+
+	sCtx.Solver.Assert(ts.IsSubtypeOf(a, javaType(ts, "Number")))
+	sCtx.Solver.Assert(ts.IsSubtypeOf(a, javaType(ts, "CharSequence")))
+
+	return "(a <: Number) && (a <: CharSequence)"
+}
+
+func types5(sCtx *smt.SymContext) string {
+	ts := encodeJavaHierarchy(sCtx)
+	a := ts.NewTypeArgument("a")
+	t := ts.NewTypeArgument("t")
+
+	sCtx.Solver.Assert(ts.IsJoin(t, a, javaType(ts, "StringBuilder")))
+	sCtx.Solver.Assert(a.NE(javaType(ts, "StringBuilder")))
+	sCtx.Solver.Assert(t.NE(javaType(ts, "Object")))
+
+	return "(t = join(a, StringBuilder)) && (a != StringBuilder) && (t != Object)"
+}
+
+func types6(sCtx *smt.SymContext) string {
+	ts := encodeJavaHierarchy(sCtx)
+	a := ts.NewTypeArgument("a")
+	t := ts.NewTypeArgument("t")
+
+	sCtx.Solver.Assert(ts.IsMeet(t, a, javaType(ts, "Comparable")))
+	sCtx.Solver.Assert(ts.IsInterface(a))
+	sCtx.Solver.Assert(a.NE(javaType(ts, "Comparable")))
+
+	return "(t = meet(a, Comparable)) && isInterface(a) && (a != Comparable)"
+}
+
+//	func Describe(s Shape) int {
+//		if s == nil {
+//			return 0					(1)
+//		}
+//
+//		if _, ok := s.(Scalable); ok {
+//			return 1					(2)
+//		}
+//
+//		if _, ok := s.(Named); ok {
+//			return 2					(3)
+//		}
+//
+//		return 3						(4)
+//	}
+func solveGoTypes() {
+	fmt.Println("func Describe(s Shape) int")
+	runForCase(describe1)
+	runForCase(describe2)
+	runForCase(describe3)
+	runForCase(describe4)
+}
+
+func newShapeArgument(sCtx *smt.SymContext) (*types.TypeSystem, types.SymInterface) {
+	pkg, err := types.LoadDir("examples/shapes")
+	if err != nil {
+		panic(err)
+	}
+
+	ts, err := types.Encode(sCtx, types.FromPackage(pkg.Package))
+	if err != nil {
+		panic(err)
+	}
+
+	argS, err := ts.NewInterfaceArgument("s", "Shape")
+	if err != nil {
+		panic(err)
+	}
+
+	return ts, argS
+}
+
+func implements(ts *types.TypeSystem, value types.SymInterface, iface string) z3.Bool {
+	cond, err := ts.Implements(value, iface)
+	if err != nil {
+		panic(err)
+	}
+
+	return cond
+}
+
+func describe1(sCtx *smt.SymContext) string {
+	_, argS := newShapeArgument(sCtx)
+
+	sCtx.Solver.Assert(argS.IsNil)
+
+	return "s == nil"
+}
+
+func describe2(sCtx *smt.SymContext) string {
+	ts, argS := newShapeArgument(sCtx)
+
+	sCtx.Solver.Assert(argS.IsNil.Not())
+	sCtx.Solver.Assert(implements(ts, argS, "Scalable"))
+
+	return "!(s == nil) && s.(Scalable)"
+}
+
+func describe3(sCtx *smt.SymContext) string {
+	ts, argS := newShapeArgument(sCtx)
+
+	sCtx.Solver.Assert(argS.IsNil.Not())
+	sCtx.Solver.Assert(implements(ts, argS, "Scalable").Not())
+	sCtx.Solver.Assert(implements(ts, argS, "Named"))
+
+	return "!(s == nil) && !s.(Scalable) && s.(Named)"
+}
+
+func describe4(sCtx *smt.SymContext) string {
+	ts, argS := newShapeArgument(sCtx)
+
+	sCtx.Solver.Assert(argS.IsNil.Not())
+	sCtx.Solver.Assert(implements(ts, argS, "Scalable").Not())
+	sCtx.Solver.Assert(implements(ts, argS, "Named").Not())
+
+	return "!(s == nil) && !s.(Scalable) && !s.(Named)"
+}
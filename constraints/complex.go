@@ -50,3 +50,13 @@ func nestedComplexOperations(a complex128, b complex128) complex128 {
 	}
 	return a + b
 }
+
+func complexOrder(a complex128, b complex128) int {
+	switch complexComparison(a, b) {
+	case "Magnitude of a is greater than b":
+		return 1
+	case "Magnitude of b is greater than a":
+		return -1
+	}
+	return 0
+}
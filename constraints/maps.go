@@ -0,0 +1,17 @@
+package main
+
+func updateStock(stock map[int]int, item int, amount int) int {
+	current, ok := stock[item]
+	if !ok {
+		stock[item] = amount // паникует на nil map
+		return len(stock)
+	}
+
+	if current+amount <= 0 {
+		delete(stock, item)
+		return -1
+	}
+
+	stock[item] = current + amount
+	return len(stock)
+}
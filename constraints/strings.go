@@ -0,0 +1,20 @@
+package main
+
+import "strings"
+
+func parseCommand(cmd string) int {
+	if !strings.HasPrefix(cmd, "go ") {
+		return -1 // неизвестная команда
+	}
+
+	target := cmd[3:]
+	if strings.Index(target, "/") == 0 {
+		return 0 // абсолютный путь
+	}
+
+	if len([]rune(target)) != len(target) {
+		return 1 // есть не-ASCII символы
+	}
+
+	return 2
+}
@@ -0,0 +1,31 @@
+package main
+
+type Point struct {
+	X int
+	Y int
+}
+
+type Named struct {
+	Name string
+}
+
+type Label struct {
+	Named
+	Position Point
+	Size     int
+}
+
+func shiftLabel(l Label, dx int) int {
+	shifted := l
+	shifted.Position.X += dx
+
+	if shifted.Position.X == l.Position.X {
+		return 0 // копия совпала с оригиналом
+	}
+
+	if len(l.Name) == 0 {
+		return -1 // безымянная метка
+	}
+
+	return 1
+}